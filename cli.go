@@ -0,0 +1,51 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"kindExport/internal/config"
+	"kindExport/internal/db"
+	"kindExport/internal/secret"
+	"log"
+	"os"
+)
+
+// runCommand executes the maintenance command given on the command line instead of starting the bot
+func runCommand(args []string) {
+	switch args[0] {
+	case "generate-key":
+		key := make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			log.Fatalf("Error generating key: %s", err.Error())
+		}
+		fmt.Println(base64.StdEncoding.EncodeToString(key))
+	case "rotate-key":
+		rotateKey()
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown command %s\n\nCommands:\n"+
+			"  generate-key  print a new random encryption key\n"+
+			"  rotate-key    re-encrypt the stored credentials from ENCRYPTION_KEY to NEW_ENCRYPTION_KEY\n", args[0])
+		os.Exit(2)
+	}
+}
+
+// rotateKey re-encrypts the data keys of all stored credentials with the key from NEW_ENCRYPTION_KEY.
+// ENCRYPTION_KEY has to be replaced with the new key before the bot is started again.
+func rotateKey() {
+	newKey, err := config.ParseKey(os.Getenv("NEW_ENCRYPTION_KEY"))
+	if err != nil {
+		log.Fatalf("NEW_ENCRYPTION_KEY is invalid: %s", err.Error())
+	}
+	dbSession, err := db.GetDB()
+	if err != nil {
+		log.Fatalf("Error initializing database: %s", err.Error())
+	}
+	defer dbSession.Close()
+
+	count, err := db.RotateEncryptionKey(newKey)
+	if err != nil {
+		log.Fatalf("Error rotating encryption key: %s", err.Error())
+	}
+	log.Printf("Re-encrypted %d credentials with key %s, set ENCRYPTION_KEY to the new key now", count, secret.KeyID(newKey))
+}
@@ -0,0 +1,21 @@
+//
+// Code generated by go-jet DO NOT EDIT.
+//
+// WARNING: Changes to this file may cause incorrect behavior
+// and will be lost if the code is regenerated
+//
+
+package model
+
+import (
+	"time"
+)
+
+type APITokens struct {
+	ID         *int32 `sql:"primary_key"`
+	UserID     int32
+	Scope      string
+	TokenHash  string
+	CreatedAt  time.Time
+	LastUsedAt *time.Time
+}
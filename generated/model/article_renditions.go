@@ -0,0 +1,20 @@
+//
+// Code generated by go-jet DO NOT EDIT.
+//
+// WARNING: Changes to this file may cause incorrect behavior
+// and will be lost if the code is regenerated
+//
+
+package model
+
+import (
+	"time"
+)
+
+type ArticleRenditions struct {
+	ID        *int32 `sql:"primary_key"`
+	ArticleID int32
+	Format    string
+	LocalPath string
+	CreatedAt time.Time
+}
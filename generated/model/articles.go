@@ -12,12 +12,15 @@ import (
 )
 
 type Articles struct {
-	ID          *int32 `sql:"primary_key"`
-	Title       string
-	Author      string
-	URL         string
-	ReleaseDate time.Time
-	LocalPath   string
-	CreatedAt   time.Time
-	Paid        bool
+	ID           *int32 `sql:"primary_key"`
+	Title        string
+	Author       string
+	URL          string
+	ReleaseDate  time.Time
+	LocalPath    string
+	CreatedAt    time.Time
+	Paid         bool
+	Completeness string
+	ScrapedBy    *int32
+	PreviewPath  *string
 }
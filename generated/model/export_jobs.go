@@ -0,0 +1,29 @@
+//
+// Code generated by go-jet DO NOT EDIT.
+//
+// WARNING: Changes to this file may cause incorrect behavior
+// and will be lost if the code is regenerated
+//
+
+package model
+
+import (
+	"time"
+)
+
+type ExportJobs struct {
+	ID               *int32 `sql:"primary_key"`
+	UserID           int32
+	URL              string
+	State            string
+	Attempts         int32
+	NextAttemptAt    time.Time
+	LastError        *string
+	Result           *string
+	ArticleID        *int32
+	ApplicationID    *string
+	InteractionToken *string
+	CreatedAt        time.Time
+	UpdatedAt        time.Time
+	PageHTML         *string
+}
@@ -0,0 +1,19 @@
+//
+// Code generated by go-jet DO NOT EDIT.
+//
+// WARNING: Changes to this file may cause incorrect behavior
+// and will be lost if the code is regenerated
+//
+
+package model
+
+import (
+	"time"
+)
+
+type SubscriptionItems struct {
+	ID             *int32 `sql:"primary_key"`
+	SubscriptionID int32
+	URL            string
+	CreatedAt      time.Time
+}
@@ -0,0 +1,23 @@
+//
+// Code generated by go-jet DO NOT EDIT.
+//
+// WARNING: Changes to this file may cause incorrect behavior
+// and will be lost if the code is regenerated
+//
+
+package model
+
+import (
+	"time"
+)
+
+type Subscriptions struct {
+	ID              *int32 `sql:"primary_key"`
+	UserID          int32
+	FeedURL         string
+	Title           string
+	LastPublishedAt time.Time
+	LastCheckedAt   *time.Time
+	CreatedAt       time.Time
+	ItemsRecorded   bool
+}
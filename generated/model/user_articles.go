@@ -16,4 +16,6 @@ type UserArticles struct {
 	UserID    int32
 	ArticleID int32
 	CreatedAt time.Time
+	Status    string
+	Preview   bool
 }
@@ -0,0 +1,23 @@
+//
+// Code generated by go-jet DO NOT EDIT.
+//
+// WARNING: Changes to this file may cause incorrect behavior
+// and will be lost if the code is regenerated
+//
+
+package model
+
+import (
+	"time"
+)
+
+type UserSessions struct {
+	ID          *int32 `sql:"primary_key"`
+	UserID      int32
+	Platform    string
+	Domain      string
+	Cookie      string
+	CreatedAt   time.Time
+	ValidatedAt *time.Time
+	ExpiredAt   *time.Time
+}
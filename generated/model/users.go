@@ -12,11 +12,17 @@ import (
 )
 
 type Users struct {
-	ID               *int32 `sql:"primary_key"`
-	Name             string
-	CreatedAt        time.Time
-	DiscordID        string
-	SubstackSession  *string
-	SubstackUsername *string
-	KindleMail       *string
+	ID                  *int32 `sql:"primary_key"`
+	Name                string
+	CreatedAt           time.Time
+	DiscordID           string
+	SubstackSession     *string
+	SubstackUsername    *string
+	KindleMail          *string
+	Format              string
+	PdfPageSize         string
+	PdfFontSize         int32
+	ReceivePreviews     bool
+	SubstackValidatedAt *time.Time
+	SubstackExpiredAt   *time.Time
 }
@@ -0,0 +1,90 @@
+//
+// Code generated by go-jet DO NOT EDIT.
+//
+// WARNING: Changes to this file may cause incorrect behavior
+// and will be lost if the code is regenerated
+//
+
+package table
+
+import (
+	"github.com/go-jet/jet/v2/sqlite"
+)
+
+var APITokens = newAPITokensTable("", "api_tokens", "")
+
+type aPITokensTable struct {
+	sqlite.Table
+
+	// Columns
+	ID         sqlite.ColumnInteger
+	UserID     sqlite.ColumnInteger
+	Scope      sqlite.ColumnString
+	TokenHash  sqlite.ColumnString
+	CreatedAt  sqlite.ColumnTimestamp
+	LastUsedAt sqlite.ColumnTimestamp
+
+	AllColumns     sqlite.ColumnList
+	MutableColumns sqlite.ColumnList
+}
+
+type APITokensTable struct {
+	aPITokensTable
+
+	EXCLUDED aPITokensTable
+}
+
+// AS creates new APITokensTable with assigned alias
+func (a APITokensTable) AS(alias string) *APITokensTable {
+	return newAPITokensTable(a.SchemaName(), a.TableName(), alias)
+}
+
+// Schema creates new APITokensTable with assigned schema name
+func (a APITokensTable) FromSchema(schemaName string) *APITokensTable {
+	return newAPITokensTable(schemaName, a.TableName(), a.Alias())
+}
+
+// WithPrefix creates new APITokensTable with assigned table prefix
+func (a APITokensTable) WithPrefix(prefix string) *APITokensTable {
+	return newAPITokensTable(a.SchemaName(), prefix+a.TableName(), a.TableName())
+}
+
+// WithSuffix creates new APITokensTable with assigned table suffix
+func (a APITokensTable) WithSuffix(suffix string) *APITokensTable {
+	return newAPITokensTable(a.SchemaName(), a.TableName()+suffix, a.TableName())
+}
+
+func newAPITokensTable(schemaName, tableName, alias string) *APITokensTable {
+	return &APITokensTable{
+		aPITokensTable: newAPITokensTableImpl(schemaName, tableName, alias),
+		EXCLUDED:       newAPITokensTableImpl("", "excluded", ""),
+	}
+}
+
+func newAPITokensTableImpl(schemaName, tableName, alias string) aPITokensTable {
+	var (
+		IDColumn         = sqlite.IntegerColumn("id")
+		UserIDColumn     = sqlite.IntegerColumn("user_id")
+		ScopeColumn      = sqlite.StringColumn("scope")
+		TokenHashColumn  = sqlite.StringColumn("token_hash")
+		CreatedAtColumn  = sqlite.TimestampColumn("created_at")
+		LastUsedAtColumn = sqlite.TimestampColumn("last_used_at")
+		allColumns       = sqlite.ColumnList{IDColumn, UserIDColumn, ScopeColumn, TokenHashColumn, CreatedAtColumn, LastUsedAtColumn}
+		mutableColumns   = sqlite.ColumnList{UserIDColumn, ScopeColumn, TokenHashColumn, CreatedAtColumn, LastUsedAtColumn}
+	)
+
+	return aPITokensTable{
+		Table: sqlite.NewTable(schemaName, tableName, alias, allColumns...),
+
+		//Columns
+		ID:         IDColumn,
+		UserID:     UserIDColumn,
+		Scope:      ScopeColumn,
+		TokenHash:  TokenHashColumn,
+		CreatedAt:  CreatedAtColumn,
+		LastUsedAt: LastUsedAtColumn,
+
+		AllColumns:     allColumns,
+		MutableColumns: mutableColumns,
+	}
+}
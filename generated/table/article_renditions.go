@@ -0,0 +1,87 @@
+//
+// Code generated by go-jet DO NOT EDIT.
+//
+// WARNING: Changes to this file may cause incorrect behavior
+// and will be lost if the code is regenerated
+//
+
+package table
+
+import (
+	"github.com/go-jet/jet/v2/sqlite"
+)
+
+var ArticleRenditions = newArticleRenditionsTable("", "article_renditions", "")
+
+type articleRenditionsTable struct {
+	sqlite.Table
+
+	// Columns
+	ID        sqlite.ColumnInteger
+	ArticleID sqlite.ColumnInteger
+	Format    sqlite.ColumnString
+	LocalPath sqlite.ColumnString
+	CreatedAt sqlite.ColumnTimestamp
+
+	AllColumns     sqlite.ColumnList
+	MutableColumns sqlite.ColumnList
+}
+
+type ArticleRenditionsTable struct {
+	articleRenditionsTable
+
+	EXCLUDED articleRenditionsTable
+}
+
+// AS creates new ArticleRenditionsTable with assigned alias
+func (a ArticleRenditionsTable) AS(alias string) *ArticleRenditionsTable {
+	return newArticleRenditionsTable(a.SchemaName(), a.TableName(), alias)
+}
+
+// Schema creates new ArticleRenditionsTable with assigned schema name
+func (a ArticleRenditionsTable) FromSchema(schemaName string) *ArticleRenditionsTable {
+	return newArticleRenditionsTable(schemaName, a.TableName(), a.Alias())
+}
+
+// WithPrefix creates new ArticleRenditionsTable with assigned table prefix
+func (a ArticleRenditionsTable) WithPrefix(prefix string) *ArticleRenditionsTable {
+	return newArticleRenditionsTable(a.SchemaName(), prefix+a.TableName(), a.TableName())
+}
+
+// WithSuffix creates new ArticleRenditionsTable with assigned table suffix
+func (a ArticleRenditionsTable) WithSuffix(suffix string) *ArticleRenditionsTable {
+	return newArticleRenditionsTable(a.SchemaName(), a.TableName()+suffix, a.TableName())
+}
+
+func newArticleRenditionsTable(schemaName, tableName, alias string) *ArticleRenditionsTable {
+	return &ArticleRenditionsTable{
+		articleRenditionsTable: newArticleRenditionsTableImpl(schemaName, tableName, alias),
+		EXCLUDED:               newArticleRenditionsTableImpl("", "excluded", ""),
+	}
+}
+
+func newArticleRenditionsTableImpl(schemaName, tableName, alias string) articleRenditionsTable {
+	var (
+		IDColumn        = sqlite.IntegerColumn("id")
+		ArticleIDColumn = sqlite.IntegerColumn("article_id")
+		FormatColumn    = sqlite.StringColumn("format")
+		LocalPathColumn = sqlite.StringColumn("local_path")
+		CreatedAtColumn = sqlite.TimestampColumn("created_at")
+		allColumns      = sqlite.ColumnList{IDColumn, ArticleIDColumn, FormatColumn, LocalPathColumn, CreatedAtColumn}
+		mutableColumns  = sqlite.ColumnList{ArticleIDColumn, FormatColumn, LocalPathColumn, CreatedAtColumn}
+	)
+
+	return articleRenditionsTable{
+		Table: sqlite.NewTable(schemaName, tableName, alias, allColumns...),
+
+		//Columns
+		ID:        IDColumn,
+		ArticleID: ArticleIDColumn,
+		Format:    FormatColumn,
+		LocalPath: LocalPathColumn,
+		CreatedAt: CreatedAtColumn,
+
+		AllColumns:     allColumns,
+		MutableColumns: mutableColumns,
+	}
+}
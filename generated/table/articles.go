@@ -17,14 +17,17 @@ type articlesTable struct {
 	sqlite.Table
 
 	// Columns
-	ID          sqlite.ColumnInteger
-	Title       sqlite.ColumnString
-	Author      sqlite.ColumnString
-	URL         sqlite.ColumnString
-	ReleaseDate sqlite.ColumnTimestamp
-	LocalPath   sqlite.ColumnString
-	CreatedAt   sqlite.ColumnTimestamp
-	Paid        sqlite.ColumnBool
+	ID           sqlite.ColumnInteger
+	Title        sqlite.ColumnString
+	Author       sqlite.ColumnString
+	URL          sqlite.ColumnString
+	ReleaseDate  sqlite.ColumnTimestamp
+	LocalPath    sqlite.ColumnString
+	CreatedAt    sqlite.ColumnTimestamp
+	Paid         sqlite.ColumnBool
+	Completeness sqlite.ColumnString
+	ScrapedBy    sqlite.ColumnInteger
+	PreviewPath  sqlite.ColumnString
 
 	AllColumns     sqlite.ColumnList
 	MutableColumns sqlite.ColumnList
@@ -65,30 +68,36 @@ func newArticlesTable(schemaName, tableName, alias string) *ArticlesTable {
 
 func newArticlesTableImpl(schemaName, tableName, alias string) articlesTable {
 	var (
-		IDColumn          = sqlite.IntegerColumn("id")
-		TitleColumn       = sqlite.StringColumn("title")
-		AuthorColumn      = sqlite.StringColumn("author")
-		URLColumn         = sqlite.StringColumn("url")
-		ReleaseDateColumn = sqlite.TimestampColumn("release_date")
-		LocalPathColumn   = sqlite.StringColumn("local_path")
-		CreatedAtColumn   = sqlite.TimestampColumn("created_at")
-		PaidColumn        = sqlite.BoolColumn("paid")
-		allColumns        = sqlite.ColumnList{IDColumn, TitleColumn, AuthorColumn, URLColumn, ReleaseDateColumn, LocalPathColumn, CreatedAtColumn, PaidColumn}
-		mutableColumns    = sqlite.ColumnList{TitleColumn, AuthorColumn, URLColumn, ReleaseDateColumn, LocalPathColumn, CreatedAtColumn, PaidColumn}
+		IDColumn           = sqlite.IntegerColumn("id")
+		TitleColumn        = sqlite.StringColumn("title")
+		AuthorColumn       = sqlite.StringColumn("author")
+		URLColumn          = sqlite.StringColumn("url")
+		ReleaseDateColumn  = sqlite.TimestampColumn("release_date")
+		LocalPathColumn    = sqlite.StringColumn("local_path")
+		CreatedAtColumn    = sqlite.TimestampColumn("created_at")
+		PaidColumn         = sqlite.BoolColumn("paid")
+		CompletenessColumn = sqlite.StringColumn("completeness")
+		ScrapedByColumn    = sqlite.IntegerColumn("scraped_by")
+		PreviewPathColumn  = sqlite.StringColumn("preview_path")
+		allColumns         = sqlite.ColumnList{IDColumn, TitleColumn, AuthorColumn, URLColumn, ReleaseDateColumn, LocalPathColumn, CreatedAtColumn, PaidColumn, CompletenessColumn, ScrapedByColumn, PreviewPathColumn}
+		mutableColumns     = sqlite.ColumnList{TitleColumn, AuthorColumn, URLColumn, ReleaseDateColumn, LocalPathColumn, CreatedAtColumn, PaidColumn, CompletenessColumn, ScrapedByColumn, PreviewPathColumn}
 	)
 
 	return articlesTable{
 		Table: sqlite.NewTable(schemaName, tableName, alias, allColumns...),
 
 		//Columns
-		ID:          IDColumn,
-		Title:       TitleColumn,
-		Author:      AuthorColumn,
-		URL:         URLColumn,
-		ReleaseDate: ReleaseDateColumn,
-		LocalPath:   LocalPathColumn,
-		CreatedAt:   CreatedAtColumn,
-		Paid:        PaidColumn,
+		ID:           IDColumn,
+		Title:        TitleColumn,
+		Author:       AuthorColumn,
+		URL:          URLColumn,
+		ReleaseDate:  ReleaseDateColumn,
+		LocalPath:    LocalPathColumn,
+		CreatedAt:    CreatedAtColumn,
+		Paid:         PaidColumn,
+		Completeness: CompletenessColumn,
+		ScrapedBy:    ScrapedByColumn,
+		PreviewPath:  PreviewPathColumn,
 
 		AllColumns:     allColumns,
 		MutableColumns: mutableColumns,
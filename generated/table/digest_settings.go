@@ -0,0 +1,90 @@
+//
+// Code generated by go-jet DO NOT EDIT.
+//
+// WARNING: Changes to this file may cause incorrect behavior
+// and will be lost if the code is regenerated
+//
+
+package table
+
+import (
+	"github.com/go-jet/jet/v2/sqlite"
+)
+
+var DigestSettings = newDigestSettingsTable("", "digest_settings", "")
+
+type digestSettingsTable struct {
+	sqlite.Table
+
+	// Columns
+	ID         sqlite.ColumnInteger
+	UserID     sqlite.ColumnInteger
+	Frequency  sqlite.ColumnString
+	LastSentAt sqlite.ColumnTimestamp
+	CreatedAt  sqlite.ColumnTimestamp
+	FailedAt   sqlite.ColumnTimestamp
+
+	AllColumns     sqlite.ColumnList
+	MutableColumns sqlite.ColumnList
+}
+
+type DigestSettingsTable struct {
+	digestSettingsTable
+
+	EXCLUDED digestSettingsTable
+}
+
+// AS creates new DigestSettingsTable with assigned alias
+func (a DigestSettingsTable) AS(alias string) *DigestSettingsTable {
+	return newDigestSettingsTable(a.SchemaName(), a.TableName(), alias)
+}
+
+// Schema creates new DigestSettingsTable with assigned schema name
+func (a DigestSettingsTable) FromSchema(schemaName string) *DigestSettingsTable {
+	return newDigestSettingsTable(schemaName, a.TableName(), a.Alias())
+}
+
+// WithPrefix creates new DigestSettingsTable with assigned table prefix
+func (a DigestSettingsTable) WithPrefix(prefix string) *DigestSettingsTable {
+	return newDigestSettingsTable(a.SchemaName(), prefix+a.TableName(), a.TableName())
+}
+
+// WithSuffix creates new DigestSettingsTable with assigned table suffix
+func (a DigestSettingsTable) WithSuffix(suffix string) *DigestSettingsTable {
+	return newDigestSettingsTable(a.SchemaName(), a.TableName()+suffix, a.TableName())
+}
+
+func newDigestSettingsTable(schemaName, tableName, alias string) *DigestSettingsTable {
+	return &DigestSettingsTable{
+		digestSettingsTable: newDigestSettingsTableImpl(schemaName, tableName, alias),
+		EXCLUDED:            newDigestSettingsTableImpl("", "excluded", ""),
+	}
+}
+
+func newDigestSettingsTableImpl(schemaName, tableName, alias string) digestSettingsTable {
+	var (
+		IDColumn         = sqlite.IntegerColumn("id")
+		UserIDColumn     = sqlite.IntegerColumn("user_id")
+		FrequencyColumn  = sqlite.StringColumn("frequency")
+		LastSentAtColumn = sqlite.TimestampColumn("last_sent_at")
+		CreatedAtColumn  = sqlite.TimestampColumn("created_at")
+		FailedAtColumn   = sqlite.TimestampColumn("failed_at")
+		allColumns       = sqlite.ColumnList{IDColumn, UserIDColumn, FrequencyColumn, LastSentAtColumn, CreatedAtColumn, FailedAtColumn}
+		mutableColumns   = sqlite.ColumnList{UserIDColumn, FrequencyColumn, LastSentAtColumn, CreatedAtColumn, FailedAtColumn}
+	)
+
+	return digestSettingsTable{
+		Table: sqlite.NewTable(schemaName, tableName, alias, allColumns...),
+
+		//Columns
+		ID:         IDColumn,
+		UserID:     UserIDColumn,
+		Frequency:  FrequencyColumn,
+		LastSentAt: LastSentAtColumn,
+		CreatedAt:  CreatedAtColumn,
+		FailedAt:   FailedAtColumn,
+
+		AllColumns:     allColumns,
+		MutableColumns: mutableColumns,
+	}
+}
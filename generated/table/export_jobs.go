@@ -0,0 +1,114 @@
+//
+// Code generated by go-jet DO NOT EDIT.
+//
+// WARNING: Changes to this file may cause incorrect behavior
+// and will be lost if the code is regenerated
+//
+
+package table
+
+import (
+	"github.com/go-jet/jet/v2/sqlite"
+)
+
+var ExportJobs = newExportJobsTable("", "export_jobs", "")
+
+type exportJobsTable struct {
+	sqlite.Table
+
+	// Columns
+	ID               sqlite.ColumnInteger
+	UserID           sqlite.ColumnInteger
+	URL              sqlite.ColumnString
+	State            sqlite.ColumnString
+	Attempts         sqlite.ColumnInteger
+	NextAttemptAt    sqlite.ColumnTimestamp
+	LastError        sqlite.ColumnString
+	Result           sqlite.ColumnString
+	ArticleID        sqlite.ColumnInteger
+	ApplicationID    sqlite.ColumnString
+	InteractionToken sqlite.ColumnString
+	CreatedAt        sqlite.ColumnTimestamp
+	UpdatedAt        sqlite.ColumnTimestamp
+	PageHTML         sqlite.ColumnString
+
+	AllColumns     sqlite.ColumnList
+	MutableColumns sqlite.ColumnList
+}
+
+type ExportJobsTable struct {
+	exportJobsTable
+
+	EXCLUDED exportJobsTable
+}
+
+// AS creates new ExportJobsTable with assigned alias
+func (a ExportJobsTable) AS(alias string) *ExportJobsTable {
+	return newExportJobsTable(a.SchemaName(), a.TableName(), alias)
+}
+
+// Schema creates new ExportJobsTable with assigned schema name
+func (a ExportJobsTable) FromSchema(schemaName string) *ExportJobsTable {
+	return newExportJobsTable(schemaName, a.TableName(), a.Alias())
+}
+
+// WithPrefix creates new ExportJobsTable with assigned table prefix
+func (a ExportJobsTable) WithPrefix(prefix string) *ExportJobsTable {
+	return newExportJobsTable(a.SchemaName(), prefix+a.TableName(), a.TableName())
+}
+
+// WithSuffix creates new ExportJobsTable with assigned table suffix
+func (a ExportJobsTable) WithSuffix(suffix string) *ExportJobsTable {
+	return newExportJobsTable(a.SchemaName(), a.TableName()+suffix, a.TableName())
+}
+
+func newExportJobsTable(schemaName, tableName, alias string) *ExportJobsTable {
+	return &ExportJobsTable{
+		exportJobsTable: newExportJobsTableImpl(schemaName, tableName, alias),
+		EXCLUDED:        newExportJobsTableImpl("", "excluded", ""),
+	}
+}
+
+func newExportJobsTableImpl(schemaName, tableName, alias string) exportJobsTable {
+	var (
+		IDColumn               = sqlite.IntegerColumn("id")
+		UserIDColumn           = sqlite.IntegerColumn("user_id")
+		URLColumn              = sqlite.StringColumn("url")
+		StateColumn            = sqlite.StringColumn("state")
+		AttemptsColumn         = sqlite.IntegerColumn("attempts")
+		NextAttemptAtColumn    = sqlite.TimestampColumn("next_attempt_at")
+		LastErrorColumn        = sqlite.StringColumn("last_error")
+		ResultColumn           = sqlite.StringColumn("result")
+		ArticleIDColumn        = sqlite.IntegerColumn("article_id")
+		ApplicationIDColumn    = sqlite.StringColumn("application_id")
+		InteractionTokenColumn = sqlite.StringColumn("interaction_token")
+		CreatedAtColumn        = sqlite.TimestampColumn("created_at")
+		UpdatedAtColumn        = sqlite.TimestampColumn("updated_at")
+		PageHTMLColumn         = sqlite.StringColumn("page_html")
+		allColumns             = sqlite.ColumnList{IDColumn, UserIDColumn, URLColumn, StateColumn, AttemptsColumn, NextAttemptAtColumn, LastErrorColumn, ResultColumn, ArticleIDColumn, ApplicationIDColumn, InteractionTokenColumn, CreatedAtColumn, UpdatedAtColumn, PageHTMLColumn}
+		mutableColumns         = sqlite.ColumnList{UserIDColumn, URLColumn, StateColumn, AttemptsColumn, NextAttemptAtColumn, LastErrorColumn, ResultColumn, ArticleIDColumn, ApplicationIDColumn, InteractionTokenColumn, CreatedAtColumn, UpdatedAtColumn, PageHTMLColumn}
+	)
+
+	return exportJobsTable{
+		Table: sqlite.NewTable(schemaName, tableName, alias, allColumns...),
+
+		//Columns
+		ID:               IDColumn,
+		UserID:           UserIDColumn,
+		URL:              URLColumn,
+		State:            StateColumn,
+		Attempts:         AttemptsColumn,
+		NextAttemptAt:    NextAttemptAtColumn,
+		LastError:        LastErrorColumn,
+		Result:           ResultColumn,
+		ArticleID:        ArticleIDColumn,
+		ApplicationID:    ApplicationIDColumn,
+		InteractionToken: InteractionTokenColumn,
+		CreatedAt:        CreatedAtColumn,
+		UpdatedAt:        UpdatedAtColumn,
+		PageHTML:         PageHTMLColumn,
+
+		AllColumns:     allColumns,
+		MutableColumns: mutableColumns,
+	}
+}
@@ -0,0 +1,84 @@
+//
+// Code generated by go-jet DO NOT EDIT.
+//
+// WARNING: Changes to this file may cause incorrect behavior
+// and will be lost if the code is regenerated
+//
+
+package table
+
+import (
+	"github.com/go-jet/jet/v2/sqlite"
+)
+
+var SubscriptionItems = newSubscriptionItemsTable("", "subscription_items", "")
+
+type subscriptionItemsTable struct {
+	sqlite.Table
+
+	// Columns
+	ID             sqlite.ColumnInteger
+	SubscriptionID sqlite.ColumnInteger
+	URL            sqlite.ColumnString
+	CreatedAt      sqlite.ColumnTimestamp
+
+	AllColumns     sqlite.ColumnList
+	MutableColumns sqlite.ColumnList
+}
+
+type SubscriptionItemsTable struct {
+	subscriptionItemsTable
+
+	EXCLUDED subscriptionItemsTable
+}
+
+// AS creates new SubscriptionItemsTable with assigned alias
+func (a SubscriptionItemsTable) AS(alias string) *SubscriptionItemsTable {
+	return newSubscriptionItemsTable(a.SchemaName(), a.TableName(), alias)
+}
+
+// Schema creates new SubscriptionItemsTable with assigned schema name
+func (a SubscriptionItemsTable) FromSchema(schemaName string) *SubscriptionItemsTable {
+	return newSubscriptionItemsTable(schemaName, a.TableName(), a.Alias())
+}
+
+// WithPrefix creates new SubscriptionItemsTable with assigned table prefix
+func (a SubscriptionItemsTable) WithPrefix(prefix string) *SubscriptionItemsTable {
+	return newSubscriptionItemsTable(a.SchemaName(), prefix+a.TableName(), a.TableName())
+}
+
+// WithSuffix creates new SubscriptionItemsTable with assigned table suffix
+func (a SubscriptionItemsTable) WithSuffix(suffix string) *SubscriptionItemsTable {
+	return newSubscriptionItemsTable(a.SchemaName(), a.TableName()+suffix, a.TableName())
+}
+
+func newSubscriptionItemsTable(schemaName, tableName, alias string) *SubscriptionItemsTable {
+	return &SubscriptionItemsTable{
+		subscriptionItemsTable: newSubscriptionItemsTableImpl(schemaName, tableName, alias),
+		EXCLUDED:               newSubscriptionItemsTableImpl("", "excluded", ""),
+	}
+}
+
+func newSubscriptionItemsTableImpl(schemaName, tableName, alias string) subscriptionItemsTable {
+	var (
+		IDColumn             = sqlite.IntegerColumn("id")
+		SubscriptionIDColumn = sqlite.IntegerColumn("subscription_id")
+		URLColumn            = sqlite.StringColumn("url")
+		CreatedAtColumn      = sqlite.TimestampColumn("created_at")
+		allColumns           = sqlite.ColumnList{IDColumn, SubscriptionIDColumn, URLColumn, CreatedAtColumn}
+		mutableColumns       = sqlite.ColumnList{SubscriptionIDColumn, URLColumn, CreatedAtColumn}
+	)
+
+	return subscriptionItemsTable{
+		Table: sqlite.NewTable(schemaName, tableName, alias, allColumns...),
+
+		//Columns
+		ID:             IDColumn,
+		SubscriptionID: SubscriptionIDColumn,
+		URL:            URLColumn,
+		CreatedAt:      CreatedAtColumn,
+
+		AllColumns:     allColumns,
+		MutableColumns: mutableColumns,
+	}
+}
@@ -0,0 +1,96 @@
+//
+// Code generated by go-jet DO NOT EDIT.
+//
+// WARNING: Changes to this file may cause incorrect behavior
+// and will be lost if the code is regenerated
+//
+
+package table
+
+import (
+	"github.com/go-jet/jet/v2/sqlite"
+)
+
+var Subscriptions = newSubscriptionsTable("", "subscriptions", "")
+
+type subscriptionsTable struct {
+	sqlite.Table
+
+	// Columns
+	ID              sqlite.ColumnInteger
+	UserID          sqlite.ColumnInteger
+	FeedURL         sqlite.ColumnString
+	Title           sqlite.ColumnString
+	LastPublishedAt sqlite.ColumnTimestamp
+	LastCheckedAt   sqlite.ColumnTimestamp
+	CreatedAt       sqlite.ColumnTimestamp
+	ItemsRecorded   sqlite.ColumnBool
+
+	AllColumns     sqlite.ColumnList
+	MutableColumns sqlite.ColumnList
+}
+
+type SubscriptionsTable struct {
+	subscriptionsTable
+
+	EXCLUDED subscriptionsTable
+}
+
+// AS creates new SubscriptionsTable with assigned alias
+func (a SubscriptionsTable) AS(alias string) *SubscriptionsTable {
+	return newSubscriptionsTable(a.SchemaName(), a.TableName(), alias)
+}
+
+// Schema creates new SubscriptionsTable with assigned schema name
+func (a SubscriptionsTable) FromSchema(schemaName string) *SubscriptionsTable {
+	return newSubscriptionsTable(schemaName, a.TableName(), a.Alias())
+}
+
+// WithPrefix creates new SubscriptionsTable with assigned table prefix
+func (a SubscriptionsTable) WithPrefix(prefix string) *SubscriptionsTable {
+	return newSubscriptionsTable(a.SchemaName(), prefix+a.TableName(), a.TableName())
+}
+
+// WithSuffix creates new SubscriptionsTable with assigned table suffix
+func (a SubscriptionsTable) WithSuffix(suffix string) *SubscriptionsTable {
+	return newSubscriptionsTable(a.SchemaName(), a.TableName()+suffix, a.TableName())
+}
+
+func newSubscriptionsTable(schemaName, tableName, alias string) *SubscriptionsTable {
+	return &SubscriptionsTable{
+		subscriptionsTable: newSubscriptionsTableImpl(schemaName, tableName, alias),
+		EXCLUDED:           newSubscriptionsTableImpl("", "excluded", ""),
+	}
+}
+
+func newSubscriptionsTableImpl(schemaName, tableName, alias string) subscriptionsTable {
+	var (
+		IDColumn              = sqlite.IntegerColumn("id")
+		UserIDColumn          = sqlite.IntegerColumn("user_id")
+		FeedURLColumn         = sqlite.StringColumn("feed_url")
+		TitleColumn           = sqlite.StringColumn("title")
+		LastPublishedAtColumn = sqlite.TimestampColumn("last_published_at")
+		LastCheckedAtColumn   = sqlite.TimestampColumn("last_checked_at")
+		CreatedAtColumn       = sqlite.TimestampColumn("created_at")
+		ItemsRecordedColumn   = sqlite.BoolColumn("items_recorded")
+		allColumns            = sqlite.ColumnList{IDColumn, UserIDColumn, FeedURLColumn, TitleColumn, LastPublishedAtColumn, LastCheckedAtColumn, CreatedAtColumn, ItemsRecordedColumn}
+		mutableColumns        = sqlite.ColumnList{UserIDColumn, FeedURLColumn, TitleColumn, LastPublishedAtColumn, LastCheckedAtColumn, CreatedAtColumn, ItemsRecordedColumn}
+	)
+
+	return subscriptionsTable{
+		Table: sqlite.NewTable(schemaName, tableName, alias, allColumns...),
+
+		//Columns
+		ID:              IDColumn,
+		UserID:          UserIDColumn,
+		FeedURL:         FeedURLColumn,
+		Title:           TitleColumn,
+		LastPublishedAt: LastPublishedAtColumn,
+		LastCheckedAt:   LastCheckedAtColumn,
+		CreatedAt:       CreatedAtColumn,
+		ItemsRecorded:   ItemsRecordedColumn,
+
+		AllColumns:     allColumns,
+		MutableColumns: mutableColumns,
+	}
+}
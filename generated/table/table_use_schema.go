@@ -10,7 +10,14 @@ package table
 // UseSchema sets a new schema name for all generated table SQL builder types. It is recommended to invoke
 // this method only once at the beginning of the program.
 func UseSchema(schema string) {
+	APITokens = APITokens.FromSchema(schema)
+	ArticleRenditions = ArticleRenditions.FromSchema(schema)
 	Articles = Articles.FromSchema(schema)
+	DigestSettings = DigestSettings.FromSchema(schema)
+	ExportJobs = ExportJobs.FromSchema(schema)
+	SubscriptionItems = SubscriptionItems.FromSchema(schema)
+	Subscriptions = Subscriptions.FromSchema(schema)
 	UserArticles = UserArticles.FromSchema(schema)
+	UserSessions = UserSessions.FromSchema(schema)
 	Users = Users.FromSchema(schema)
 }
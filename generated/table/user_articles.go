@@ -21,6 +21,8 @@ type userArticlesTable struct {
 	UserID    sqlite.ColumnInteger
 	ArticleID sqlite.ColumnInteger
 	CreatedAt sqlite.ColumnTimestamp
+	Status    sqlite.ColumnString
+	Preview   sqlite.ColumnBool
 
 	AllColumns     sqlite.ColumnList
 	MutableColumns sqlite.ColumnList
@@ -65,8 +67,10 @@ func newUserArticlesTableImpl(schemaName, tableName, alias string) userArticlesT
 		UserIDColumn    = sqlite.IntegerColumn("user_id")
 		ArticleIDColumn = sqlite.IntegerColumn("article_id")
 		CreatedAtColumn = sqlite.TimestampColumn("created_at")
-		allColumns      = sqlite.ColumnList{IDColumn, UserIDColumn, ArticleIDColumn, CreatedAtColumn}
-		mutableColumns  = sqlite.ColumnList{UserIDColumn, ArticleIDColumn, CreatedAtColumn}
+		StatusColumn    = sqlite.StringColumn("status")
+		PreviewColumn   = sqlite.BoolColumn("preview")
+		allColumns      = sqlite.ColumnList{IDColumn, UserIDColumn, ArticleIDColumn, CreatedAtColumn, StatusColumn, PreviewColumn}
+		mutableColumns  = sqlite.ColumnList{UserIDColumn, ArticleIDColumn, CreatedAtColumn, StatusColumn, PreviewColumn}
 	)
 
 	return userArticlesTable{
@@ -77,6 +81,8 @@ func newUserArticlesTableImpl(schemaName, tableName, alias string) userArticlesT
 		UserID:    UserIDColumn,
 		ArticleID: ArticleIDColumn,
 		CreatedAt: CreatedAtColumn,
+		Status:    StatusColumn,
+		Preview:   PreviewColumn,
 
 		AllColumns:     allColumns,
 		MutableColumns: mutableColumns,
@@ -0,0 +1,96 @@
+//
+// Code generated by go-jet DO NOT EDIT.
+//
+// WARNING: Changes to this file may cause incorrect behavior
+// and will be lost if the code is regenerated
+//
+
+package table
+
+import (
+	"github.com/go-jet/jet/v2/sqlite"
+)
+
+var UserSessions = newUserSessionsTable("", "user_sessions", "")
+
+type userSessionsTable struct {
+	sqlite.Table
+
+	// Columns
+	ID          sqlite.ColumnInteger
+	UserID      sqlite.ColumnInteger
+	Platform    sqlite.ColumnString
+	Domain      sqlite.ColumnString
+	Cookie      sqlite.ColumnString
+	CreatedAt   sqlite.ColumnTimestamp
+	ValidatedAt sqlite.ColumnTimestamp
+	ExpiredAt   sqlite.ColumnTimestamp
+
+	AllColumns     sqlite.ColumnList
+	MutableColumns sqlite.ColumnList
+}
+
+type UserSessionsTable struct {
+	userSessionsTable
+
+	EXCLUDED userSessionsTable
+}
+
+// AS creates new UserSessionsTable with assigned alias
+func (a UserSessionsTable) AS(alias string) *UserSessionsTable {
+	return newUserSessionsTable(a.SchemaName(), a.TableName(), alias)
+}
+
+// Schema creates new UserSessionsTable with assigned schema name
+func (a UserSessionsTable) FromSchema(schemaName string) *UserSessionsTable {
+	return newUserSessionsTable(schemaName, a.TableName(), a.Alias())
+}
+
+// WithPrefix creates new UserSessionsTable with assigned table prefix
+func (a UserSessionsTable) WithPrefix(prefix string) *UserSessionsTable {
+	return newUserSessionsTable(a.SchemaName(), prefix+a.TableName(), a.TableName())
+}
+
+// WithSuffix creates new UserSessionsTable with assigned table suffix
+func (a UserSessionsTable) WithSuffix(suffix string) *UserSessionsTable {
+	return newUserSessionsTable(a.SchemaName(), a.TableName()+suffix, a.TableName())
+}
+
+func newUserSessionsTable(schemaName, tableName, alias string) *UserSessionsTable {
+	return &UserSessionsTable{
+		userSessionsTable: newUserSessionsTableImpl(schemaName, tableName, alias),
+		EXCLUDED:          newUserSessionsTableImpl("", "excluded", ""),
+	}
+}
+
+func newUserSessionsTableImpl(schemaName, tableName, alias string) userSessionsTable {
+	var (
+		IDColumn          = sqlite.IntegerColumn("id")
+		UserIDColumn      = sqlite.IntegerColumn("user_id")
+		PlatformColumn    = sqlite.StringColumn("platform")
+		DomainColumn      = sqlite.StringColumn("domain")
+		CookieColumn      = sqlite.StringColumn("cookie")
+		CreatedAtColumn   = sqlite.TimestampColumn("created_at")
+		ValidatedAtColumn = sqlite.TimestampColumn("validated_at")
+		ExpiredAtColumn   = sqlite.TimestampColumn("expired_at")
+		allColumns        = sqlite.ColumnList{IDColumn, UserIDColumn, PlatformColumn, DomainColumn, CookieColumn, CreatedAtColumn, ValidatedAtColumn, ExpiredAtColumn}
+		mutableColumns    = sqlite.ColumnList{UserIDColumn, PlatformColumn, DomainColumn, CookieColumn, CreatedAtColumn, ValidatedAtColumn, ExpiredAtColumn}
+	)
+
+	return userSessionsTable{
+		Table: sqlite.NewTable(schemaName, tableName, alias, allColumns...),
+
+		//Columns
+		ID:          IDColumn,
+		UserID:      UserIDColumn,
+		Platform:    PlatformColumn,
+		Domain:      DomainColumn,
+		Cookie:      CookieColumn,
+		CreatedAt:   CreatedAtColumn,
+		ValidatedAt: ValidatedAtColumn,
+		ExpiredAt:   ExpiredAtColumn,
+
+		AllColumns:     allColumns,
+		MutableColumns: mutableColumns,
+	}
+}
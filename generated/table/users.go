@@ -17,13 +17,19 @@ type usersTable struct {
 	sqlite.Table
 
 	// Columns
-	ID               sqlite.ColumnInteger
-	Name             sqlite.ColumnString
-	CreatedAt        sqlite.ColumnTimestamp
-	DiscordID        sqlite.ColumnString
-	SubstackSession  sqlite.ColumnString
-	SubstackUsername sqlite.ColumnString
-	KindleMail       sqlite.ColumnString
+	ID                  sqlite.ColumnInteger
+	Name                sqlite.ColumnString
+	CreatedAt           sqlite.ColumnTimestamp
+	DiscordID           sqlite.ColumnString
+	SubstackSession     sqlite.ColumnString
+	SubstackUsername    sqlite.ColumnString
+	KindleMail          sqlite.ColumnString
+	Format              sqlite.ColumnString
+	PdfPageSize         sqlite.ColumnString
+	PdfFontSize         sqlite.ColumnInteger
+	ReceivePreviews     sqlite.ColumnBool
+	SubstackValidatedAt sqlite.ColumnTimestamp
+	SubstackExpiredAt   sqlite.ColumnTimestamp
 
 	AllColumns     sqlite.ColumnList
 	MutableColumns sqlite.ColumnList
@@ -64,28 +70,40 @@ func newUsersTable(schemaName, tableName, alias string) *UsersTable {
 
 func newUsersTableImpl(schemaName, tableName, alias string) usersTable {
 	var (
-		IDColumn               = sqlite.IntegerColumn("id")
-		NameColumn             = sqlite.StringColumn("name")
-		CreatedAtColumn        = sqlite.TimestampColumn("created_at")
-		DiscordIDColumn        = sqlite.StringColumn("discord_id")
-		SubstackSessionColumn  = sqlite.StringColumn("substack_session")
-		SubstackUsernameColumn = sqlite.StringColumn("substack_username")
-		KindleMailColumn       = sqlite.StringColumn("kindle_mail")
-		allColumns             = sqlite.ColumnList{IDColumn, NameColumn, CreatedAtColumn, DiscordIDColumn, SubstackSessionColumn, SubstackUsernameColumn, KindleMailColumn}
-		mutableColumns         = sqlite.ColumnList{NameColumn, CreatedAtColumn, DiscordIDColumn, SubstackSessionColumn, SubstackUsernameColumn, KindleMailColumn}
+		IDColumn                  = sqlite.IntegerColumn("id")
+		NameColumn                = sqlite.StringColumn("name")
+		CreatedAtColumn           = sqlite.TimestampColumn("created_at")
+		DiscordIDColumn           = sqlite.StringColumn("discord_id")
+		SubstackSessionColumn     = sqlite.StringColumn("substack_session")
+		SubstackUsernameColumn    = sqlite.StringColumn("substack_username")
+		KindleMailColumn          = sqlite.StringColumn("kindle_mail")
+		FormatColumn              = sqlite.StringColumn("format")
+		PdfPageSizeColumn         = sqlite.StringColumn("pdf_page_size")
+		PdfFontSizeColumn         = sqlite.IntegerColumn("pdf_font_size")
+		ReceivePreviewsColumn     = sqlite.BoolColumn("receive_previews")
+		SubstackValidatedAtColumn = sqlite.TimestampColumn("substack_validated_at")
+		SubstackExpiredAtColumn   = sqlite.TimestampColumn("substack_expired_at")
+		allColumns                = sqlite.ColumnList{IDColumn, NameColumn, CreatedAtColumn, DiscordIDColumn, SubstackSessionColumn, SubstackUsernameColumn, KindleMailColumn, FormatColumn, PdfPageSizeColumn, PdfFontSizeColumn, ReceivePreviewsColumn, SubstackValidatedAtColumn, SubstackExpiredAtColumn}
+		mutableColumns            = sqlite.ColumnList{NameColumn, CreatedAtColumn, DiscordIDColumn, SubstackSessionColumn, SubstackUsernameColumn, KindleMailColumn, FormatColumn, PdfPageSizeColumn, PdfFontSizeColumn, ReceivePreviewsColumn, SubstackValidatedAtColumn, SubstackExpiredAtColumn}
 	)
 
 	return usersTable{
 		Table: sqlite.NewTable(schemaName, tableName, alias, allColumns...),
 
 		//Columns
-		ID:               IDColumn,
-		Name:             NameColumn,
-		CreatedAt:        CreatedAtColumn,
-		DiscordID:        DiscordIDColumn,
-		SubstackSession:  SubstackSessionColumn,
-		SubstackUsername: SubstackUsernameColumn,
-		KindleMail:       KindleMailColumn,
+		ID:                  IDColumn,
+		Name:                NameColumn,
+		CreatedAt:           CreatedAtColumn,
+		DiscordID:           DiscordIDColumn,
+		SubstackSession:     SubstackSessionColumn,
+		SubstackUsername:    SubstackUsernameColumn,
+		KindleMail:          KindleMailColumn,
+		Format:              FormatColumn,
+		PdfPageSize:         PdfPageSizeColumn,
+		PdfFontSize:         PdfFontSizeColumn,
+		ReceivePreviews:     ReceivePreviewsColumn,
+		SubstackValidatedAt: SubstackValidatedAtColumn,
+		SubstackExpiredAt:   SubstackExpiredAtColumn,
 
 		AllColumns:     allColumns,
 		MutableColumns: mutableColumns,
@@ -1,11 +1,14 @@
 package config
 
 import (
+	"encoding/base64"
 	"errors"
+	"fmt"
 	"os"
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 )
 
 // Config is a struct that holds the configuration for the application
@@ -24,33 +27,83 @@ type Config struct {
 	MailUser string
 	// MailPassword is the password for the mail server
 	MailPassword string
+	// FeedPollInterval is the time between two checks of the subscribed feeds
+	FeedPollInterval time.Duration
+	// SessionCheckInterval is the time between two validations of the stored Substack sessions
+	SessionCheckInterval time.Duration
+	// ExportWorkers is the amount of exports that are processed at the same time
+	ExportWorkers int
+	// EncryptionKey is the key that protects the data keys of the stored credentials
+	EncryptionKey []byte
+	// HTTPAddress is the address the HTTP server listens on, the server is disabled if it is empty
+	HTTPAddress string
+	// PublicURL is the URL under which the HTTP server is reachable for the users
+	PublicURL string
+	// SharedCatalog lists the free articles of all users in the OPDS catalog of every user
+	SharedCatalog bool
+}
+
+// StorageConfig is the part of the configuration needed to open the database and its credentials.
+// Maintenance commands only need this part, so it does not require the bot settings.
+type StorageConfig struct {
+	// DatabasePath is the path to the sqlite database
+	DatabasePath string
+	// EncryptionKey is the key that protects the data keys of the stored credentials
+	EncryptionKey []byte
 }
 
 var (
 	instance  *Config
 	once      sync.Once
 	initError error
+
+	storageInstance  *StorageConfig
+	storageOnce      sync.Once
+	storageInitError error
 )
 
+// GetStorageConfig returns the database path and encryption key of the application
+func GetStorageConfig() (*StorageConfig, error) {
+	storageOnce.Do(func() {
+		storageInstance = &StorageConfig{
+			DatabasePath: "./kindExport.sqlite",
+		}
+		if os.Getenv("DATABASE_PATH") != "" {
+			storageInstance.DatabasePath = os.Getenv("DATABASE_PATH")
+		}
+		if os.Getenv("ENCRYPTION_KEY") == "" {
+			storageInitError = errors.New("ENCRYPTION_KEY is not set, it is required")
+			return
+		}
+		key, err := ParseKey(os.Getenv("ENCRYPTION_KEY"))
+		if err != nil {
+			storageInitError = fmt.Errorf("ENCRYPTION_KEY is invalid: %w", err)
+			return
+		}
+		storageInstance.EncryptionKey = key
+	})
+	return storageInstance, storageInitError
+}
+
 // GetConfig returns the configuration for the application
 func GetConfig() (*Config, error) {
 	if instance == nil {
 		once.Do(func() {
 			instance = &Config{
-				DatabasePath:    "./kindExport.sqlite",
 				OutputDirectory: "./output",
 				DiscordToken:    "",
 				MailServer:      "",
 				MailPort:        587,
 				MailUser:        "",
 				MailPassword:    "",
+
+				FeedPollInterval:     30 * time.Minute,
+				SessionCheckInterval: 24 * time.Hour,
+				ExportWorkers:        2,
 			}
 			if os.Getenv("OUTPUT_DIRECTORY") != "" {
 				instance.OutputDirectory = strings.TrimRight(os.Getenv("OUTPUT_DIRECTORY"), "/")
 			}
-			if os.Getenv("DATABASE_PATH") != "" {
-				instance.DatabasePath = os.Getenv("DATABASE_PATH")
-			}
 			if os.Getenv("DISCORD_TOKEN") != "" {
 				instance.DiscordToken = os.Getenv("DISCORD_TOKEN")
 			} else {
@@ -83,7 +136,68 @@ func GetConfig() (*Config, error) {
 				initError = errors.New("MAIL_PASSWORD is not set, it is required")
 				return
 			}
+			if os.Getenv("FEED_POLL_INTERVAL") != "" {
+				interval, err := time.ParseDuration(os.Getenv("FEED_POLL_INTERVAL"))
+				if err != nil || interval <= 0 {
+					initError = errors.New("FEED_POLL_INTERVAL is not a valid duration")
+					return
+				}
+				instance.FeedPollInterval = interval
+			}
+			if os.Getenv("SESSION_CHECK_INTERVAL") != "" {
+				interval, err := time.ParseDuration(os.Getenv("SESSION_CHECK_INTERVAL"))
+				if err != nil || interval <= 0 {
+					initError = errors.New("SESSION_CHECK_INTERVAL is not a valid duration")
+					return
+				}
+				instance.SessionCheckInterval = interval
+			}
+			if os.Getenv("EXPORT_WORKERS") != "" {
+				workers, err := strconv.Atoi(os.Getenv("EXPORT_WORKERS"))
+				if err != nil || workers <= 0 {
+					initError = errors.New("EXPORT_WORKERS is not a valid number")
+					return
+				}
+				instance.ExportWorkers = workers
+			}
+			if os.Getenv("HTTP_ADDRESS") != "" {
+				instance.HTTPAddress = os.Getenv("HTTP_ADDRESS")
+				instance.PublicURL = "http://" + instance.HTTPAddress
+				if strings.HasPrefix(instance.HTTPAddress, ":") {
+					instance.PublicURL = "http://localhost" + instance.HTTPAddress
+				}
+			}
+			if os.Getenv("SHARED_CATALOG") != "" {
+				shared, err := strconv.ParseBool(os.Getenv("SHARED_CATALOG"))
+				if err != nil {
+					initError = errors.New("SHARED_CATALOG is not a valid boolean")
+					return
+				}
+				instance.SharedCatalog = shared
+			}
+			if os.Getenv("PUBLIC_URL") != "" {
+				instance.PublicURL = strings.TrimRight(os.Getenv("PUBLIC_URL"), "/")
+			}
+			storage, err := GetStorageConfig()
+			if err != nil {
+				initError = err
+				return
+			}
+			instance.DatabasePath = storage.DatabasePath
+			instance.EncryptionKey = storage.EncryptionKey
 		})
 	}
 	return instance, initError
 }
+
+// ParseKey decodes a base64 encoded 256 bit encryption key
+func ParseKey(value string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(value))
+	if err != nil {
+		return nil, errors.New("the key is not valid base64")
+	}
+	if len(key) != 32 {
+		return nil, fmt.Errorf("the key has %d bytes instead of 32", len(key))
+	}
+	return key, nil
+}
@@ -0,0 +1,284 @@
+package db
+
+import (
+	"github.com/go-jet/jet/v2/sqlite"
+	"kindExport/generated/model"
+	"kindExport/internal/scrape"
+	"strings"
+
+	. "kindExport/generated/table"
+)
+
+// InsertBook stores the scraped book and returns the id of the new article.
+// scrapedBy is the user whose sessions were used to scrape it.
+func InsertBook(book scrape.Book, scrapedBy int32) (int32, error) {
+	db, err := GetDB()
+	if err != nil {
+		return 0, err
+	}
+
+	result, err := Articles.
+		INSERT(Articles.Title, Articles.LocalPath, Articles.URL, Articles.Paid, Articles.Author, Articles.ReleaseDate, Articles.Completeness, Articles.ScrapedBy).
+		VALUES(book.Book.Title(), book.Path, book.Permalink, book.Paid, book.Book.Author(), book.ReleaseDate, book.Completeness, scrapedBy).
+		Exec(db)
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := result.LastInsertId()
+	return int32(id), err
+}
+
+// GetArticle returns the article with the given id, nil if it does not exist
+func GetArticle(id int32) (*model.Articles, error) {
+	db, err := GetDB()
+	if err != nil {
+		return nil, err
+	}
+
+	var articles []model.Articles
+	err = sqlite.SELECT(
+		Articles.AllColumns,
+	).FROM(
+		Articles,
+	).WHERE(
+		Articles.ID.EQ(sqlite.Int32(id)),
+	).LIMIT(1).Query(db, &articles)
+	if err != nil || len(articles) == 0 {
+		return nil, err
+	}
+	return &articles[0], nil
+}
+
+// GetArticleByURL returns the article stored for the normalized URL, nil if it was not exported yet
+func GetArticleByURL(url string) (*model.Articles, error) {
+	db, err := GetDB()
+	if err != nil {
+		return nil, err
+	}
+
+	var articles []model.Articles
+	err = sqlite.SELECT(
+		Articles.AllColumns,
+	).FROM(
+		Articles,
+	).WHERE(
+		Articles.URL.EQ(sqlite.String(url)),
+	).LIMIT(1).Query(db, &articles)
+	if err != nil {
+		return nil, err
+	}
+	if len(articles) == 0 {
+		return nil, nil
+	}
+	return &articles[0], nil
+}
+
+const (
+	// StatusFetched is used for articles that were exported but not sent yet
+	StatusFetched = "fetched"
+	// StatusSent is used for articles that were sent to the kindle mail address
+	StatusSent = "sent"
+	// StatusFailed is used for articles that could not be sent
+	StatusFailed = "failed"
+	// StatusSkippedPaywall is used for articles the user has no access to
+	StatusSkippedPaywall = "skipped-paywall"
+)
+
+// HistoryEntry is an article that was exported for a user
+type HistoryEntry struct {
+	model.UserArticles
+	Article model.Articles
+}
+
+// Accessible returns whether the user may receive the article, users who only received the preview get the preview.
+// Paid articles are withheld from users whose sessions could not read them.
+func (e HistoryEntry) Accessible() bool {
+	return !e.Article.Paid || e.Status != StatusSkippedPaywall
+}
+
+// Path returns the stored epub the user received, empty if the user only received the preview and it is gone
+func (e HistoryEntry) Path() string {
+	if e.Preview {
+		return PreviewPath(e.Article)
+	}
+	return e.Article.LocalPath
+}
+
+// RecordUserArticle records that the article was exported for the user with the outcome of the delivery.
+// preview is set if the user only received the preview of the article.
+// Exporting an article again moves it to the current time, so it is part of the next digest.
+func RecordUserArticle(userID int32, articleID int32, status string, preview bool) error {
+	db, err := GetDB()
+	if err != nil {
+		return err
+	}
+
+	_, err = UserArticles.
+		INSERT(UserArticles.UserID, UserArticles.ArticleID, UserArticles.Status, UserArticles.Preview).
+		VALUES(userID, articleID, status, preview).
+		ON_CONFLICT(UserArticles.UserID, UserArticles.ArticleID).
+		DO_UPDATE(sqlite.SET(
+			UserArticles.CreatedAt.SET(sqlite.CURRENT_TIMESTAMP()),
+			UserArticles.Status.SET(UserArticles.EXCLUDED.Status),
+			UserArticles.Preview.SET(UserArticles.EXCLUDED.Preview),
+		)).
+		Exec(db)
+	return err
+}
+
+// SetUserArticlesStatus updates the delivery status of articles of the user without changing their export time
+func SetUserArticlesStatus(userID int32, articleIDs []int32, status string) error {
+	if len(articleIDs) == 0 {
+		return nil
+	}
+	db, err := GetDB()
+	if err != nil {
+		return err
+	}
+
+	ids := make([]sqlite.Expression, 0, len(articleIDs))
+	for _, id := range articleIDs {
+		ids = append(ids, sqlite.Int32(id))
+	}
+	_, err = UserArticles.
+		UPDATE(UserArticles.Status).
+		SET(status).
+		WHERE(UserArticles.UserID.EQ(sqlite.Int32(userID)).AND(UserArticles.ArticleID.IN(ids...))).
+		Exec(db)
+	return err
+}
+
+// GetHistory returns the articles exported for the user, newest first
+func GetHistory(userID int32, limit int64, offset int64) ([]HistoryEntry, error) {
+	db, err := GetDB()
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []HistoryEntry
+	err = sqlite.SELECT(
+		UserArticles.AllColumns,
+		Articles.AllColumns,
+	).FROM(
+		UserArticles.INNER_JOIN(Articles, Articles.ID.EQ(UserArticles.ArticleID)),
+	).WHERE(
+		UserArticles.UserID.EQ(sqlite.Int32(userID)),
+	).ORDER_BY(
+		UserArticles.CreatedAt.DESC(),
+		UserArticles.ID.DESC(),
+	).LIMIT(limit).OFFSET(offset).Query(db, &entries)
+	return entries, err
+}
+
+// CountHistory returns the amount of articles exported for the user
+func CountHistory(userID int32) (int64, error) {
+	db, err := GetDB()
+	if err != nil {
+		return 0, err
+	}
+
+	var result struct {
+		Count int64
+	}
+	err = sqlite.SELECT(
+		sqlite.COUNT(UserArticles.ID).AS("count"),
+	).FROM(
+		UserArticles,
+	).WHERE(
+		UserArticles.UserID.EQ(sqlite.Int32(userID)),
+	).Query(db, &result)
+	return result.Count, err
+}
+
+// SearchHistory returns the articles exported for the user whose title contains the query, newest first
+func SearchHistory(userID int32, query string, limit int64) ([]HistoryEntry, error) {
+	db, err := GetDB()
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []HistoryEntry
+	err = sqlite.SELECT(
+		UserArticles.AllColumns,
+		Articles.AllColumns,
+	).FROM(
+		UserArticles.INNER_JOIN(Articles, Articles.ID.EQ(UserArticles.ArticleID)),
+	).WHERE(
+		UserArticles.UserID.EQ(sqlite.Int32(userID)).
+			AND(sqlite.LOWER(Articles.Title).LIKE(sqlite.String("%"+strings.ToLower(query)+"%"))),
+	).ORDER_BY(
+		UserArticles.CreatedAt.DESC(),
+		UserArticles.ID.DESC(),
+	).LIMIT(limit).Query(db, &entries)
+	return entries, err
+}
+
+// GetHistoryEntry returns the article if it was exported for the user, nil otherwise
+func GetHistoryEntry(userID int32, articleID int32) (*HistoryEntry, error) {
+	db, err := GetDB()
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []HistoryEntry
+	err = sqlite.SELECT(
+		UserArticles.AllColumns,
+		Articles.AllColumns,
+	).FROM(
+		UserArticles.INNER_JOIN(Articles, Articles.ID.EQ(UserArticles.ArticleID)),
+	).WHERE(
+		UserArticles.UserID.EQ(sqlite.Int32(userID)).
+			AND(Articles.ID.EQ(sqlite.Int32(articleID))),
+	).LIMIT(1).Query(db, &entries)
+	if err != nil || len(entries) == 0 {
+		return nil, err
+	}
+	return &entries[0], nil
+}
+
+// UpdateArticleBook replaces the stored epub of an article with a freshly scraped one.
+// The renditions of the previous epub are removed, so they are converted again.
+func UpdateArticleBook(articleID int32, book scrape.Book, scrapedBy int32) error {
+	if err := deleteRenditions(articleID); err != nil {
+		return err
+	}
+
+	db, err := GetDB()
+	if err != nil {
+		return err
+	}
+
+	_, err = Articles.
+		UPDATE(Articles.Title, Articles.LocalPath, Articles.Paid, Articles.Author, Articles.ReleaseDate, Articles.Completeness, Articles.ScrapedBy).
+		SET(book.Book.Title(), book.Path, book.Paid, book.Book.Author(), book.ReleaseDate, book.Completeness, scrapedBy).
+		WHERE(Articles.ID.EQ(sqlite.Int32(articleID))).
+		Exec(db)
+	return err
+}
+
+// SetArticlePreview stores the preview of an article whose stored epub contains the full article
+func SetArticlePreview(articleID int32, previewPath string) error {
+	db, err := GetDB()
+	if err != nil {
+		return err
+	}
+
+	_, err = Articles.
+		UPDATE(Articles.PreviewPath).
+		SET(previewPath).
+		WHERE(Articles.ID.EQ(sqlite.Int32(articleID))).
+		Exec(db)
+	return err
+}
+
+// PreviewPath returns the stored preview of the article, empty if there is none
+func PreviewPath(article model.Articles) string {
+	if article.Completeness == scrape.CompletenessPreview {
+		return article.LocalPath
+	}
+	if article.PreviewPath != nil {
+		return *article.PreviewPath
+	}
+	return ""
+}
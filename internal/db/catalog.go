@@ -0,0 +1,185 @@
+package db
+
+import (
+	"github.com/go-jet/jet/v2/sqlite"
+	"kindExport/generated/model"
+	"net/url"
+	"sort"
+	"unicode/utf8"
+
+	. "kindExport/generated/table"
+)
+
+// CatalogFilter selects the articles of a catalog feed.
+// Users see the articles that were exported for them, paid articles only if they had access.
+type CatalogFilter struct {
+	UserID int32
+	// Shared includes the free articles other users exported, except for the shelf
+	Shared bool
+	// Shelf only includes the articles that were exported for the user
+	Shelf bool
+	// Author only includes the articles of the author if set
+	Author string
+	// Publication only includes the articles of the host if set
+	Publication string
+}
+
+// CatalogGroup is an author or publication with the amount of its articles
+type CatalogGroup struct {
+	Name  string
+	Count int64
+}
+
+func (f CatalogFilter) condition() sqlite.BoolExpression {
+	exported := Articles.ID.IN(
+		sqlite.SELECT(UserArticles.ArticleID).
+			FROM(UserArticles).
+			WHERE(UserArticles.UserID.EQ(sqlite.Int32(f.UserID))),
+	)
+	// Paid articles are only offered to users whose sessions could read them completely
+	accessible := Articles.ID.IN(
+		sqlite.SELECT(UserArticles.ArticleID).
+			FROM(UserArticles).
+			WHERE(UserArticles.UserID.EQ(sqlite.Int32(f.UserID)).
+				AND(UserArticles.Status.NOT_EQ(sqlite.String(StatusSkippedPaywall))).
+				AND(UserArticles.Preview.EQ(sqlite.Bool(false)))),
+	)
+	condition := Articles.Paid.EQ(sqlite.Bool(false)).OR(accessible)
+	if f.Shelf || !f.Shared {
+		condition = exported.AND(condition)
+	}
+	if f.Author != "" {
+		condition = condition.AND(Articles.Author.EQ(sqlite.String(f.Author)))
+	}
+	if f.Publication != "" {
+		// Article URLs are normalized to https. The prefix is compared literally, so wildcards in the
+		// publication do not match other hosts. substr counts characters, not bytes.
+		prefix := "https://" + f.Publication + "/"
+		length := sqlite.Int(int64(utf8.RuneCountInString(prefix)))
+		condition = condition.AND(sqlite.SUBSTR(Articles.URL, sqlite.Int(1), length).EQ(sqlite.String(prefix)))
+	}
+	return condition
+}
+
+// GetCatalogArticles returns the matching articles, the most recently exported first
+func GetCatalogArticles(filter CatalogFilter, limit int64, offset int64) ([]model.Articles, error) {
+	db, err := GetDB()
+	if err != nil {
+		return nil, err
+	}
+
+	var articles []model.Articles
+	err = sqlite.SELECT(
+		Articles.AllColumns,
+	).FROM(
+		Articles,
+	).WHERE(
+		filter.condition(),
+	).ORDER_BY(
+		Articles.CreatedAt.DESC(),
+		Articles.ID.DESC(),
+	).LIMIT(limit).OFFSET(offset).Query(db, &articles)
+	return articles, err
+}
+
+// CountCatalogArticles returns the amount of matching articles
+func CountCatalogArticles(filter CatalogFilter) (int64, error) {
+	db, err := GetDB()
+	if err != nil {
+		return 0, err
+	}
+
+	var result struct {
+		Count int64
+	}
+	err = sqlite.SELECT(
+		sqlite.COUNT(Articles.ID).AS("count"),
+	).FROM(
+		Articles,
+	).WHERE(
+		filter.condition(),
+	).Query(db, &result)
+	return result.Count, err
+}
+
+// GetCatalogArticle returns the article if it is part of the catalog selected by the filter, nil otherwise
+func GetCatalogArticle(filter CatalogFilter, articleID int32) (*model.Articles, error) {
+	db, err := GetDB()
+	if err != nil {
+		return nil, err
+	}
+
+	var articles []model.Articles
+	err = sqlite.SELECT(
+		Articles.AllColumns,
+	).FROM(
+		Articles,
+	).WHERE(
+		Articles.ID.EQ(sqlite.Int32(articleID)).AND(filter.condition()),
+	).LIMIT(1).Query(db, &articles)
+	if err != nil || len(articles) == 0 {
+		return nil, err
+	}
+	return &articles[0], nil
+}
+
+// GetCatalogAuthors returns the authors of the articles in the catalog selected by the filter
+func GetCatalogAuthors(filter CatalogFilter) ([]CatalogGroup, error) {
+	db, err := GetDB()
+	if err != nil {
+		return nil, err
+	}
+
+	var groups []CatalogGroup
+	err = sqlite.SELECT(
+		Articles.Author.AS("catalog_group.name"),
+		sqlite.COUNT(Articles.ID).AS("catalog_group.count"),
+	).FROM(
+		Articles,
+	).WHERE(
+		filter.condition(),
+	).GROUP_BY(
+		Articles.Author,
+	).ORDER_BY(
+		Articles.Author.ASC(),
+	).Query(db, &groups)
+	return groups, err
+}
+
+// GetCatalogPublications returns the hosts of the articles in the catalog selected by the filter
+func GetCatalogPublications(filter CatalogFilter) ([]CatalogGroup, error) {
+	db, err := GetDB()
+	if err != nil {
+		return nil, err
+	}
+
+	var articles []model.Articles
+	err = sqlite.SELECT(
+		Articles.URL,
+	).FROM(
+		Articles,
+	).WHERE(
+		filter.condition(),
+	).Query(db, &articles)
+	if err != nil {
+		return nil, err
+	}
+
+	// sqlite cannot extract the host of a URL, so the articles are grouped here
+	counts := map[string]int64{}
+	for _, article := range articles {
+		parsed, err := url.Parse(article.URL)
+		if err != nil || parsed.Host == "" {
+			continue
+		}
+		counts[parsed.Host]++
+	}
+	groups := make([]CatalogGroup, 0, len(counts))
+	for host, count := range counts {
+		groups = append(groups, CatalogGroup{Name: host, Count: count})
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		return groups[i].Name < groups[j].Name
+	})
+	return groups, nil
+}
@@ -0,0 +1,41 @@
+package db
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCatalogPublication(t *testing.T) {
+	db := useTestDB(t)
+	for _, url := range []string{
+		"https://a_b.example.com/p/first",
+		"https://axb.example.com/p/second",
+		"https://a_b.example.com.evil.com/p/third",
+		"https://blog.bücher.de/p/fourth",
+	} {
+		_, err := db.Exec("INSERT INTO articles (title, author, url, release_date, local_path) VALUES (?, 'Jane', ?, ?, '/books/article.epub')", url, url, time.Now())
+		if err != nil {
+			t.Fatalf("inserting the article failed: %s", err)
+		}
+	}
+
+	tests := []struct {
+		publication string
+		want        int64
+	}{
+		{publication: "a_b.example.com", want: 1},
+		{publication: "a%", want: 0},
+		{publication: "%", want: 0},
+		{publication: `a\_b.example.com`, want: 0},
+		{publication: "blog.bücher.de", want: 1},
+	}
+	for _, test := range tests {
+		count, err := CountCatalogArticles(CatalogFilter{UserID: 1, Shared: true, Publication: test.publication})
+		if err != nil {
+			t.Fatalf("CountCatalogArticles failed: %s", err)
+		}
+		if count != test.want {
+			t.Errorf("publication %q has %d articles, want %d", test.publication, count, test.want)
+		}
+	}
+}
@@ -0,0 +1,260 @@
+package db
+
+import (
+	"database/sql"
+	"github.com/go-jet/jet/v2/qrm"
+	"github.com/go-jet/jet/v2/sqlite"
+	"kindExport/generated/model"
+	"kindExport/internal/config"
+	"kindExport/internal/scrape"
+	"kindExport/internal/secret"
+
+	. "kindExport/generated/table"
+)
+
+// SetSubstackSession stores the encrypted Substack session cookie of the user after it was validated.
+// The username is the handle of the account the cookie belongs to, it is left empty if it is unknown.
+func SetSubstackSession(userID int32, cookie string, username string) error {
+	db, err := GetDB()
+	if err != nil {
+		return err
+	}
+
+	encrypted, err := secret.Encrypt(cookie)
+	if err != nil {
+		return err
+	}
+	_, err = Users.
+		UPDATE(Users.SubstackSession, Users.SubstackUsername, Users.SubstackValidatedAt, Users.SubstackExpiredAt).
+		SET(encrypted, nullableString(username), sqlite.CURRENT_TIMESTAMP(), sqlite.NULL).
+		WHERE(Users.ID.EQ(sqlite.Int32(userID))).
+		Exec(db)
+	return err
+}
+
+// MarkSubstackSessionValid records that Substack still accepts the stored session cookie of the user.
+// An empty username keeps the stored one.
+func MarkSubstackSessionValid(userID int32, username string) error {
+	db, err := GetDB()
+	if err != nil {
+		return err
+	}
+
+	_, err = Users.
+		UPDATE(Users.SubstackUsername, Users.SubstackValidatedAt, Users.SubstackExpiredAt).
+		SET(sqlite.COALESCE(nullableString(username), Users.SubstackUsername), sqlite.CURRENT_TIMESTAMP(), sqlite.NULL).
+		WHERE(Users.ID.EQ(sqlite.Int32(userID))).
+		Exec(db)
+	return err
+}
+
+// MarkSubstackSessionExpired records that Substack rejected the stored session cookie of the user
+func MarkSubstackSessionExpired(userID int32) error {
+	db, err := GetDB()
+	if err != nil {
+		return err
+	}
+
+	_, err = Users.
+		UPDATE(Users.SubstackExpiredAt).
+		SET(sqlite.CURRENT_TIMESTAMP()).
+		WHERE(Users.ID.EQ(sqlite.Int32(userID))).
+		Exec(db)
+	return err
+}
+
+// GetUsersWithSubstackSession returns the users whose stored Substack session has not expired yet
+func GetUsersWithSubstackSession() ([]model.Users, error) {
+	db, err := GetDB()
+	if err != nil {
+		return nil, err
+	}
+
+	var users []model.Users
+	err = sqlite.SELECT(
+		Users.AllColumns,
+	).FROM(
+		Users,
+	).WHERE(
+		Users.SubstackSession.IS_NOT_NULL().
+			AND(Users.SubstackSession.NOT_EQ(sqlite.String(""))).
+			AND(Users.SubstackExpiredAt.IS_NULL()),
+	).Query(db, &users)
+	return users, err
+}
+
+// PublicationSession is a session stored for a single publication together with its owner
+type PublicationSession struct {
+	model.UserSessions
+	User model.Users
+}
+
+// GetSubstackPublicationSessions returns the sessions of Substack publications on custom domains that have not expired yet
+func GetSubstackPublicationSessions() ([]PublicationSession, error) {
+	db, err := GetDB()
+	if err != nil {
+		return nil, err
+	}
+
+	var sessions []PublicationSession
+	err = sqlite.SELECT(
+		UserSessions.AllColumns,
+		Users.AllColumns,
+	).FROM(
+		UserSessions.INNER_JOIN(Users, Users.ID.EQ(UserSessions.UserID)),
+	).WHERE(
+		UserSessions.Platform.EQ(sqlite.String(scrape.PlatformSubstack)).
+			AND(UserSessions.Domain.NOT_EQ(sqlite.String(""))).
+			AND(UserSessions.ExpiredAt.IS_NULL()),
+	).Query(db, &sessions)
+	return sessions, err
+}
+
+// MarkSessionValid records that the platform still accepts the stored session cookie
+func MarkSessionValid(id int32) error {
+	db, err := GetDB()
+	if err != nil {
+		return err
+	}
+
+	_, err = UserSessions.
+		UPDATE(UserSessions.ValidatedAt, UserSessions.ExpiredAt).
+		SET(sqlite.CURRENT_TIMESTAMP(), sqlite.NULL).
+		WHERE(UserSessions.ID.EQ(sqlite.Int32(id))).
+		Exec(db)
+	return err
+}
+
+// MarkSessionExpired records that the platform rejected the stored session cookie
+func MarkSessionExpired(id int32) error {
+	db, err := GetDB()
+	if err != nil {
+		return err
+	}
+
+	_, err = UserSessions.
+		UPDATE(UserSessions.ExpiredAt).
+		SET(sqlite.CURRENT_TIMESTAMP()).
+		WHERE(UserSessions.ID.EQ(sqlite.Int32(id))).
+		Exec(db)
+	return err
+}
+
+// RemoveSubstackSession deletes the Substack session cookie of the user
+func RemoveSubstackSession(userID int32) error {
+	db, err := GetDB()
+	if err != nil {
+		return err
+	}
+
+	_, err = Users.
+		UPDATE(Users.SubstackSession, Users.SubstackUsername, Users.SubstackValidatedAt, Users.SubstackExpiredAt).
+		SET(sqlite.NULL, sqlite.NULL, sqlite.NULL, sqlite.NULL).
+		WHERE(Users.ID.EQ(sqlite.Int32(userID))).
+		Exec(db)
+	return err
+}
+
+// RotateEncryptionKey re-encrypts the data keys of all stored credentials with the new key.
+// It returns the amount of updated credentials.
+func RotateEncryptionKey(newKey []byte) (int, error) {
+	conf, err := config.GetStorageConfig()
+	if err != nil {
+		return 0, err
+	}
+	db, err := GetDB()
+	if err != nil {
+		return 0, err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	count, err := updateCredentials(tx, func(value string) (string, error) {
+		return secret.Rewrap(conf.EncryptionKey, newKey, value)
+	})
+	if err != nil {
+		return 0, err
+	}
+	return count, tx.Commit()
+}
+
+// encryptCredentials encrypts the credentials that were stored in plaintext before
+func encryptCredentials(tx *sql.Tx) error {
+	_, err := updateCredentials(tx, func(value string) (string, error) {
+		if secret.IsEncrypted(value) {
+			return value, nil
+		}
+		return secret.Encrypt(value)
+	})
+	return err
+}
+
+// updateCredentials replaces every stored credential with the result of the update function
+func updateCredentials(tx qrm.DB, update func(value string) (string, error)) (int, error) {
+	count := 0
+
+	var users []model.Users
+	err := sqlite.SELECT(
+		Users.ID, Users.SubstackSession,
+	).FROM(
+		Users,
+	).WHERE(
+		Users.SubstackSession.IS_NOT_NULL().AND(Users.SubstackSession.NOT_EQ(sqlite.String(""))),
+	).Query(tx, &users)
+	if err != nil {
+		return 0, err
+	}
+	for _, user := range users {
+		value, err := update(*user.SubstackSession)
+		if err != nil {
+			return 0, err
+		}
+		_, err = Users.
+			UPDATE(Users.SubstackSession).
+			SET(value).
+			WHERE(Users.ID.EQ(sqlite.Int32(*user.ID))).
+			Exec(tx)
+		if err != nil {
+			return 0, err
+		}
+		count++
+	}
+
+	var sessions []model.UserSessions
+	err = sqlite.SELECT(
+		UserSessions.ID, UserSessions.Cookie,
+	).FROM(
+		UserSessions,
+	).Query(tx, &sessions)
+	if err != nil {
+		return 0, err
+	}
+	for _, session := range sessions {
+		value, err := update(session.Cookie)
+		if err != nil {
+			return 0, err
+		}
+		_, err = UserSessions.
+			UPDATE(UserSessions.Cookie).
+			SET(value).
+			WHERE(UserSessions.ID.EQ(sqlite.Int32(*session.ID))).
+			Exec(tx)
+		if err != nil {
+			return 0, err
+		}
+		count++
+	}
+	return count, nil
+}
+
+// nullableString returns NULL for empty strings
+func nullableString(value string) sqlite.Expression {
+	if value == "" {
+		return sqlite.NULL
+	}
+	return sqlite.String(value)
+}
@@ -3,12 +3,8 @@ package db
 import (
 	"database/sql"
 	"kindExport/internal/config"
-	"kindExport/internal/scrape"
-	"os"
 	"sync"
 
-	. "kindExport/generated/table"
-
 	_ "modernc.org/sqlite"
 )
 
@@ -28,27 +24,9 @@ func GetDB() (*sql.DB, error) {
 	return instance, initErr
 }
 
-func InsertBook(book scrape.Book) {
-	db, err := GetDB()
-	if err != nil {
-		return
-	}
-
-	_ = db
-
-	_, err = Articles.
-		INSERT(Articles.Title, Articles.LocalPath, Articles.URL, Articles.Paid, Articles.Author, Articles.ReleaseDate).
-		VALUES(book.Book.Title(), book.Path, book.Permalink, book.Paid, book.Book.Author(), book.ReleaseDate).
-		Exec(db)
-
-	if err != nil {
-		return
-	}
-}
-
 // initDB creates the initial database connection
 func initDB() (*sql.DB, error) {
-	cfg, err := config.GetConfig()
+	cfg, err := config.GetStorageConfig()
 	if err != nil {
 		return nil, err
 	}
@@ -59,19 +37,11 @@ func initDB() (*sql.DB, error) {
 	}
 
 	_, err = db.Exec("PRAGMA journal_mode=WAL;")
-	// Check whether we find the initial tables file
-	for _, file := range []string{"./tables_initial.sql", "./sql/tables_initial.sql"} {
-		content, err := os.ReadFile(file)
-		if err != nil {
-			continue
-		}
-		_, err = db.Exec(string(content))
-		if err != nil {
-			return nil, err
-		}
-		break
+	if err != nil {
+		return nil, err
 	}
 
+	err = migrate(db)
 	if err != nil {
 		return nil, err
 	}
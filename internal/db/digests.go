@@ -0,0 +1,149 @@
+package db
+
+import (
+	"github.com/go-jet/jet/v2/sqlite"
+	"kindExport/generated/model"
+	"time"
+
+	. "kindExport/generated/table"
+)
+
+// GetDigestSettings returns the digest settings of the user, nil if the user receives every article on its own
+func GetDigestSettings(userID int32) (*model.DigestSettings, error) {
+	db, err := GetDB()
+	if err != nil {
+		return nil, err
+	}
+
+	var settings []model.DigestSettings
+	err = sqlite.SELECT(
+		DigestSettings.AllColumns,
+	).FROM(
+		DigestSettings,
+	).WHERE(
+		DigestSettings.UserID.EQ(sqlite.Int32(userID)),
+	).LIMIT(1).Query(db, &settings)
+	if err != nil {
+		return nil, err
+	}
+	if len(settings) == 0 {
+		return nil, nil
+	}
+	return &settings[0], nil
+}
+
+// GetAllDigestSettings returns the digest settings of all users
+func GetAllDigestSettings() ([]model.DigestSettings, error) {
+	db, err := GetDB()
+	if err != nil {
+		return nil, err
+	}
+
+	var settings []model.DigestSettings
+	err = sqlite.SELECT(
+		DigestSettings.AllColumns,
+	).FROM(
+		DigestSettings,
+	).Query(db, &settings)
+	return settings, err
+}
+
+// SetDigestFrequency enables the digest of the user, the first digest covers the articles from now on.
+// An empty frequency disables the digest.
+func SetDigestFrequency(userID int32, frequency string) error {
+	db, err := GetDB()
+	if err != nil {
+		return err
+	}
+
+	if frequency == "" {
+		_, err = DigestSettings.
+			DELETE().
+			WHERE(DigestSettings.UserID.EQ(sqlite.Int32(userID))).
+			Exec(db)
+		return err
+	}
+
+	_, err = DigestSettings.
+		INSERT(DigestSettings.UserID, DigestSettings.Frequency, DigestSettings.LastSentAt).
+		VALUES(userID, frequency, time.Now().UTC()).
+		ON_CONFLICT(DigestSettings.UserID).
+		DO_UPDATE(sqlite.SET(
+			DigestSettings.Frequency.SET(DigestSettings.EXCLUDED.Frequency),
+		)).
+		Exec(db)
+	return err
+}
+
+// MarkDigestSent stores when the last digest was sent to the user, earlier failures are cleared
+func MarkDigestSent(id int32, sentAt time.Time) error {
+	db, err := GetDB()
+	if err != nil {
+		return err
+	}
+
+	_, err = DigestSettings.
+		UPDATE(DigestSettings.LastSentAt, DigestSettings.FailedAt).
+		SET(sentAt.UTC(), sqlite.NULL).
+		WHERE(DigestSettings.ID.EQ(sqlite.Int32(id))).
+		Exec(db)
+	return err
+}
+
+// MarkDigestFailed stores when sending the digest failed, the articles are kept for the next attempt
+func MarkDigestFailed(id int32, failedAt time.Time) error {
+	db, err := GetDB()
+	if err != nil {
+		return err
+	}
+
+	_, err = DigestSettings.
+		UPDATE(DigestSettings.FailedAt).
+		SET(failedAt.UTC()).
+		WHERE(DigestSettings.ID.EQ(sqlite.Int32(id))).
+		Exec(db)
+	return err
+}
+
+// GetArticlesSince returns the articles that were exported for the user after the given time, oldest first.
+// Articles that were withheld because of a paywall are left out, the local path of articles
+// the user only received the preview of points to the preview.
+func GetArticlesSince(userID int32, since time.Time) ([]model.Articles, error) {
+	db, err := GetDB()
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []HistoryEntry
+	err = sqlite.SELECT(
+		UserArticles.AllColumns,
+		Articles.AllColumns,
+	).FROM(
+		UserArticles.INNER_JOIN(Articles, Articles.ID.EQ(UserArticles.ArticleID)),
+	).WHERE(
+		UserArticles.UserID.EQ(sqlite.Int32(userID)).
+			AND(UserArticles.CreatedAt.GT(timestamp(since))).
+			AND(UserArticles.Status.NOT_EQ(sqlite.String(StatusSkippedPaywall))),
+	).ORDER_BY(
+		UserArticles.CreatedAt,
+	).Query(db, &entries)
+	if err != nil {
+		return nil, err
+	}
+
+	articles := make([]model.Articles, 0, len(entries))
+	for _, entry := range entries {
+		if entry.Path() == "" {
+			continue
+		}
+		entry.Article.LocalPath = entry.Path()
+		articles = append(articles, entry.Article)
+	}
+	return articles, nil
+}
+
+// timestamp converts the time into a literal that can be compared with columns defaulting to current_timestamp
+func timestamp(t time.Time) sqlite.DateTimeExpression {
+	t = t.UTC()
+	return sqlite.DateTime(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second())
+}
@@ -0,0 +1,84 @@
+package db
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+)
+
+// useTestDB replaces the database returned by GetDB with a migrated one for the duration of the test
+func useTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	db := openTestDB(t)
+	previous := instance
+	instance = db
+	t.Cleanup(func() { instance = previous })
+	return db
+}
+
+func TestGetArticlesSince(t *testing.T) {
+	db := useTestDB(t)
+	since := time.Date(2026, 10, 12, 6, 0, 0, 0, time.UTC)
+
+	insert := func(query string, args ...any) int32 {
+		t.Helper()
+		result, err := db.Exec(query, args...)
+		if err != nil {
+			t.Fatalf("inserting test data failed: %s", err)
+		}
+		id, _ := result.LastInsertId()
+		return int32(id)
+	}
+	user := insert("INSERT INTO users (name, discord_id) VALUES ('reader', '1')")
+	other := insert("INSERT INTO users (name, discord_id) VALUES ('other', '2')")
+	article := func(title string, completeness string, previewPath any) int32 {
+		return insert("INSERT INTO articles (title, author, url, release_date, local_path, completeness, preview_path) VALUES (?, 'Jane', ?, ?, ?, ?, ?)",
+			title, "https://example.com/p/"+title, since, "/books/"+title+".epub", completeness, previewPath)
+	}
+	record := func(userID int32, articleID int32, offset time.Duration, status string, preview bool) {
+		insert("INSERT INTO user_articles (user_id, article_id, created_at, status, preview) VALUES (?, ?, ?, ?, ?)",
+			userID, articleID, since.Add(offset).Format("2006-01-02 15:04:05"), status, preview)
+	}
+
+	before := article("before", "full", nil)
+	sent := article("sent", "full", nil)
+	fetched := article("fetched", "full", nil)
+	withheld := article("withheld", "preview", nil)
+	preview := article("preview", "full", "/books/preview-preview.epub")
+	missingPreview := article("missing-preview", "full", nil)
+	foreign := article("foreign", "full", nil)
+
+	record(user, before, -time.Hour, StatusSent, false)
+	record(user, sent, 2*time.Hour, StatusSent, false)
+	record(user, fetched, time.Hour, StatusFetched, false)
+	record(user, withheld, 3*time.Hour, StatusSkippedPaywall, true)
+	record(user, preview, 4*time.Hour, StatusFetched, true)
+	record(user, missingPreview, 5*time.Hour, StatusFetched, true)
+	record(other, foreign, 2*time.Hour, StatusFetched, false)
+	record(other, sent, 2*time.Hour, StatusFetched, false)
+
+	articles, err := GetArticlesSince(user, since)
+	if err != nil {
+		t.Fatalf("GetArticlesSince failed: %s", err)
+	}
+	want := []struct {
+		title string
+		path  string
+	}{
+		{title: "fetched", path: "/books/fetched.epub"},
+		{title: "sent", path: "/books/sent.epub"},
+		{title: "preview", path: "/books/preview-preview.epub"},
+	}
+	if len(articles) != len(want) {
+		var titles []string
+		for _, a := range articles {
+			titles = append(titles, a.Title)
+		}
+		t.Fatalf("GetArticlesSince() returned %v, want %d articles", titles, len(want))
+	}
+	for i, w := range want {
+		if articles[i].Title != w.title || articles[i].LocalPath != w.path {
+			t.Errorf("article %d = %s at %s, want %s at %s", i, articles[i].Title, articles[i].LocalPath, w.title, w.path)
+		}
+	}
+}
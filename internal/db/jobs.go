@@ -0,0 +1,181 @@
+package db
+
+import (
+	"github.com/go-jet/jet/v2/sqlite"
+	"kindExport/generated/model"
+	"time"
+
+	. "kindExport/generated/table"
+)
+
+const (
+	JobQueued  = "queued"
+	JobRunning = "running"
+	JobDone    = "done"
+	JobFailed  = "failed"
+)
+
+// InsertExportJob queues the export of the URL for the user and returns the id of the job.
+// The interaction is empty for jobs that were not created by a command, the page HTML is empty unless the user supplied it.
+func InsertExportJob(userID int32, url string, applicationID string, interactionToken string, pageHTML string) (int32, error) {
+	db, err := GetDB()
+	if err != nil {
+		return 0, err
+	}
+
+	columns := sqlite.ColumnList{ExportJobs.UserID, ExportJobs.URL}
+	values := []interface{}{userID, url}
+	if applicationID != "" && interactionToken != "" {
+		columns = append(columns, ExportJobs.ApplicationID, ExportJobs.InteractionToken)
+		values = append(values, applicationID, interactionToken)
+	}
+	if pageHTML != "" {
+		columns = append(columns, ExportJobs.PageHTML)
+		values = append(values, pageHTML)
+	}
+	result, err := ExportJobs.
+		INSERT(columns).
+		VALUES(values[0], values[1:]...).
+		Exec(db)
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := result.LastInsertId()
+	return int32(id), err
+}
+
+// GetExportJob returns the job with the given id, nil if it does not exist
+func GetExportJob(id int32) (*model.ExportJobs, error) {
+	db, err := GetDB()
+	if err != nil {
+		return nil, err
+	}
+
+	var jobs []model.ExportJobs
+	err = sqlite.SELECT(
+		ExportJobs.AllColumns,
+	).FROM(
+		ExportJobs,
+	).WHERE(
+		ExportJobs.ID.EQ(sqlite.Int32(id)),
+	).LIMIT(1).Query(db, &jobs)
+	if err != nil || len(jobs) == 0 {
+		return nil, err
+	}
+	return &jobs[0], nil
+}
+
+// ClaimExportJob marks the next due job as running and returns it, nil if no job is due
+func ClaimExportJob() (*model.ExportJobs, error) {
+	db, err := GetDB()
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var jobs []model.ExportJobs
+	err = sqlite.SELECT(
+		ExportJobs.AllColumns,
+	).FROM(
+		ExportJobs,
+	).WHERE(
+		ExportJobs.State.EQ(sqlite.String(JobQueued)).
+			AND(ExportJobs.NextAttemptAt.LT_EQ(timestamp(time.Now()))),
+	).ORDER_BY(
+		ExportJobs.NextAttemptAt.ASC(),
+		ExportJobs.ID.ASC(),
+	).LIMIT(1).Query(tx, &jobs)
+	if err != nil || len(jobs) == 0 {
+		return nil, err
+	}
+	job := jobs[0]
+
+	_, err = ExportJobs.
+		UPDATE().
+		SET(
+			ExportJobs.State.SET(sqlite.String(JobRunning)),
+			ExportJobs.Attempts.SET(ExportJobs.Attempts.ADD(sqlite.Int32(1))),
+			ExportJobs.UpdatedAt.SET(sqlite.CURRENT_TIMESTAMP()),
+		).
+		WHERE(ExportJobs.ID.EQ(sqlite.Int32(*job.ID))).
+		Exec(tx)
+	if err != nil {
+		return nil, err
+	}
+	job.State = JobRunning
+	job.Attempts++
+	return &job, tx.Commit()
+}
+
+// RequeueRunningExportJobs queues the jobs again that were interrupted by a restart
+func RequeueRunningExportJobs() (int64, error) {
+	db, err := GetDB()
+	if err != nil {
+		return 0, err
+	}
+
+	result, err := ExportJobs.
+		UPDATE().
+		SET(
+			ExportJobs.State.SET(sqlite.String(JobQueued)),
+			ExportJobs.UpdatedAt.SET(sqlite.CURRENT_TIMESTAMP()),
+		).
+		WHERE(ExportJobs.State.EQ(sqlite.String(JobRunning))).
+		Exec(db)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// RetryExportJob queues the job again for the given time after a failed attempt
+func RetryExportJob(id int32, nextAttempt time.Time, lastError string) error {
+	db, err := GetDB()
+	if err != nil {
+		return err
+	}
+
+	_, err = ExportJobs.
+		UPDATE().
+		SET(
+			ExportJobs.State.SET(sqlite.String(JobQueued)),
+			ExportJobs.NextAttemptAt.SET(timestamp(nextAttempt)),
+			ExportJobs.LastError.SET(sqlite.String(lastError)),
+			ExportJobs.UpdatedAt.SET(sqlite.CURRENT_TIMESTAMP()),
+		).
+		WHERE(ExportJobs.ID.EQ(sqlite.Int32(id))).
+		Exec(db)
+	return err
+}
+
+// FinishExportJob stores the final state of the job together with the message shown to the user.
+// The supplied page is not needed anymore and removed.
+func FinishExportJob(id int32, state string, result string, articleID *int32) error {
+	db, err := GetDB()
+	if err != nil {
+		return err
+	}
+
+	articleValue := sqlite.Expression(sqlite.NULL)
+	if articleID != nil {
+		articleValue = sqlite.Int32(*articleID)
+	}
+	_, err = ExportJobs.
+		UPDATE().
+		SET(
+			ExportJobs.State.SET(sqlite.String(state)),
+			ExportJobs.Result.SET(sqlite.String(result)),
+			ExportJobs.ArticleID.SET(sqlite.IntExp(articleValue)),
+			ExportJobs.PageHTML.SET(sqlite.StringExp(sqlite.NULL)),
+			ExportJobs.UpdatedAt.SET(sqlite.CURRENT_TIMESTAMP()),
+		).
+		WHERE(ExportJobs.ID.EQ(sqlite.Int32(id))).
+		Exec(db)
+	return err
+}
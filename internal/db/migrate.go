@@ -0,0 +1,168 @@
+package db
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"embed"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// migrationFiles contains the schema changes, named <version>_<description>.sql
+//
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// codeMigrations are the changes that cannot be expressed in SQL, they are ordered together with the files
+var codeMigrations = []migration{
+	{Version: 6, Name: "0006_encrypt_credentials", Up: encryptCredentials},
+}
+
+// migration is a single schema change which is applied once
+type migration struct {
+	Version  int
+	Name     string
+	SQL      string
+	Checksum string
+	// Up is executed instead of SQL for migrations written in Go
+	Up func(tx *sql.Tx) error
+}
+
+// loadMigrations reads the embedded migrations ordered by their version
+func loadMigrations() ([]migration, error) {
+	files, err := fs.Glob(migrationFiles, "migrations/*.sql")
+	if err != nil {
+		return nil, err
+	}
+
+	var migrations []migration
+	versions := map[int]string{}
+	for _, m := range codeMigrations {
+		checksum := sha256.Sum256([]byte(m.Name))
+		m.Checksum = hex.EncodeToString(checksum[:])
+		versions[m.Version] = m.Name
+		migrations = append(migrations, m)
+	}
+	for _, file := range files {
+		name := strings.TrimSuffix(strings.TrimPrefix(file, "migrations/"), ".sql")
+		versionPart, _, _ := strings.Cut(name, "_")
+		version, err := strconv.Atoi(versionPart)
+		if err != nil {
+			return nil, fmt.Errorf("migration %s does not start with a version", name)
+		}
+		if other, ok := versions[version]; ok {
+			return nil, fmt.Errorf("migrations %s and %s share version %d", other, name, version)
+		}
+		versions[version] = name
+
+		content, err := migrationFiles.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		checksum := sha256.Sum256(content)
+		migrations = append(migrations, migration{
+			Version:  version,
+			Name:     name,
+			SQL:      string(content),
+			Checksum: hex.EncodeToString(checksum[:]),
+		})
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations, nil
+}
+
+// migrate brings the schema up to date. It fails if an applied migration was changed afterwards
+// or if the database was migrated by a newer version of the binary.
+func migrate(db *sql.DB) error {
+	migrations, err := loadMigrations()
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(`create table if not exists schema_migrations
+(
+    version    integer primary key,
+    name       varchar   not null,
+    checksum   varchar   not null,
+    applied_at timestamp not null default current_timestamp
+)`)
+	if err != nil {
+		return err
+	}
+
+	applied := map[int]string{}
+	rows, err := db.Query("SELECT version, checksum FROM schema_migrations")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var version int
+		var checksum string
+		if err := rows.Scan(&version, &checksum); err != nil {
+			return err
+		}
+		applied[version] = checksum
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	known := map[int]bool{}
+	latest := 0
+	for _, m := range migrations {
+		known[m.Version] = true
+		latest = max(latest, m.Version)
+	}
+	for version := range applied {
+		if !known[version] {
+			return fmt.Errorf("the database contains migration %d which is unknown to this binary (latest is %d), refusing to start", version, latest)
+		}
+	}
+
+	for _, m := range migrations {
+		checksum, ok := applied[m.Version]
+		if ok {
+			if checksum != m.Checksum {
+				return fmt.Errorf("migration %s was changed after it has been applied", m.Name)
+			}
+			continue
+		}
+		err = applyMigration(db, m)
+		if err != nil {
+			return fmt.Errorf("applying migration %s failed: %w", m.Name, err)
+		}
+		log.Printf("Applied migration %s", m.Name)
+	}
+	return nil
+}
+
+// applyMigration executes the migration and records it in a single transaction
+func applyMigration(db *sql.DB, m migration) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if m.Up != nil {
+		err = m.Up(tx)
+	} else {
+		_, err = tx.Exec(m.SQL)
+	}
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec("INSERT INTO schema_migrations (version, name, checksum) VALUES (?, ?, ?)", m.Version, m.Name, m.Checksum)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,98 @@
+package db
+
+import (
+	"database/sql"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// openTestDB returns a migrated database in the temporary directory of the test
+func openTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "test.sqlite"))
+	if err != nil {
+		t.Fatalf("opening the database failed: %s", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := migrate(db); err != nil {
+		t.Fatalf("migrate failed: %s", err)
+	}
+	return db
+}
+
+func TestLoadMigrations(t *testing.T) {
+	migrations, err := loadMigrations()
+	if err != nil {
+		t.Fatalf("loadMigrations failed: %s", err)
+	}
+	for i, m := range migrations {
+		if i > 0 && m.Version <= migrations[i-1].Version {
+			t.Errorf("migration %s is ordered after %s", m.Name, migrations[i-1].Name)
+		}
+		if (m.SQL == "") == (m.Up == nil) {
+			t.Errorf("migration %s needs either SQL or a function", m.Name)
+		}
+		if m.Checksum == "" {
+			t.Errorf("migration %s has no checksum", m.Name)
+		}
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	db := openTestDB(t)
+	migrations, _ := loadMigrations()
+
+	var count int
+	if err := db.QueryRow("SELECT count(*) FROM schema_migrations").Scan(&count); err != nil {
+		t.Fatalf("querying the applied migrations failed: %s", err)
+	}
+	if count != len(migrations) {
+		t.Errorf("%d migrations were recorded, want %d", count, len(migrations))
+	}
+
+	// Migrating an up to date database changes nothing
+	if err := migrate(db); err != nil {
+		t.Errorf("migrating again failed: %s", err)
+	}
+}
+
+func TestMigrateChecksumMismatch(t *testing.T) {
+	db := openTestDB(t)
+	if _, err := db.Exec("UPDATE schema_migrations SET checksum = 'changed' WHERE version = 1"); err != nil {
+		t.Fatalf("changing the checksum failed: %s", err)
+	}
+	err := migrate(db)
+	if err == nil || !strings.Contains(err.Error(), "was changed after it has been applied") {
+		t.Errorf("migrate() = %v, want an error for the changed migration", err)
+	}
+}
+
+func TestMigrateRefusesNewerDatabase(t *testing.T) {
+	db := openTestDB(t)
+	migrations, _ := loadMigrations()
+	latest := migrations[len(migrations)-1].Version
+	if _, err := db.Exec("INSERT INTO schema_migrations (version, name, checksum) VALUES (?, 'future', 'future')", latest+1); err != nil {
+		t.Fatalf("recording a newer migration failed: %s", err)
+	}
+	err := migrate(db)
+	if err == nil || !strings.Contains(err.Error(), "unknown to this binary") {
+		t.Errorf("migrate() = %v, want an error for the newer database", err)
+	}
+}
+
+func TestMigrateFailureIsRolledBack(t *testing.T) {
+	db := openTestDB(t)
+	failing := migration{Version: 1000, Name: "1000_failing", SQL: "create table partial (id integer); select * from missing_table;", Checksum: "failing"}
+	if err := applyMigration(db, failing); err == nil {
+		t.Fatalf("applying the failing migration succeeded")
+	}
+	var count int
+	db.QueryRow("SELECT count(*) FROM schema_migrations WHERE version = 1000").Scan(&count)
+	if count != 0 {
+		t.Errorf("the failed migration was recorded")
+	}
+	if _, err := db.Exec("SELECT * FROM partial"); err == nil {
+		t.Errorf("the table of the failed migration was kept")
+	}
+}
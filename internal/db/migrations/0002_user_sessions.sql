@@ -0,0 +1,11 @@
+create table if not exists user_sessions
+(
+    id         integer primary key,
+    user_id    integer   not null,
+    platform   varchar   not null,
+    domain     varchar   not null default '',
+    cookie     varchar   not null,
+    created_at timestamp not null default current_timestamp,
+    foreign key (user_id) references users (id),
+    unique (user_id, platform, domain)
+);
@@ -0,0 +1,12 @@
+create table if not exists subscriptions
+(
+    id                integer primary key,
+    user_id           integer   not null,
+    feed_url          varchar   not null,
+    title             varchar   not null,
+    last_published_at timestamp not null,
+    last_checked_at   timestamp,
+    created_at        timestamp not null default current_timestamp,
+    foreign key (user_id) references users (id),
+    unique (user_id, feed_url)
+);
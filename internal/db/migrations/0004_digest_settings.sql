@@ -0,0 +1,9 @@
+create table if not exists digest_settings
+(
+    id           integer primary key,
+    user_id      integer   not null unique,
+    frequency    varchar   not null,
+    last_sent_at timestamp not null,
+    created_at   timestamp not null default current_timestamp,
+    foreign key (user_id) references users (id)
+);
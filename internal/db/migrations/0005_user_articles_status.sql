@@ -0,0 +1,2 @@
+-- Articles delivered before the status was tracked have been sent
+alter table user_articles add column status varchar not null default 'sent';
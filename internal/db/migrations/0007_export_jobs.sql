@@ -0,0 +1,22 @@
+create table export_jobs
+(
+    id                integer primary key,
+    user_id           integer   not null,
+    url               varchar   not null,
+    -- queued, running, done or failed
+    state             varchar   not null default 'queued',
+    attempts          integer   not null default 0,
+    next_attempt_at   timestamp not null default current_timestamp,
+    last_error        varchar,
+    result            varchar,
+    article_id        integer,
+    -- The interaction of the command, its response shows the progress of the job
+    application_id    varchar,
+    interaction_token varchar,
+    created_at        timestamp not null default current_timestamp,
+    updated_at        timestamp not null default current_timestamp,
+    foreign key (user_id) references users (id),
+    foreign key (article_id) references articles (id)
+);
+
+create index export_jobs_state on export_jobs (state, next_attempt_at);
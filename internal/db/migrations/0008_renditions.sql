@@ -0,0 +1,14 @@
+-- Format the articles are delivered in: epub, azw3 or mobi
+alter table users add column format varchar not null default 'epub';
+
+-- Converted files of an article, the epub is stored in articles.local_path
+create table article_renditions
+(
+    id         integer primary key,
+    article_id integer   not null,
+    format     varchar   not null,
+    local_path varchar   not null,
+    created_at timestamp not null default current_timestamp,
+    foreign key (article_id) references articles (id),
+    unique (article_id, format)
+);
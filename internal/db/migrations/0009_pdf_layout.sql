@@ -0,0 +1,3 @@
+-- Layout of the articles delivered as PDF
+alter table users add column pdf_page_size varchar not null default 'a5';
+alter table users add column pdf_font_size integer not null default 11;
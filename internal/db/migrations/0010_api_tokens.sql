@@ -0,0 +1,14 @@
+-- Tokens authenticating a user at the HTTP server, only their hash is stored
+create table api_tokens
+(
+    id           integer primary key,
+    user_id      integer   not null,
+    -- What the token grants access to, e.g. opds for the catalog
+    scope        varchar   not null,
+    token_hash   varchar   not null unique,
+    created_at   timestamp not null default current_timestamp,
+    last_used_at timestamp,
+    foreign key (user_id) references users (id)
+);
+
+create index api_tokens_user on api_tokens (user_id, scope);
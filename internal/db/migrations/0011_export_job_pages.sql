@@ -0,0 +1,2 @@
+-- HTML of the article as rendered by the browser of the user, it is used instead of fetching the page
+alter table export_jobs add column page_html varchar;
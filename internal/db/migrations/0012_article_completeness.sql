@@ -0,0 +1,15 @@
+-- Whether the stored epub contains the full article or only the preview of a paywalled post.
+-- Articles stored before the completeness was tracked are assumed to be complete.
+alter table articles add column completeness varchar not null default 'full';
+
+-- User whose sessions were used to scrape the stored epub
+alter table articles add column scraped_by integer references users (id);
+
+-- Preview kept for users without access when local_path contains the full article
+alter table articles add column preview_path varchar;
+
+-- Users may opt in to receive the preview of articles they cannot read completely
+alter table users add column receive_previews boolean not null default false;
+
+-- The user only received the preview of the article
+alter table user_articles add column preview boolean not null default false;
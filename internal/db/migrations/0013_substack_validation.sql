@@ -0,0 +1,5 @@
+-- Last time Substack accepted the stored session cookie
+alter table users add column substack_validated_at timestamp;
+
+-- Set once the stored session cookie was rejected, so the user is only notified once
+alter table users add column substack_expired_at timestamp;
@@ -0,0 +1,5 @@
+-- Last time the platform accepted the stored session cookie
+alter table user_sessions add column validated_at timestamp;
+
+-- Set once the stored session cookie was rejected, so the user is only notified once
+alter table user_sessions add column expired_at timestamp;
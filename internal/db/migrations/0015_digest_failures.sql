@@ -0,0 +1,2 @@
+-- Last time sending the digest failed, so it is retried later and the user is only notified once
+alter table digest_settings add column failed_at timestamp;
@@ -0,0 +1,14 @@
+-- Posts without publication date cannot be compared with last_published_at,
+-- the ones already known are remembered by their URL instead
+create table if not exists subscription_items
+(
+    id              integer primary key,
+    subscription_id integer   not null,
+    url             varchar   not null,
+    created_at      timestamp not null default current_timestamp,
+    foreign key (subscription_id) references subscriptions (id),
+    unique (subscription_id, url)
+);
+
+-- Subscriptions created before the table existed record the posts of their feed on the next check without delivering them
+alter table subscriptions add column items_recorded boolean not null default false;
@@ -0,0 +1,102 @@
+package db
+
+import (
+	"github.com/go-jet/jet/v2/sqlite"
+	"kindExport/generated/model"
+	"kindExport/internal/ebook"
+
+	. "kindExport/generated/table"
+)
+
+// SetFormat stores the format the articles are delivered in for the user
+func SetFormat(userID int32, format string) error {
+	db, err := GetDB()
+	if err != nil {
+		return err
+	}
+
+	_, err = Users.
+		UPDATE(Users.Format).
+		SET(format).
+		WHERE(Users.ID.EQ(sqlite.Int32(userID))).
+		Exec(db)
+	return err
+}
+
+// SetPDFLayout stores the page and font size of the PDF files of the user
+func SetPDFLayout(userID int32, pageSize string, fontSize int32) error {
+	db, err := GetDB()
+	if err != nil {
+		return err
+	}
+
+	_, err = Users.
+		UPDATE(Users.PdfPageSize, Users.PdfFontSize).
+		SET(pageSize, fontSize).
+		WHERE(Users.ID.EQ(sqlite.Int32(userID))).
+		Exec(db)
+	return err
+}
+
+// ConvertOptions returns the layout of the converted files of the user
+func ConvertOptions(user *model.Users) ebook.Options {
+	return ebook.Options{
+		PageSize: user.PdfPageSize,
+		FontSize: int(user.PdfFontSize),
+	}
+}
+
+// GetRendition returns the stored file of the article in the given variant, nil if it was not converted yet
+func GetRendition(articleID int32, variant string) (*model.ArticleRenditions, error) {
+	db, err := GetDB()
+	if err != nil {
+		return nil, err
+	}
+
+	var renditions []model.ArticleRenditions
+	err = sqlite.SELECT(
+		ArticleRenditions.AllColumns,
+	).FROM(
+		ArticleRenditions,
+	).WHERE(
+		ArticleRenditions.ArticleID.EQ(sqlite.Int32(articleID)).
+			AND(ArticleRenditions.Format.EQ(sqlite.String(variant))),
+	).LIMIT(1).Query(db, &renditions)
+	if err != nil || len(renditions) == 0 {
+		return nil, err
+	}
+	return &renditions[0], nil
+}
+
+// SetRendition stores the path of the article converted to the given variant
+func SetRendition(articleID int32, variant string, localPath string) error {
+	db, err := GetDB()
+	if err != nil {
+		return err
+	}
+
+	_, err = ArticleRenditions.
+		INSERT(ArticleRenditions.ArticleID, ArticleRenditions.Format, ArticleRenditions.LocalPath).
+		VALUES(articleID, variant, localPath).
+		ON_CONFLICT(ArticleRenditions.ArticleID, ArticleRenditions.Format).
+		DO_UPDATE(sqlite.SET(
+			ArticleRenditions.LocalPath.SET(ArticleRenditions.EXCLUDED.LocalPath),
+			ArticleRenditions.CreatedAt.SET(sqlite.CURRENT_TIMESTAMP()),
+		)).
+		Exec(db)
+	return err
+}
+
+// deleteRenditions removes the renditions of the article, e.g. after its epub changed
+func deleteRenditions(articleID int32) error {
+	db, err := GetDB()
+	if err != nil {
+		return err
+	}
+
+	_, err = ArticleRenditions.
+		DELETE().
+		WHERE(ArticleRenditions.ArticleID.EQ(sqlite.Int32(articleID))).
+		Exec(db)
+	return err
+}
@@ -0,0 +1,162 @@
+package db
+
+import (
+	"github.com/go-jet/jet/v2/sqlite"
+	"kindExport/generated/model"
+	"time"
+
+	. "kindExport/generated/table"
+)
+
+// AddSubscription subscribes the user to a feed. Only posts published after lastPublished are delivered,
+// the posts without publication date given as knownURLs are never delivered.
+func AddSubscription(userID int32, feedURL string, title string, lastPublished time.Time, knownURLs []string) error {
+	db, err := GetDB()
+	if err != nil {
+		return err
+	}
+
+	result, err := Subscriptions.
+		INSERT(Subscriptions.UserID, Subscriptions.FeedURL, Subscriptions.Title, Subscriptions.LastPublishedAt, Subscriptions.ItemsRecorded).
+		VALUES(userID, feedURL, title, lastPublished.UTC(), true).
+		Exec(db)
+	if err != nil {
+		return err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	return AddSubscriptionItems(int32(id), knownURLs)
+}
+
+// RemoveSubscription removes a subscription of the user and returns whether it existed
+func RemoveSubscription(userID int32, id int32) (bool, error) {
+	db, err := GetDB()
+	if err != nil {
+		return false, err
+	}
+
+	result, err := Subscriptions.
+		DELETE().
+		WHERE(Subscriptions.UserID.EQ(sqlite.Int32(userID)).AND(Subscriptions.ID.EQ(sqlite.Int32(id)))).
+		Exec(db)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil || affected == 0 {
+		return false, err
+	}
+
+	_, err = SubscriptionItems.
+		DELETE().
+		WHERE(SubscriptionItems.SubscriptionID.EQ(sqlite.Int32(id))).
+		Exec(db)
+	return true, err
+}
+
+// GetSubscriptions returns all subscriptions, or the subscriptions of a single user if userID is given
+func GetSubscriptions(userID *int32) ([]model.Subscriptions, error) {
+	db, err := GetDB()
+	if err != nil {
+		return nil, err
+	}
+
+	stmt := sqlite.SELECT(
+		Subscriptions.AllColumns,
+	).FROM(
+		Subscriptions,
+	).ORDER_BY(
+		Subscriptions.ID,
+	)
+	if userID != nil {
+		stmt = stmt.WHERE(Subscriptions.UserID.EQ(sqlite.Int32(*userID)))
+	}
+
+	var subscriptions []model.Subscriptions
+	err = stmt.Query(db, &subscriptions)
+	return subscriptions, err
+}
+
+// UpdateSubscriptionProgress stores when the feed was checked and the publication date of the newest delivered post
+func UpdateSubscriptionProgress(id int32, lastPublished time.Time) error {
+	db, err := GetDB()
+	if err != nil {
+		return err
+	}
+
+	_, err = Subscriptions.
+		UPDATE(Subscriptions.LastPublishedAt, Subscriptions.LastCheckedAt).
+		SET(lastPublished.UTC(), time.Now().UTC()).
+		WHERE(Subscriptions.ID.EQ(sqlite.Int32(id))).
+		Exec(db)
+	return err
+}
+
+// GetSubscriptionItems returns the URLs of the posts without publication date that are known for the subscription
+func GetSubscriptionItems(subscriptionID int32) (map[string]bool, error) {
+	db, err := GetDB()
+	if err != nil {
+		return nil, err
+	}
+
+	var items []model.SubscriptionItems
+	err = sqlite.SELECT(
+		SubscriptionItems.AllColumns,
+	).FROM(
+		SubscriptionItems,
+	).WHERE(
+		SubscriptionItems.SubscriptionID.EQ(sqlite.Int32(subscriptionID)),
+	).Query(db, &items)
+	if err != nil {
+		return nil, err
+	}
+
+	urls := make(map[string]bool, len(items))
+	for _, item := range items {
+		urls[item.URL] = true
+	}
+	return urls, nil
+}
+
+// AddSubscriptionItems remembers posts without publication date, so they are only delivered once
+func AddSubscriptionItems(subscriptionID int32, urls []string) error {
+	if len(urls) == 0 {
+		return nil
+	}
+	db, err := GetDB()
+	if err != nil {
+		return err
+	}
+
+	stmt := SubscriptionItems.INSERT(SubscriptionItems.SubscriptionID, SubscriptionItems.URL)
+	for _, url := range urls {
+		stmt = stmt.VALUES(subscriptionID, url)
+	}
+	_, err = stmt.
+		ON_CONFLICT(SubscriptionItems.SubscriptionID, SubscriptionItems.URL).
+		DO_NOTHING().
+		Exec(db)
+	return err
+}
+
+// RecordSubscriptionItems remembers the posts without publication date of a subscription that was created
+// before they were recorded, so the posts that were in the feed already are not delivered
+func RecordSubscriptionItems(subscriptionID int32, urls []string) error {
+	err := AddSubscriptionItems(subscriptionID, urls)
+	if err != nil {
+		return err
+	}
+	db, err := GetDB()
+	if err != nil {
+		return err
+	}
+
+	_, err = Subscriptions.
+		UPDATE(Subscriptions.ItemsRecorded).
+		SET(true).
+		WHERE(Subscriptions.ID.EQ(sqlite.Int32(subscriptionID))).
+		Exec(db)
+	return err
+}
@@ -0,0 +1,75 @@
+package db
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSubscriptionItems(t *testing.T) {
+	db := useTestDB(t)
+	result, err := db.Exec("INSERT INTO users (name, discord_id) VALUES ('reader', '1')")
+	if err != nil {
+		t.Fatalf("inserting the user failed: %s", err)
+	}
+	userID, _ := result.LastInsertId()
+
+	err = AddSubscription(int32(userID), "https://example.com/feed", "Example", time.Now(), []string{"https://example.com/p/known"})
+	if err != nil {
+		t.Fatalf("AddSubscription failed: %s", err)
+	}
+	subscriptions, err := GetSubscriptions(nil)
+	if err != nil || len(subscriptions) != 1 {
+		t.Fatalf("GetSubscriptions() = %v, %v, want the subscription", subscriptions, err)
+	}
+	subscription := subscriptions[0]
+	if !subscription.ItemsRecorded {
+		t.Errorf("new subscription does not record its posts")
+	}
+
+	// Recording a post twice keeps it once
+	err = AddSubscriptionItems(*subscription.ID, []string{"https://example.com/p/new", "https://example.com/p/known"})
+	if err != nil {
+		t.Fatalf("AddSubscriptionItems failed: %s", err)
+	}
+	items, err := GetSubscriptionItems(*subscription.ID)
+	if err != nil {
+		t.Fatalf("GetSubscriptionItems failed: %s", err)
+	}
+	want := map[string]bool{"https://example.com/p/known": true, "https://example.com/p/new": true}
+	if !reflect.DeepEqual(items, want) {
+		t.Errorf("GetSubscriptionItems() = %v, want %v", items, want)
+	}
+
+	removed, err := RemoveSubscription(int32(userID), *subscription.ID)
+	if err != nil || !removed {
+		t.Fatalf("RemoveSubscription() = %v, %v, want the subscription removed", removed, err)
+	}
+	items, _ = GetSubscriptionItems(*subscription.ID)
+	if len(items) != 0 {
+		t.Errorf("posts of the removed subscription were kept: %v", items)
+	}
+}
+
+func TestRecordSubscriptionItems(t *testing.T) {
+	db := useTestDB(t)
+	db.Exec("INSERT INTO users (name, discord_id) VALUES ('reader', '1')")
+	// Subscriptions created before the posts were recorded
+	result, err := db.Exec("INSERT INTO subscriptions (user_id, feed_url, title, last_published_at) VALUES (1, 'https://example.com/feed', 'Example', ?)", time.Now())
+	if err != nil {
+		t.Fatalf("inserting the subscription failed: %s", err)
+	}
+	id, _ := result.LastInsertId()
+
+	if err := RecordSubscriptionItems(int32(id), []string{"https://example.com/p/old"}); err != nil {
+		t.Fatalf("RecordSubscriptionItems failed: %s", err)
+	}
+	subscriptions, _ := GetSubscriptions(nil)
+	if len(subscriptions) != 1 || !subscriptions[0].ItemsRecorded {
+		t.Errorf("subscription was not marked as recording its posts")
+	}
+	items, _ := GetSubscriptionItems(int32(id))
+	if !items["https://example.com/p/old"] {
+		t.Errorf("post of the feed was not recorded")
+	}
+}
@@ -0,0 +1,177 @@
+package db
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"github.com/go-jet/jet/v2/qrm"
+	"github.com/go-jet/jet/v2/sqlite"
+	"kindExport/generated/model"
+	"time"
+
+	. "kindExport/generated/table"
+)
+
+const (
+	// TokenScopeOPDS grants access to the OPDS catalog of the user
+	TokenScopeOPDS = "opds"
+	// TokenScopeAPI authenticates requests to the REST API
+	TokenScopeAPI = "api"
+	// TokenScopeShare authenticates the pages a bookmarklet or share sheet sends
+	TokenScopeShare = "share"
+	// TokenScopeLogin is the one-time token of a login link to the dashboard
+	TokenScopeLogin = "login"
+	// TokenScopeDashboard authenticates a browser that is logged in to the dashboard
+	TokenScopeDashboard = "dashboard"
+)
+
+// tokenLifetimes limits how long the tokens of a scope are valid, tokens of other scopes do not expire
+var tokenLifetimes = map[string]time.Duration{
+	TokenScopeLogin:     15 * time.Minute,
+	TokenScopeDashboard: 30 * 24 * time.Hour,
+	TokenScopeShare:     90 * 24 * time.Hour,
+}
+
+// TokenExpiry returns when a token of the scope created now expires, zero if tokens of the scope do not expire
+func TokenExpiry(scope string) time.Time {
+	lifetime, ok := tokenLifetimes[scope]
+	if !ok {
+		return time.Time{}
+	}
+	return time.Now().Add(lifetime)
+}
+
+// CreateToken creates a new token of the scope for the user and replaces the previous ones.
+// The token is only returned here, the database only contains its hash.
+func CreateToken(userID int32, scope string) (string, error) {
+	db, err := GetDB()
+	if err != nil {
+		return "", err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback()
+
+	_, err = APITokens.
+		DELETE().
+		WHERE(APITokens.UserID.EQ(sqlite.Int32(userID)).AND(APITokens.Scope.EQ(sqlite.String(scope)))).
+		Exec(tx)
+	if err != nil {
+		return "", err
+	}
+	token, err := insertToken(tx, userID, scope)
+	if err != nil {
+		return "", err
+	}
+	return token, tx.Commit()
+}
+
+// AddToken creates a new token of the scope for the user and keeps the previous ones
+func AddToken(userID int32, scope string) (string, error) {
+	db, err := GetDB()
+	if err != nil {
+		return "", err
+	}
+	return insertToken(db, userID, scope)
+}
+
+func insertToken(db qrm.DB, userID int32, scope string) (string, error) {
+	value := make([]byte, 32)
+	if _, err := rand.Read(value); err != nil {
+		return "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(value)
+
+	_, err := APITokens.
+		INSERT(APITokens.UserID, APITokens.Scope, APITokens.TokenHash).
+		VALUES(userID, scope, hashToken(token)).
+		Exec(db)
+	if err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// ConsumeToken returns the user of a one-time token and deletes the token, nil if the token is unknown or expired.
+// The token is deleted and read in a single statement, so concurrent requests cannot both redeem it.
+func ConsumeToken(token string, scope string) (*model.Users, error) {
+	db, err := GetDB()
+	if err != nil {
+		return nil, err
+	}
+
+	var tokens []model.APITokens
+	err = APITokens.
+		DELETE().
+		WHERE(tokenCondition(token, scope)).
+		RETURNING(APITokens.AllColumns).
+		Query(db, &tokens)
+	if err != nil || len(tokens) == 0 {
+		return nil, err
+	}
+	return GetUserByID(tokens[0].UserID)
+}
+
+// DeleteToken revokes the token
+func DeleteToken(token string, scope string) error {
+	db, err := GetDB()
+	if err != nil {
+		return err
+	}
+
+	_, err = APITokens.
+		DELETE().
+		WHERE(APITokens.TokenHash.EQ(sqlite.String(hashToken(token))).AND(APITokens.Scope.EQ(sqlite.String(scope)))).
+		Exec(db)
+	return err
+}
+
+// GetUserByToken returns the user the token of the scope belongs to, nil if the token is unknown or expired
+func GetUserByToken(token string, scope string) (*model.Users, error) {
+	db, err := GetDB()
+	if err != nil {
+		return nil, err
+	}
+
+	var tokens []model.APITokens
+	err = sqlite.SELECT(
+		APITokens.AllColumns,
+	).FROM(
+		APITokens,
+	).WHERE(
+		tokenCondition(token, scope),
+	).LIMIT(1).Query(db, &tokens)
+	if err != nil || len(tokens) == 0 {
+		return nil, err
+	}
+
+	_, err = APITokens.
+		UPDATE(APITokens.LastUsedAt).
+		SET(timestamp(time.Now())).
+		WHERE(APITokens.ID.EQ(sqlite.Int32(*tokens[0].ID))).
+		Exec(db)
+	if err != nil {
+		return nil, err
+	}
+	return GetUserByID(tokens[0].UserID)
+}
+
+// tokenCondition matches the token of the scope if it has not expired yet
+func tokenCondition(token string, scope string) sqlite.BoolExpression {
+	condition := APITokens.TokenHash.EQ(sqlite.String(hashToken(token))).
+		AND(APITokens.Scope.EQ(sqlite.String(scope)))
+	if lifetime, ok := tokenLifetimes[scope]; ok {
+		condition = condition.AND(APITokens.CreatedAt.GT(timestamp(time.Now().Add(-lifetime))))
+	}
+	return condition
+}
+
+// hashToken returns the stored form of a token, tokens are random enough to not need a salt
+func hashToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
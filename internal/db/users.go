@@ -0,0 +1,222 @@
+package db
+
+import (
+	"github.com/go-jet/jet/v2/sqlite"
+	"kindExport/generated/model"
+	"kindExport/internal/scrape"
+	"kindExport/internal/secret"
+
+	. "kindExport/generated/table"
+)
+
+// GetUser returns the user with the given discord id, nil if the user does not exist yet
+func GetUser(discordID string) (*model.Users, error) {
+	db, err := GetDB()
+	if err != nil {
+		return nil, err
+	}
+
+	var users []model.Users
+	err = sqlite.SELECT(
+		Users.AllColumns,
+	).FROM(
+		Users,
+	).WHERE(
+		Users.DiscordID.EQ(sqlite.String(discordID)),
+	).LIMIT(1).Query(db, &users)
+	if err != nil {
+		return nil, err
+	}
+	if len(users) == 0 {
+		return nil, nil
+	}
+	return &users[0], nil
+}
+
+// GetOrCreateUser returns the user with the given discord id and creates it if it does not exist yet
+func GetOrCreateUser(discordID string, name string) (*model.Users, error) {
+	user, err := GetUser(discordID)
+	if err != nil || user != nil {
+		return user, err
+	}
+
+	db, err := GetDB()
+	if err != nil {
+		return nil, err
+	}
+	_, err = Users.
+		INSERT(Users.DiscordID, Users.Name).
+		VALUES(discordID, name).
+		Exec(db)
+	if err != nil {
+		return nil, err
+	}
+	return GetUser(discordID)
+}
+
+// GetSessions returns the decrypted session cookies the user stored besides the substack.com session
+func GetSessions(userID int32) ([]scrape.Session, error) {
+	db, err := GetDB()
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []model.UserSessions
+	err = sqlite.SELECT(
+		UserSessions.AllColumns,
+	).FROM(
+		UserSessions,
+	).WHERE(
+		UserSessions.UserID.EQ(sqlite.Int32(userID)),
+	).Query(db, &rows)
+	if err != nil {
+		return nil, err
+	}
+
+	sessions := make([]scrape.Session, 0, len(rows))
+	for _, row := range rows {
+		cookie, err := secret.Decrypt(row.Cookie)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, scrape.Session{
+			Platform: row.Platform,
+			Domain:   row.Domain,
+			Cookie:   cookie,
+		})
+	}
+	return sessions, nil
+}
+
+// SetSession stores the encrypted session cookie of the user for a platform, replacing a previously stored one
+func SetSession(userID int32, platform string, domain string, cookie string) error {
+	db, err := GetDB()
+	if err != nil {
+		return err
+	}
+
+	cookie, err = secret.Encrypt(cookie)
+	if err != nil {
+		return err
+	}
+
+	_, err = UserSessions.
+		INSERT(UserSessions.UserID, UserSessions.Platform, UserSessions.Domain, UserSessions.Cookie).
+		VALUES(userID, platform, domain, cookie).
+		ON_CONFLICT(UserSessions.UserID, UserSessions.Platform, UserSessions.Domain).
+		DO_UPDATE(sqlite.SET(
+			UserSessions.Cookie.SET(UserSessions.EXCLUDED.Cookie),
+			UserSessions.CreatedAt.SET(sqlite.CURRENT_TIMESTAMP()),
+			UserSessions.ValidatedAt.SET(sqlite.TimestampExp(sqlite.NULL)),
+			UserSessions.ExpiredAt.SET(sqlite.TimestampExp(sqlite.NULL)),
+		)).
+		Exec(db)
+	return err
+}
+
+// GetStoredSessions returns the sessions the user stored besides the substack.com session without their cookies
+func GetStoredSessions(userID int32) ([]model.UserSessions, error) {
+	db, err := GetDB()
+	if err != nil {
+		return nil, err
+	}
+
+	var sessions []model.UserSessions
+	err = sqlite.SELECT(
+		UserSessions.ID, UserSessions.UserID, UserSessions.Platform, UserSessions.Domain, UserSessions.CreatedAt,
+	).FROM(
+		UserSessions,
+	).WHERE(
+		UserSessions.UserID.EQ(sqlite.Int32(userID)),
+	).ORDER_BY(
+		UserSessions.Platform, UserSessions.Domain,
+	).Query(db, &sessions)
+	return sessions, err
+}
+
+// RemoveSession removes a session of the user and returns whether it existed
+func RemoveSession(userID int32, id int32) (bool, error) {
+	db, err := GetDB()
+	if err != nil {
+		return false, err
+	}
+
+	result, err := UserSessions.
+		DELETE().
+		WHERE(UserSessions.UserID.EQ(sqlite.Int32(userID)).AND(UserSessions.ID.EQ(sqlite.Int32(id)))).
+		Exec(db)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+// SetKindleMail stores the address the articles of the user are sent to
+func SetKindleMail(userID int32, address string) error {
+	db, err := GetDB()
+	if err != nil {
+		return err
+	}
+
+	_, err = Users.
+		UPDATE(Users.KindleMail).
+		SET(address).
+		WHERE(Users.ID.EQ(sqlite.Int32(userID))).
+		Exec(db)
+	return err
+}
+
+// SetReceivePreviews sets whether the user receives the preview of articles they cannot read completely
+func SetReceivePreviews(userID int32, enabled bool) error {
+	db, err := GetDB()
+	if err != nil {
+		return err
+	}
+
+	_, err = Users.
+		UPDATE(Users.ReceivePreviews).
+		SET(enabled).
+		WHERE(Users.ID.EQ(sqlite.Int32(userID))).
+		Exec(db)
+	return err
+}
+
+// GetUserByID returns the user with the given id
+func GetUserByID(id int32) (*model.Users, error) {
+	db, err := GetDB()
+	if err != nil {
+		return nil, err
+	}
+
+	var user model.Users
+	err = sqlite.SELECT(
+		Users.AllColumns,
+	).FROM(
+		Users,
+	).WHERE(
+		Users.ID.EQ(sqlite.Int32(id)),
+	).Query(db, &user)
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// ScrapeOptions collects the decrypted sessions of the user that are passed to the scrapers
+func ScrapeOptions(user *model.Users) (scrape.Options, error) {
+	if user == nil {
+		return scrape.Options{}, nil
+	}
+	var options scrape.Options
+	if user.SubstackSession != nil && *user.SubstackSession != "" {
+		substackSession, err := secret.Decrypt(*user.SubstackSession)
+		if err != nil {
+			return options, err
+		}
+		options.SubstackSession = &substackSession
+	}
+	sessions, err := GetSessions(*user.ID)
+	options.Sessions = sessions
+	return options, err
+}
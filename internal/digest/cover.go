@@ -0,0 +1,109 @@
+package digest
+
+import (
+	"golang.org/x/image/draw"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"strings"
+)
+
+const (
+	coverWidth  = 800
+	coverHeight = 1280
+	coverMargin = 60
+)
+
+var (
+	coverBackground = color.RGBA{R: 0x1f, G: 0x2a, B: 0x38, A: 0xff}
+	coverAccent     = color.RGBA{R: 0xe8, G: 0x8d, B: 0x2a, A: 0xff}
+	coverText       = color.RGBA{R: 0xf4, G: 0xf1, B: 0xea, A: 0xff}
+	coverMuted      = color.RGBA{R: 0xa9, G: 0xb4, B: 0xc2, A: 0xff}
+)
+
+// writeCover renders a cover with the title, the period of the digest and the titles of the articles
+func writeCover(target string, title string, period string, articleTitles []string) error {
+	cover := image.NewRGBA(image.Rect(0, 0, coverWidth, coverHeight))
+	draw.Draw(cover, cover.Bounds(), image.NewUniform(coverBackground), image.Point{}, draw.Src)
+	draw.Draw(cover, image.Rect(0, 220, coverWidth, 236), image.NewUniform(coverAccent), image.Point{}, draw.Src)
+
+	y := 100
+	for _, line := range wrap(title, 16) {
+		drawText(cover, line, coverMargin, y, 6, coverText)
+		y += 13 * 6
+	}
+
+	drawText(cover, period, coverMargin, 280, 3, coverAccent)
+
+	y = 380
+	for i, articleTitle := range articleTitles {
+		if y > coverHeight-2*coverMargin {
+			drawText(cover, "...", coverMargin, y, 2, coverMuted)
+			break
+		}
+		for j, line := range wrap(articleTitle, 45) {
+			prefix := "  "
+			if j == 0 {
+				prefix = "- "
+			}
+			drawText(cover, prefix+line, coverMargin, y, 2, coverMuted)
+			y += 13 * 2
+		}
+		if i < len(articleTitles)-1 {
+			y += 16
+		}
+	}
+
+	file, err := os.Create(target)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return png.Encode(file, cover)
+}
+
+// drawText draws the text with the built-in bitmap font, scaled up by the given factor
+func drawText(dst draw.Image, text string, x int, y int, scale int, textColor color.Color) {
+	face := basicfont.Face7x13
+	width := font.MeasureString(face, text).Ceil()
+	if width == 0 {
+		return
+	}
+	height := face.Metrics().Height.Ceil()
+
+	small := image.NewRGBA(image.Rect(0, 0, width, height))
+	drawer := font.Drawer{
+		Dst:  small,
+		Src:  image.NewUniform(textColor),
+		Face: face,
+		Dot:  fixed.P(0, face.Metrics().Ascent.Ceil()),
+	}
+	drawer.DrawString(text)
+
+	target := image.Rect(x, y, x+width*scale, y+height*scale)
+	draw.NearestNeighbor.Scale(dst, target, small, small.Bounds(), draw.Over, nil)
+}
+
+// wrap splits the text into lines of at most width characters
+func wrap(text string, width int) []string {
+	var lines []string
+	var line string
+	for _, word := range strings.Fields(text) {
+		if line != "" && len([]rune(line))+1+len([]rune(word)) > width {
+			lines = append(lines, line)
+			line = ""
+		}
+		if line != "" {
+			line += " "
+		}
+		line += word
+	}
+	if line != "" {
+		lines = append(lines, line)
+	}
+	return lines
+}
@@ -0,0 +1,273 @@
+package digest
+
+import (
+	"fmt"
+	"github.com/PuerkitoBio/goquery"
+	"github.com/go-shiori/go-epub"
+	"github.com/google/uuid"
+	"html"
+	"kindExport/generated/model"
+	"kindExport/internal/config"
+	"kindExport/internal/db"
+	"kindExport/internal/ebook"
+	"kindExport/internal/mailer"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	FrequencyDaily  = "daily"
+	FrequencyWeekly = "weekly"
+
+	// checkInterval is the time between two checks for due digests
+	checkInterval = 10 * time.Minute
+	// retryDelay is the time after which a digest that could not be sent is tried again
+	retryDelay = 6 * time.Hour
+)
+
+// Window returns the time span a digest of the frequency covers
+func Window(frequency string) time.Duration {
+	if frequency == FrequencyWeekly {
+		return 7 * 24 * time.Hour
+	}
+	return 24 * time.Hour
+}
+
+// Scheduler sends the digests of all users once their window has passed
+type Scheduler struct {
+	notify func(discordID string, message string) error
+}
+
+func NewScheduler(notify func(discordID string, message string) error) *Scheduler {
+	return &Scheduler{notify: notify}
+}
+
+// Run checks for due digests periodically, it never returns
+func (s *Scheduler) Run() {
+	ticker := time.NewTicker(checkInterval)
+	defer ticker.Stop()
+	for {
+		s.sendDue()
+		<-ticker.C
+	}
+}
+
+func (s *Scheduler) sendDue() {
+	settings, err := db.GetAllDigestSettings()
+	if err != nil {
+		log.Printf("Error querying digest settings: %s", err.Error())
+		return
+	}
+	for _, setting := range settings {
+		if time.Since(setting.LastSentAt) < Window(setting.Frequency) {
+			continue
+		}
+		if setting.FailedAt != nil && time.Since(*setting.FailedAt) < retryDelay {
+			continue
+		}
+		err = s.send(setting)
+		if err != nil {
+			log.Printf("Error sending digest of user %d: %s", setting.UserID, err.Error())
+		}
+	}
+}
+
+func (s *Scheduler) send(setting model.DigestSettings) error {
+	now := time.Now()
+	articles, err := db.GetArticlesSince(setting.UserID, setting.LastSentAt)
+	if err != nil {
+		return err
+	}
+	if len(articles) == 0 {
+		return db.MarkDigestSent(*setting.ID, now)
+	}
+
+	user, err := db.GetUserByID(setting.UserID)
+	if err != nil {
+		return err
+	}
+	if user.KindleMail == nil || *user.KindleMail == "" {
+		// Keep collecting until a mail address is configured
+		return nil
+	}
+
+	digestPath, articles, err := Build(setting.Frequency, setting.LastSentAt, now, articles)
+	if err != nil {
+		return s.fail(setting, user, now, "Your digest could not be created: ", err)
+	}
+	digestPath, err = ebook.Convert(digestPath, user.Format, db.ConvertOptions(user))
+	if err != nil {
+		return s.fail(setting, user, now, "Your digest could not be converted to "+user.Format+": ", err)
+	}
+	err = mailer.SendMail(*user.KindleMail, digestPath)
+	if err != nil {
+		return s.fail(setting, user, now, "Your digest could not be sent: ", err)
+	}
+	var articleIDs []int32
+	for _, article := range articles {
+		articleIDs = append(articleIDs, *article.ID)
+	}
+	err = db.SetUserArticlesStatus(setting.UserID, articleIDs, db.StatusSent)
+	if err != nil {
+		log.Printf("Error updating status of digest articles: %s", err.Error())
+	}
+	s.sendNotification(user, fmt.Sprintf("Sent your %s digest with %d articles to your kindle mail address", setting.Frequency, len(articles)))
+	return db.MarkDigestSent(*setting.ID, now)
+}
+
+// fail records the failed attempt, so the digest is retried after retryDelay.
+// Only the first failure after a sent digest is reported to the user.
+func (s *Scheduler) fail(setting model.DigestSettings, user *model.Users, now time.Time, message string, err error) error {
+	if setting.FailedAt == nil {
+		s.sendNotification(user, message+err.Error()+". It is tried again later")
+	}
+	if markErr := db.MarkDigestFailed(*setting.ID, now); markErr != nil {
+		log.Printf("Error recording failed digest of user %d: %s", setting.UserID, markErr.Error())
+	}
+	return err
+}
+
+func (s *Scheduler) sendNotification(user *model.Users, message string) {
+	if s.notify == nil {
+		return
+	}
+	err := s.notify(user.DiscordID, message)
+	if err != nil {
+		log.Printf("Error notifying user %s: %s", user.DiscordID, err.Error())
+	}
+}
+
+// Build combines the exported articles into a single epub with a generated cover,
+// a table of contents and one section per article. Articles that cannot be read are left out,
+// it returns the path of the epub and the articles it contains.
+func Build(frequency string, from time.Time, to time.Time, articles []model.Articles) (string, []model.Articles, error) {
+	conf, _ := config.GetConfig()
+
+	var included []model.Articles
+	var documents []*ebook.Document
+	for _, article := range articles {
+		document, err := ebook.Read(article.LocalPath)
+		if err != nil {
+			log.Printf("Error reading %s for the digest: %s", article.LocalPath, err.Error())
+			continue
+		}
+		included = append(included, article)
+		documents = append(documents, document)
+	}
+	if len(included) == 0 {
+		return "", nil, fmt.Errorf("none of the %d articles could be read", len(articles))
+	}
+	articles = included
+
+	title := fmt.Sprintf("%s digest", strings.ToUpper(frequency[:1])+frequency[1:])
+	period := fmt.Sprintf("%s - %s", from.Format("Jan 02"), to.Format("Jan 02, 2006"))
+	if frequency == FrequencyDaily {
+		period = to.Format("Jan 02, 2006")
+	}
+
+	book, err := epub.NewEpub(fmt.Sprintf("%s, %s", title, period))
+	if err != nil {
+		return "", nil, err
+	}
+	book.SetAuthor("kindExport")
+	book.SetIdentifier(uuid.New().String())
+
+	workDir, err := os.MkdirTemp("", "digest")
+	if err != nil {
+		return "", nil, err
+	}
+	defer os.RemoveAll(workDir)
+
+	var titles []string
+	for _, article := range articles {
+		titles = append(titles, article.Title)
+	}
+	coverPath := filepath.Join(workDir, "cover.png")
+	err = writeCover(coverPath, title, period, titles)
+	if err != nil {
+		return "", nil, err
+	}
+	internalCover, err := book.AddImage(coverPath, "cover.png")
+	if err != nil {
+		return "", nil, err
+	}
+	err = book.SetCover(internalCover, "")
+	if err != nil {
+		return "", nil, err
+	}
+
+	var contents strings.Builder
+	contents.WriteString("<h1>Contents</h1><ol>")
+	for i, article := range articles {
+		contents.WriteString(fmt.Sprintf("<li><a href=\"%s\">%s</a><br/><em>%s</em></li>", sectionFilename(i), html.EscapeString(article.Title), html.EscapeString(article.Author)))
+	}
+	contents.WriteString("</ol>")
+	_, err = book.AddSection(contents.String(), "Contents", "contents.xhtml", "")
+	if err != nil {
+		return "", nil, err
+	}
+
+	for i, article := range articles {
+		err = addArticle(book, workDir, i, article, documents[i])
+		if err != nil {
+			return "", nil, fmt.Errorf("adding %s failed: %w", article.Title, err)
+		}
+	}
+
+	err = os.MkdirAll(fmt.Sprintf("%s/digests", conf.OutputDirectory), os.ModePerm)
+	if err != nil {
+		return "", nil, err
+	}
+	digestPath := fmt.Sprintf("%s/digests/%s %s.epub", conf.OutputDirectory, title, to.Format("2006-01-02 1504"))
+	err = book.Write(digestPath)
+	if err != nil {
+		return "", nil, err
+	}
+	return digestPath, articles, nil
+}
+
+func sectionFilename(index int) string {
+	return fmt.Sprintf("article-%d.xhtml", index+1)
+}
+
+// addArticle copies the sections and images of the exported epub of the article into the digest
+func addArticle(book *epub.Epub, workDir string, index int, article model.Articles, document *ebook.Document) error {
+	var body strings.Builder
+	for _, section := range document.Sections {
+		doc, err := goquery.NewDocumentFromReader(strings.NewReader(section.Body))
+		if err != nil {
+			return err
+		}
+		doc.Find("img").Each(func(i int, selection *goquery.Selection) {
+			src, _ := selection.Attr("src")
+			content, ok := document.Images[section.ResolveImage(src)]
+			if !ok {
+				selection.Remove()
+				return
+			}
+			imagePath := filepath.Join(workDir, fmt.Sprintf("%d-%s", index, path.Base(src)))
+			if err := os.WriteFile(imagePath, content, 0o600); err != nil {
+				selection.Remove()
+				return
+			}
+			internalPath, err := book.AddImage(imagePath, fmt.Sprintf("%d-%s", index, path.Base(src)))
+			if err != nil {
+				selection.Remove()
+				return
+			}
+			selection.SetAttr("src", internalPath)
+		})
+		content, err := doc.Find("body").Html()
+		if err != nil {
+			return err
+		}
+		body.WriteString(content)
+	}
+
+	_, err := book.AddSection(body.String(), article.Title, sectionFilename(index), "")
+	return err
+}
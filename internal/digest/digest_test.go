@@ -0,0 +1,205 @@
+package digest
+
+import (
+	"fmt"
+	"github.com/go-shiori/go-epub"
+	"image"
+	"image/png"
+	"kindExport/generated/model"
+	"kindExport/internal/db"
+	"kindExport/internal/ebook"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeArticle stores an exported article with an image the way the scrapers do
+func writeArticle(t *testing.T, dir string, title string, author string) model.Articles {
+	t.Helper()
+	imagePath := filepath.Join(dir, title+".png")
+	file, err := os.Create(imagePath)
+	if err != nil {
+		t.Fatalf("creating the image failed: %s", err)
+	}
+	png.Encode(file, image.NewGray(image.Rect(0, 0, 2, 2)))
+	file.Close()
+
+	book, err := epub.NewEpub(title)
+	if err != nil {
+		t.Fatalf("creating the epub failed: %s", err)
+	}
+	book.SetAuthor(author)
+	internalImage, err := book.AddImage(imagePath, "image.png")
+	if err != nil {
+		t.Fatalf("adding the image failed: %s", err)
+	}
+	_, err = book.AddSection(fmt.Sprintf(`<h1>%s</h1><p>Text of %s</p><img src="%s"/>`, title, title, internalImage), title, "", "")
+	if err != nil {
+		t.Fatalf("adding the section failed: %s", err)
+	}
+	epubPath := filepath.Join(dir, title+".epub")
+	if err := book.Write(epubPath); err != nil {
+		t.Fatalf("writing the epub failed: %s", err)
+	}
+	return model.Articles{Title: title, Author: author, LocalPath: epubPath}
+}
+
+func TestBuild(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("OUTPUT_DIRECTORY", dir)
+	articles := []model.Articles{
+		writeArticle(t, dir, "Local news", "Jane Doe"),
+		writeArticle(t, dir, "First snow", "Field Notes"),
+		writeArticle(t, dir, "The Build", "Sam Roe"),
+	}
+	from := time.Date(2026, 10, 5, 6, 0, 0, 0, time.UTC)
+	to := time.Date(2026, 10, 12, 6, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		frequency string
+		title     string
+	}{
+		{frequency: FrequencyDaily, title: "Daily digest, Oct 12, 2026"},
+		{frequency: FrequencyWeekly, title: "Weekly digest, Oct 05 - Oct 12, 2026"},
+	}
+	for _, test := range tests {
+		t.Run(test.frequency, func(t *testing.T) {
+			// Articles that cannot be read are left out instead of failing the digest
+			missing := model.Articles{Title: "Deleted", Author: "Jane Doe", LocalPath: filepath.Join(dir, "deleted.epub")}
+			input := []model.Articles{articles[0], missing, articles[1], articles[2]}
+			digestPath, included, err := Build(test.frequency, from, to, input)
+			if err != nil {
+				t.Fatalf("Build failed: %s", err)
+			}
+			if !reflect.DeepEqual(included, articles) {
+				t.Errorf("Build() included %v, want %v", included, articles)
+			}
+			document, err := ebook.Read(digestPath)
+			if err != nil {
+				t.Fatalf("reading the digest failed: %s", err)
+			}
+			if document.Title != test.title {
+				t.Errorf("title = %q, want %q", document.Title, test.title)
+			}
+			if document.Cover == "" {
+				t.Errorf("digest has no cover")
+			}
+
+			// The cover page is followed by the contents and one section per article in the given order
+			if len(document.Sections) != len(articles)+2 {
+				t.Fatalf("digest has %d sections, want %d", len(document.Sections), len(articles)+2)
+			}
+			contents := document.Sections[1]
+			if contents.Title != "Contents" {
+				t.Errorf("second section is %q, want the contents", contents.Title)
+			}
+			for i, article := range articles {
+				section := document.Sections[i+2]
+				if section.Title != article.Title || !strings.Contains(section.Body, "Text of "+article.Title) {
+					t.Errorf("section %d is %q, want %q", i+2, section.Title, article.Title)
+				}
+				link := fmt.Sprintf(`<a href="%s">%s</a><br/><em>%s</em>`, filepath.Base(section.Path), article.Title, article.Author)
+				if !strings.Contains(contents.Body, link) {
+					t.Errorf("contents do not link %s", link)
+				}
+				// Images of different articles share the same name, so they are prefixed with the index of the article
+				if _, ok := document.Images[fmt.Sprintf("EPUB/images/%d-image.png", i)]; !ok {
+					t.Errorf("image of %q was not copied", article.Title)
+				}
+			}
+		})
+	}
+}
+
+func TestBuildWithoutReadableArticles(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("OUTPUT_DIRECTORY", dir)
+	articles := []model.Articles{{Title: "Deleted", Author: "Jane Doe", LocalPath: filepath.Join(dir, "deleted.epub")}}
+	if _, _, err := Build(FrequencyDaily, time.Now().Add(-24*time.Hour), time.Now(), articles); err == nil {
+		t.Errorf("Build succeeded without a readable article")
+	}
+}
+
+func TestWindow(t *testing.T) {
+	tests := []struct {
+		frequency string
+		want      time.Duration
+	}{
+		{frequency: FrequencyDaily, want: 24 * time.Hour},
+		{frequency: FrequencyWeekly, want: 7 * 24 * time.Hour},
+		{frequency: "", want: 24 * time.Hour},
+	}
+	for _, test := range tests {
+		if got := Window(test.frequency); got != test.want {
+			t.Errorf("Window(%q) = %s, want %s", test.frequency, got, test.want)
+		}
+	}
+}
+
+func TestSendFailureIsReportedOnce(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("DATABASE_PATH", filepath.Join(dir, "test.sqlite"))
+	t.Setenv("ENCRYPTION_KEY", "YTp3fTUcQiCy1mViG3A8ZYqmEXpGYdROn0ECUkTCvL0=")
+	database, err := db.GetDB()
+	if err != nil {
+		t.Fatalf("opening the database failed: %s", err)
+	}
+	user, err := db.GetOrCreateUser("1", "reader")
+	if err != nil {
+		t.Fatalf("creating the user failed: %s", err)
+	}
+	if err := db.SetKindleMail(*user.ID, "reader@kindle.com"); err != nil {
+		t.Fatalf("setting the mail address failed: %s", err)
+	}
+	if err := db.SetDigestFrequency(*user.ID, FrequencyDaily); err != nil {
+		t.Fatalf("enabling the digest failed: %s", err)
+	}
+	// The only article cannot be read, so the digest cannot be created
+	result, err := database.Exec("INSERT INTO articles (title, author, url, release_date, local_path) VALUES ('Gone', 'Jane', 'https://example.com/p/gone', ?, ?)",
+		time.Now(), filepath.Join(dir, "missing.epub"))
+	if err != nil {
+		t.Fatalf("inserting the article failed: %s", err)
+	}
+	articleID, _ := result.LastInsertId()
+	_, err = database.Exec("INSERT INTO user_articles (user_id, article_id, created_at, status) VALUES (?, ?, ?, ?)",
+		*user.ID, articleID, time.Now().UTC().Add(time.Hour).Format("2006-01-02 15:04:05"), db.StatusFetched)
+	if err != nil {
+		t.Fatalf("inserting the user article failed: %s", err)
+	}
+
+	var notifications []string
+	scheduler := NewScheduler(func(discordID string, message string) error {
+		notifications = append(notifications, message)
+		return nil
+	})
+	for attempt := 0; attempt < 2; attempt++ {
+		setting, err := db.GetDigestSettings(*user.ID)
+		if err != nil {
+			t.Fatalf("querying the digest settings failed: %s", err)
+		}
+		if err := scheduler.send(*setting); err == nil {
+			t.Fatalf("sending the digest succeeded")
+		}
+	}
+	if len(notifications) != 1 {
+		t.Errorf("user was notified %d times, want once: %v", len(notifications), notifications)
+	}
+
+	// The failed digest is not tried again before the retry delay passed
+	setting, _ := db.GetDigestSettings(*user.ID)
+	if setting.FailedAt == nil {
+		t.Fatalf("failed attempt was not recorded")
+	}
+	failedAt := *setting.FailedAt
+	if _, err := database.Exec("UPDATE digest_settings SET last_sent_at = ?", time.Now().UTC().Add(-48*time.Hour)); err != nil {
+		t.Fatalf("moving the last digest failed: %s", err)
+	}
+	scheduler.sendDue()
+	setting, _ = db.GetDigestSettings(*user.ID)
+	if setting.FailedAt == nil || !setting.FailedAt.Equal(failedAt) {
+		t.Errorf("digest was tried again before the retry delay passed")
+	}
+}
@@ -0,0 +1,43 @@
+package discord
+
+import (
+	"github.com/bwmarrin/discordgo"
+	"kindExport/internal/config"
+	"kindExport/internal/db"
+	"log"
+)
+
+// handleAPIToken creates a token for the REST API, the previous token of the user stops working
+func handleAPIToken(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	// The token grants access to the account, so it is only shown to the user
+	respond := func(content string) {
+		s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{
+				Content: content,
+				Flags:   discordgo.MessageFlagsEphemeral,
+			},
+		})
+	}
+
+	conf, err := config.GetConfig()
+	if err != nil || conf.HTTPAddress == "" {
+		respond("The API is not enabled on this bot")
+		return
+	}
+	user, err := db.GetOrCreateUser(i.Interaction.User.ID, i.User.Username)
+	if err != nil {
+		log.Printf("Error getting user: %s", err.Error())
+		respond("An internal error occurred")
+		return
+	}
+	token, err := db.CreateToken(*user.ID, db.TokenScopeAPI)
+	if err != nil {
+		log.Printf("Error creating token: %s", err.Error())
+		respond("An internal error occurred while creating the API token")
+		return
+	}
+
+	respond("Your API token is `" + token + "`, send it as `Authorization: Bearer <token>` header to " + conf.PublicURL + ".\n" +
+		"Tokens you created before no longer work.")
+}
@@ -0,0 +1,43 @@
+package discord
+
+import (
+	"github.com/bwmarrin/discordgo"
+	"kindExport/internal/config"
+	"kindExport/internal/db"
+	"log"
+)
+
+// handleOPDS creates a personal link to the OPDS catalog, previous links of the user stop working
+func handleOPDS(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	respond := func(content string) {
+		s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{
+				Content: content,
+			},
+		})
+	}
+
+	conf, err := config.GetConfig()
+	if err != nil || conf.HTTPAddress == "" {
+		respond("The catalog is not enabled on this bot")
+		return
+	}
+	user, err := db.GetOrCreateUser(i.Interaction.User.ID, i.User.Username)
+	if err != nil {
+		log.Printf("Error getting user: %s", err.Error())
+		respond("An internal error occurred")
+		return
+	}
+	token, err := db.CreateToken(*user.ID, db.TokenScopeOPDS)
+	if err != nil {
+		log.Printf("Error creating token: %s", err.Error())
+		respond("An internal error occurred while creating the catalog link")
+		return
+	}
+
+	respond("Add this catalog to your reading app, keep the link private as it grants access to your articles:\n" +
+		"OPDS 1.2: <" + conf.PublicURL + "/opds/" + token + "/>\n" +
+		"OPDS 2.0: <" + conf.PublicURL + "/opds2/" + token + "/>\n" +
+		"Links you created before no longer work.")
+}
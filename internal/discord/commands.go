@@ -1,20 +1,30 @@
 package discord
 
 import (
+	"errors"
+	"fmt"
 	"github.com/bwmarrin/discordgo"
 	"github.com/go-jet/jet/v2/sqlite"
 	"kindExport/generated/model"
 	. "kindExport/generated/table"
 	"kindExport/internal/db"
+	"kindExport/internal/digest"
+	"kindExport/internal/ebook"
+	"kindExport/internal/export"
 	"kindExport/internal/scrape"
+	"kindExport/internal/substack"
 	"log"
 	_ "modernc.org/sqlite"
-	"net/http"
 	"net/mail"
-	"net/url"
 	"strings"
 )
 
+// minFontSize and maxFontSize limit the font size of PDF files
+var (
+	minFontSize = 8.0
+	maxFontSize = 24.0
+)
+
 var (
 	registeredCommands map[int]*discordgo.ApplicationCommand
 	commands           = []*discordgo.ApplicationCommand{
@@ -39,7 +49,7 @@ var (
 		},
 		{
 			Name:        "export",
-			Description: "Export a Substack newsletter to a epub. Will be sent to mail address if configured.",
+			Description: "Export a newsletter article to a epub. Will be sent to mail address if configured.",
 			Contexts: &[]discordgo.InteractionContextType{
 				discordgo.InteractionContextPrivateChannel,
 				discordgo.InteractionContextBotDM,
@@ -51,14 +61,18 @@ var (
 				{
 					Type:        discordgo.ApplicationCommandOptionString,
 					Name:        "url",
-					Description: "The URL of the Substack newsletter to export.",
-					Required:    true,
+					Description: "The URL of the newsletter article to export.",
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionAttachment,
+					Name:        "file",
+					Description: "The article saved from your browser as HTML or MHTML, if the bot cannot fetch it.",
 				},
 			},
 		},
 		{
 			Name:        "session",
-			Description: "Set the session cookie, needs to be the connect.sid",
+			Description: "Set the session cookie of a newsletter platform, e.g. the connect.sid for Substack",
 			Contexts: &[]discordgo.InteractionContextType{
 				discordgo.InteractionContextPrivateChannel,
 				discordgo.InteractionContextBotDM,
@@ -73,182 +87,523 @@ var (
 					Description: "The value of the `connect.sid` cookie.",
 					Required:    true,
 				},
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "platform",
+					Description: "The platform the session cookie belongs to, defaults to Substack.",
+					Required:    false,
+					Choices: []*discordgo.ApplicationCommandOptionChoice{
+						{Name: "Substack", Value: scrape.PlatformSubstack},
+						{Name: "Medium", Value: scrape.PlatformMedium},
+						{Name: "Ghost", Value: scrape.PlatformGhost},
+						{Name: "beehiiv", Value: scrape.PlatformBeehiiv},
+					},
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "domain",
+					Description: "The domain of the publication, required for Ghost and beehiiv, optional for Substack and Medium publications on a custom domain.",
+					Required:    false,
+				},
+			},
+		},
+		{
+			Name:        "login",
+			Description: "Log in to Substack with your account instead of copying the session cookie",
+			Contexts: &[]discordgo.InteractionContextType{
+				discordgo.InteractionContextPrivateChannel,
+				discordgo.InteractionContextBotDM,
+			},
+			IntegrationTypes: &[]discordgo.ApplicationIntegrationType{
+				discordgo.ApplicationIntegrationUserInstall,
+			},
+		},
+		{
+			Name:        "subscribe",
+			Description: "Subscribe to a feed or Substack publication, new posts are sent to your mail address.",
+			Contexts: &[]discordgo.InteractionContextType{
+				discordgo.InteractionContextPrivateChannel,
+				discordgo.InteractionContextBotDM,
+			},
+			IntegrationTypes: &[]discordgo.ApplicationIntegrationType{
+				discordgo.ApplicationIntegrationUserInstall,
+			},
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "url",
+					Description: "The URL of the RSS/Atom feed, the publication or one of its posts.",
+					Required:    true,
+				},
+			},
+		},
+		{
+			Name:        "unsubscribe",
+			Description: "Stop the delivery of new posts of a subscribed feed.",
+			Contexts: &[]discordgo.InteractionContextType{
+				discordgo.InteractionContextPrivateChannel,
+				discordgo.InteractionContextBotDM,
+			},
+			IntegrationTypes: &[]discordgo.ApplicationIntegrationType{
+				discordgo.ApplicationIntegrationUserInstall,
+			},
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:         discordgo.ApplicationCommandOptionString,
+					Name:         "feed",
+					Description:  "The subscription to remove.",
+					Required:     true,
+					Autocomplete: true,
+				},
+			},
+		},
+		{
+			Name:        "digest",
+			Description: "Collect your articles and receive them as a single epub instead of one mail per article.",
+			Contexts: &[]discordgo.InteractionContextType{
+				discordgo.InteractionContextPrivateChannel,
+				discordgo.InteractionContextBotDM,
+			},
+			IntegrationTypes: &[]discordgo.ApplicationIntegrationType{
+				discordgo.ApplicationIntegrationUserInstall,
+			},
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "frequency",
+					Description: "How often the digest is sent, off sends every article on its own.",
+					Required:    true,
+					Choices: []*discordgo.ApplicationCommandOptionChoice{
+						{Name: "Off", Value: "off"},
+						{Name: "Daily", Value: digest.FrequencyDaily},
+						{Name: "Weekly", Value: digest.FrequencyWeekly},
+					},
+				},
+			},
+		},
+		{
+			Name:        "previews",
+			Description: "Receive the preview of paywalled articles you have no subscription for.",
+			Contexts: &[]discordgo.InteractionContextType{
+				discordgo.InteractionContextPrivateChannel,
+				discordgo.InteractionContextBotDM,
+			},
+			IntegrationTypes: &[]discordgo.ApplicationIntegrationType{
+				discordgo.ApplicationIntegrationUserInstall,
+			},
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionBoolean,
+					Name:        "enabled",
+					Description: "Whether previews are sent, they start with a page marking them as preview only.",
+					Required:    true,
+				},
+			},
+		},
+		{
+			Name:        "format",
+			Description: "Choose the file format of the exported articles.",
+			Contexts: &[]discordgo.InteractionContextType{
+				discordgo.InteractionContextPrivateChannel,
+				discordgo.InteractionContextBotDM,
+			},
+			IntegrationTypes: &[]discordgo.ApplicationIntegrationType{
+				discordgo.ApplicationIntegrationUserInstall,
+			},
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "format",
+					Description: "Send to Kindle mail accepts EPUB and PDF, the other formats are for other readers.",
+					Required:    true,
+					Choices: []*discordgo.ApplicationCommandOptionChoice{
+						{Name: "EPUB", Value: ebook.FormatEPUB},
+						{Name: "AZW3 (KF8)", Value: ebook.FormatAZW3},
+						{Name: "MOBI", Value: ebook.FormatMOBI},
+						{Name: "PDF", Value: ebook.FormatPDF},
+						{Name: "Markdown with assets (zip)", Value: ebook.FormatMarkdown},
+						{Name: "HTML with assets (zip)", Value: ebook.FormatHTML},
+					},
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "page_size",
+					Description: "The page size of PDF files, defaults to A5.",
+					Required:    false,
+					Choices: []*discordgo.ApplicationCommandOptionChoice{
+						{Name: "A4", Value: ebook.PageSizeA4},
+						{Name: "A5", Value: ebook.PageSizeA5},
+						{Name: "A6", Value: ebook.PageSizeA6},
+						{Name: "Letter", Value: ebook.PageSizeLetter},
+					},
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionInteger,
+					Name:        "font_size",
+					Description: "The font size of PDF files in points, defaults to 11.",
+					Required:    false,
+					MinValue:    &minFontSize,
+					MaxValue:    maxFontSize,
+				},
+			},
+		},
+		{
+			Name:        "history",
+			Description: "List the articles that were exported for you.",
+			Contexts: &[]discordgo.InteractionContextType{
+				discordgo.InteractionContextPrivateChannel,
+				discordgo.InteractionContextBotDM,
+			},
+			IntegrationTypes: &[]discordgo.ApplicationIntegrationType{
+				discordgo.ApplicationIntegrationUserInstall,
+			},
+		},
+		{
+			Name:        "apitoken",
+			Description: "Create a token for the REST API, replacing your previous token.",
+			Contexts: &[]discordgo.InteractionContextType{
+				discordgo.InteractionContextPrivateChannel,
+				discordgo.InteractionContextBotDM,
+			},
+			IntegrationTypes: &[]discordgo.ApplicationIntegrationType{
+				discordgo.ApplicationIntegrationUserInstall,
+			},
+		},
+		{
+			Name:        "share",
+			Description: "Get a personal link for bookmarklets and share sheets to export the page you are reading.",
+			Contexts: &[]discordgo.InteractionContextType{
+				discordgo.InteractionContextPrivateChannel,
+				discordgo.InteractionContextBotDM,
+			},
+			IntegrationTypes: &[]discordgo.ApplicationIntegrationType{
+				discordgo.ApplicationIntegrationUserInstall,
+			},
+		},
+		{
+			Name:        "dashboard",
+			Description: "Get a login link to the web dashboard for your settings and history.",
+			Contexts: &[]discordgo.InteractionContextType{
+				discordgo.InteractionContextPrivateChannel,
+				discordgo.InteractionContextBotDM,
+			},
+			IntegrationTypes: &[]discordgo.ApplicationIntegrationType{
+				discordgo.ApplicationIntegrationUserInstall,
+			},
+		},
+		{
+			Name:        "opds",
+			Description: "Get a link to the OPDS catalog of your articles for reading apps.",
+			Contexts: &[]discordgo.InteractionContextType{
+				discordgo.InteractionContextPrivateChannel,
+				discordgo.InteractionContextBotDM,
+			},
+			IntegrationTypes: &[]discordgo.ApplicationIntegrationType{
+				discordgo.ApplicationIntegrationUserInstall,
+			},
+		},
+		{
+			Name:        "resend",
+			Description: "Send a previously exported article to your kindle mail address again.",
+			Contexts: &[]discordgo.InteractionContextType{
+				discordgo.InteractionContextPrivateChannel,
+				discordgo.InteractionContextBotDM,
+			},
+			IntegrationTypes: &[]discordgo.ApplicationIntegrationType{
+				discordgo.ApplicationIntegrationUserInstall,
+			},
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:         discordgo.ApplicationCommandOptionString,
+					Name:         "article",
+					Description:  "The article to send again.",
+					Required:     true,
+					Autocomplete: true,
+				},
 			},
 		},
 	}
 	commandHandlers = map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate){
-		"mail":    handleMail,
-		"export":  handleExport,
-		"session": handleSession,
+		"resend":      handleResend,
+		"history":     handleHistory,
+		"opds":        handleOPDS,
+		"digest":      handleDigest,
+		"previews":    handlePreviews,
+		"dashboard":   handleDashboard,
+		"apitoken":    handleAPIToken,
+		"share":       handleShare,
+		"format":      handleFormat,
+		"mail":        handleMail,
+		"export":      handleExport,
+		"session":     handleSession,
+		"login":       handleLogin,
+		"subscribe":   handleSubscribe,
+		"unsubscribe": handleUnsubscribe,
+	}
+	// componentHandlers handle interactions with buttons, keyed by the prefix of the custom id
+	componentHandlers = map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate){
+		"history":   handleHistoryPage,
+		"loginlink": handleLoginLinkButton,
+	}
+	// modalHandlers handle submitted forms, keyed by the prefix of the custom id
+	modalHandlers = map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate){
+		"login":     handleLoginSubmit,
+		"loginlink": handleLoginLinkSubmit,
 	}
 )
 
 func handleSession(s *discordgo.Session, i *discordgo.InteractionCreate) {
-	options := i.ApplicationCommandData().Options
-	sessionCookie := options[0].StringValue()
+	var sessionCookie, domain string
+	platform := scrape.PlatformSubstack
+	for _, option := range i.ApplicationCommandData().Options {
+		switch option.Name {
+		case "session_cookie":
+			sessionCookie = option.StringValue()
+		case "platform":
+			platform = option.StringValue()
+		case "domain":
+			domain = scrape.NormalizeDomain(option.StringValue())
+		}
+	}
 
 	// Get discord user id
 	userID := i.Interaction.User.ID
 
-	stmt := sqlite.SELECT(
-		Users.AllColumns,
-	).FROM(
-		Users,
-	).WHERE(
-		Users.DiscordID.EQ(sqlite.String(userID)),
-	).LIMIT(1)
-
-	dbSession, _ := db.GetDB()
-	var users []model.Users
-	err := stmt.Query(dbSession, &users)
-
-	if err != nil {
-		log.Printf("Error querying user: %s", err.Error())
+	if (platform == scrape.PlatformGhost || platform == scrape.PlatformBeehiiv) && domain == "" {
 		s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
 			Type: discordgo.InteractionResponseChannelMessageWithSource,
 			Data: &discordgo.InteractionResponseData{
-				Content: "An internal error occurred",
+				Content: "The domain of the publication is required for " + platform + " sessions",
 			},
 		})
 		return
 	}
 
-	if len(users) == 0 {
-		// Create the user in the database
-		_, err = Users.
-			INSERT(Users.DiscordID, Users.Name, Users.SubstackSession).
-			VALUES(userID, i.User.Username, sessionCookie).
-			Exec(dbSession)
-		if err != nil {
-			s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
-				Type: discordgo.InteractionResponseChannelMessageWithSource,
-				Data: &discordgo.InteractionResponseData{
-					Content: "An internal error occurred while creating new user",
-				},
-			})
-			return
-		}
-	} else {
-		_, err = Users.
-			UPDATE(Users.SubstackSession).
-			SET(sessionCookie).
-			WHERE(Users.DiscordID.EQ(sqlite.String(userID))).
-			Exec(dbSession)
-		if err != nil {
-			s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
-				Type: discordgo.InteractionResponseChannelMessageWithSource,
-				Data: &discordgo.InteractionResponseData{
-					Content: "An internal error occurred while updating session for user",
-				},
-			})
-			return
-		}
+	// The session of substack.com is stored with the user, sessions of single publications next to the other platforms
+	if platform == scrape.PlatformSubstack && domain == "substack.com" {
+		domain = ""
+	}
+	if platform == scrape.PlatformSubstack && domain != "" {
+		handleSubstackPublicationSession(s, i, domain, sessionCookie)
+		return
+	}
+	if platform != scrape.PlatformSubstack {
+		handlePlatformSession(s, i, platform, domain, sessionCookie)
+		return
 	}
 
+	// Substack may take longer to answer than discord waits for a response
 	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
-		Type: discordgo.InteractionResponseChannelMessageWithSource,
-		Data: &discordgo.InteractionResponseData{
-			Content: "Session cookie has been updated",
-		},
+		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
 	})
-}
+	editResponse := func(content string) {
+		s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+			Content: &content,
+		})
+	}
 
-func normalizeUrl(urlValue string) string {
-	u, err := url.Parse(urlValue)
+	account, err := substack.Validate(sessionCookie)
+	if errors.Is(err, substack.ErrSessionExpired) {
+		editResponse("Substack did not accept the session cookie, it is not valid or has expired. The session has not been updated")
+		return
+	}
 	if err != nil {
-		return urlValue
+		log.Printf("Error validating session: %s", err.Error())
+		editResponse("The session cookie could not be validated, the session has not been updated: " + err.Error())
+		return
 	}
 
-	// Return the url without the query while normalizing the scheme to https
-	// And remove trailing slash
-
-	u.Scheme = "https"
-	u.RawQuery = ""
-	u.Fragment = ""
-	u.Path = strings.TrimSuffix(u.Path, "/")
-	return u.String()
-}
+	user, err := db.GetOrCreateUser(userID, i.User.Username)
+	if err != nil {
+		log.Printf("Error getting user: %s", err.Error())
+		editResponse("An internal error occurred")
+		return
+	}
 
-func getRedirectedURL(urlValue string) (string, error) {
-	client := http.Client{}
-	resp, err := client.Get(urlValue)
+	err = db.SetSubstackSession(*user.ID, sessionCookie, account.Profile.Handle)
 	if err != nil {
-		return "", err
+		log.Printf("Error storing session: %s", err.Error())
+		editResponse("An internal error occurred while updating session for user")
+		return
 	}
-	defer resp.Body.Close()
-	return resp.Request.URL.String(), nil
+
+	editResponse("Session cookie has been updated.\n" + account.Summary())
 }
 
-func handleExport(s *discordgo.Session, i *discordgo.InteractionCreate) {
-	options := i.ApplicationCommandData().Options
-	urlValue := options[0].StringValue()
+// handleSubstackPublicationSession stores the session of a Substack publication on a custom domain once the publication accepted it
+func handleSubstackPublicationSession(s *discordgo.Session, i *discordgo.InteractionCreate, domain string, sessionCookie string) {
+	// The publication may take longer to answer than discord waits for a response
+	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+	})
+	editResponse := func(content string) {
+		s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+			Content: &content,
+		})
+	}
 
-	// Get discord user id
-	userID := i.Interaction.User.ID
+	profile, err := substack.ValidatePublication(domain, sessionCookie)
+	if errors.Is(err, substack.ErrSessionExpired) {
+		editResponse(domain + " did not accept the session cookie, it is not valid or has expired. The session has not been updated")
+		return
+	}
+	if err != nil {
+		log.Printf("Error validating session for %s: %s", domain, err.Error())
+		editResponse("The session cookie could not be validated with " + domain + ", the session has not been updated: " + err.Error())
+		return
+	}
 
-	_, err := url.Parse(urlValue)
+	user, err := db.GetOrCreateUser(i.Interaction.User.ID, i.User.Username)
 	if err != nil {
+		log.Printf("Error getting user: %s", err.Error())
+		editResponse("An internal error occurred")
+		return
+	}
+	err = db.SetSession(*user.ID, scrape.PlatformSubstack, domain, sessionCookie)
+	if err != nil {
+		log.Printf("Error storing session: %s", err.Error())
+		editResponse("An internal error occurred while updating session for user")
+		return
+	}
+	editResponse("Session cookie for " + domain + " has been updated, it belongs to the Substack account " + profile.Name)
+}
+
+// handlePlatformSession stores the session cookie of a platform besides Substack
+func handlePlatformSession(s *discordgo.Session, i *discordgo.InteractionCreate, platform string, domain string, sessionCookie string) {
+	user, err := db.GetOrCreateUser(i.Interaction.User.ID, i.User.Username)
+	if err != nil {
+		log.Printf("Error getting user: %s", err.Error())
 		s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
 			Type: discordgo.InteractionResponseChannelMessageWithSource,
 			Data: &discordgo.InteractionResponseData{
-				Content: "Invalid URL",
+				Content: "An internal error occurred",
 			},
 		})
 		return
 	}
 
-	// Get the redirected URL
-	urlValue, err = getRedirectedURL(urlValue)
+	err = db.SetSession(*user.ID, platform, domain, sessionCookie)
 	if err != nil {
-		log.Printf("Error getting redirected URL: %s", err.Error())
+		log.Printf("Error storing session: %s", err.Error())
 		s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
 			Type: discordgo.InteractionResponseChannelMessageWithSource,
 			Data: &discordgo.InteractionResponseData{
-				Content: "URL could not be resolved",
+				Content: "An internal error occurred while updating session for user",
 			},
 		})
 		return
 	}
-	urlValue = normalizeUrl(urlValue)
 
-	stmt := sqlite.SELECT(
-		Users.AllColumns,
-	).FROM(
-		Users,
-	).WHERE(
-		Users.DiscordID.EQ(sqlite.String(userID)),
-	).LIMIT(1)
+	target := platform
+	if domain != "" {
+		target = domain
+	}
+	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: "Session cookie for " + target + " has been updated",
+		},
+	})
+}
 
-	dbSession, _ := db.GetDB()
-	var users []model.Users
-	err = stmt.Query(dbSession, &users)
+func handleExport(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	var urlValue string
+	var attachment *discordgo.MessageAttachment
+	data := i.ApplicationCommandData()
+	for _, option := range data.Options {
+		switch option.Name {
+		case "url":
+			urlValue = strings.TrimSpace(option.StringValue())
+		case "file":
+			if data.Resolved != nil {
+				attachment = data.Resolved.Attachments[option.Value.(string)]
+			}
+		}
+	}
 
-	if err != nil {
-		log.Printf("Error querying user: %s", err.Error())
+	respond := func(content string) {
 		s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
 			Type: discordgo.InteractionResponseChannelMessageWithSource,
 			Data: &discordgo.InteractionResponseData{
-				Content: "An internal error occurred",
+				Content: content,
 			},
 		})
+	}
+	if urlValue == "" && attachment == nil {
+		respond("Please pass the URL of the article or the page saved from your browser")
+		return
+	}
+	if attachment != nil && attachment.Size > maxUploadSize {
+		respond(fmt.Sprintf("The file is too large, at most %d MB are supported", maxUploadSize>>20))
 		return
 	}
 
-	// Check whether the epub is already in the database
-	stmt = sqlite.SELECT(
-		Articles.AllColumns,
-	).FROM(
-		Articles,
-	).WHERE(
-		Articles.URL.EQ(sqlite.String(normalizeUrl(urlValue))),
-	).LIMIT(1)
+	// Validating the URL resolves its host, which may take longer than Discord waits for the response.
+	// The export runs in the background, its progress is shown by editing the response
+	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+	})
+	if err != nil {
+		log.Printf("Error responding to interaction: %s", err.Error())
+	}
+	editResponse := func(content string) {
+		s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+			Content: &content,
+		})
+	}
+
+	if urlValue != "" {
+		if err := export.ValidateURL(urlValue); err != nil {
+			editResponse("Invalid URL")
+			return
+		}
+	}
+
+	user, err := db.GetOrCreateUser(i.Interaction.User.ID, i.User.Username)
+	if err != nil {
+		log.Printf("Error getting user: %s", err.Error())
+		editResponse("An internal error occurred")
+		return
+	}
+
+	if attachment == nil {
+		editResponse("Export has been queued")
+		_, err = export.Enqueue(*user.ID, urlValue, i.AppID, i.Token)
+	} else {
+		var page *scrape.Page
+		page, err = readUpload(attachment)
+		if errors.Is(err, scrape.ErrUnsupportedUpload) {
+			editResponse("The file is not supported, please save the page as HTML or MHTML")
+			return
+		}
+		if err != nil {
+			log.Printf("Error reading uploaded page %s: %s", attachment.Filename, err.Error())
+			editResponse("The file could not be read, please save the page as HTML or MHTML")
+			return
+		}
+		// A URL passed with the file takes precedence over the one found in the page
+		if urlValue != "" {
+			page.URL = urlValue
+		}
+		if err := export.ValidateURL(page.URL); err != nil {
+			editResponse("The address of the article could not be found in the file, please pass it with the url option")
+			return
+		}
+		editResponse("Export has been queued")
+		_, err = export.EnqueuePage(*user.ID, page.URL, page.HTML, i.AppID, i.Token)
+	}
+	if err != nil {
+		log.Printf("Error queueing export: %s", err.Error())
+		editResponse("An internal error occurred while queueing the export")
+	}
+}
 
-	var articles []model.Articles
-	err = stmt.Query(dbSession, &articles)
+func handlePreviews(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	enabled := i.ApplicationCommandData().Options[0].BoolValue()
 
+	user, err := db.GetOrCreateUser(i.Interaction.User.ID, i.User.Username)
 	if err != nil {
-		log.Printf("Error querying articles: %s", err.Error())
-		articles = []model.Articles{}
+		log.Printf("Error getting user: %s", err.Error())
 		s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
 			Type: discordgo.InteractionResponseChannelMessageWithSource,
 			Data: &discordgo.InteractionResponseData{
@@ -258,95 +613,110 @@ func handleExport(s *discordgo.Session, i *discordgo.InteractionCreate) {
 		return
 	}
 
-	ebookPath := ""
-
-	scraper := scrape.SubstackScraper{}
-	if len(users) > 0 && users[0].SubstackSession != nil {
-		scraper.SubstackLoginCookie = users[0].SubstackSession
+	content := "Paywalled articles you cannot read completely are no longer sent"
+	if enabled {
+		content = "The preview of paywalled articles you cannot read completely is sent from now on"
+	}
+	err = db.SetReceivePreviews(*user.ID, enabled)
+	if err != nil {
+		log.Printf("Error updating preview settings: %s", err.Error())
+		content = "An internal error occurred while updating the preview settings"
 	}
+	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: content,
+		},
+	})
+}
 
-	// Fetch the epub (if necessary)
-	if len(articles) > 0 {
-		// Epub already exists in the database
-		// Todo Check if the epub is paid
-		s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
-			Type: discordgo.InteractionResponseChannelMessageWithSource,
-			Data: &discordgo.InteractionResponseData{
-				Content: "Newsletter article already exists in the database, no need" +
-					" to fetch it again",
-			},
-		})
-		ebookPath = articles[0].LocalPath
-	} else {
+func handleDigest(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	frequency := i.ApplicationCommandData().Options[0].StringValue()
+
+	user, err := db.GetOrCreateUser(i.Interaction.User.ID, i.User.Username)
+	if err != nil {
+		log.Printf("Error getting user: %s", err.Error())
 		s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
 			Type: discordgo.InteractionResponseChannelMessageWithSource,
 			Data: &discordgo.InteractionResponseData{
-				Content: "Fetching newsletter, this may take a few seconds...",
+				Content: "An internal error occurred",
 			},
 		})
+		return
+	}
 
-		book, err := scraper.Scrape(&urlValue)
-		if err != nil {
-			log.Printf("Error scraping newsletter: %s", err.Error())
-			s.FollowupMessageCreate(i.Interaction, false, &discordgo.WebhookParams{
-				Content: "Error fetching newsletter: " + err.Error(),
-			})
-			return
-		}
-		// Insert the book into the database
-		db.InsertBook(*book)
-		ebookPath = *book.Path
+	content := "Articles are sent on their own from now on"
+	if frequency == "off" {
+		frequency = ""
+	} else {
+		content = "Articles are collected and sent as " + frequency + " digest from now on"
 	}
 
-	// Send the epub to the user's kindle mail address
+	err = db.SetDigestFrequency(*user.ID, frequency)
+	if err != nil {
+		log.Printf("Error updating digest settings: %s", err.Error())
+		content = "An internal error occurred while updating the digest settings"
+	}
+	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: content,
+		},
+	})
+}
 
-	if len(users) == 0 || *users[0].KindleMail == "" || users[0].KindleMail == nil {
-		s.FollowupMessageCreate(i.Interaction, false, &discordgo.WebhookParams{
-			Content: "Mail address is not configured." +
-				" Epub has been fetched, but cannot be sent to kindle mail.",
-		})
-	} else {
-		// Check for paywall on article
-		paywallAccessible, err := scraper.CheckPaywallAccessible(urlValue)
-		if err != nil {
-			log.Printf("Error checking paywall: %s", err.Error())
-			s.FollowupMessageCreate(i.Interaction, false, &discordgo.WebhookParams{
-				Content: "Error checking paywall: " + err.Error(),
-			})
-			return
+func handleFormat(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	var format, pageSize string
+	var fontSize int64
+	for _, option := range i.ApplicationCommandData().Options {
+		switch option.Name {
+		case "format":
+			format = option.StringValue()
+		case "page_size":
+			pageSize = option.StringValue()
+		case "font_size":
+			fontSize = option.IntValue()
 		}
-		if !paywallAccessible {
-			s.FollowupMessageCreate(i.Interaction, false, &discordgo.WebhookParams{
-				Content: "Article is behind a paywall. As no session with a subscription is provided, the article will not be sent to the Kindle mail address." +
-					" To access the article, please subscribe to the newsletter and provide the session cookie to the bot via the `/session` command",
-			})
-			return
-		}
-		err = sendMail(*users[0].KindleMail, ebookPath)
-		if err != nil {
-			log.Printf("Error sending mail: %s", err.Error())
-			s.FollowupMessageCreate(i.Interaction, false, &discordgo.WebhookParams{
-				Content: "Error sending epub to kindle mail address: " + err.Error(),
-			})
-			return
-		}
-		s.FollowupMessageCreate(i.Interaction, false, &discordgo.WebhookParams{
-			Content: "Sent epub to kindle mail address",
-		})
-
 	}
 
+	user, err := db.GetOrCreateUser(i.Interaction.User.ID, i.User.Username)
 	if err != nil {
-		log.Printf("Error scraping newsletter: %s", err.Error())
+		log.Printf("Error getting user: %s", err.Error())
 		s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
 			Type: discordgo.InteractionResponseChannelMessageWithSource,
 			Data: &discordgo.InteractionResponseData{
-				Content: "Error scraping newsletter: " + err.Error(),
+				Content: "An internal error occurred",
 			},
 		})
 		return
 	}
 
+	// Keep the stored layout for the options that were not given
+	if pageSize == "" {
+		pageSize = user.PdfPageSize
+	}
+	if fontSize == 0 {
+		fontSize = int64(user.PdfFontSize)
+	}
+
+	content := "Articles are sent as " + strings.ToUpper(format) + " from now on"
+	if format == ebook.FormatPDF {
+		content += fmt.Sprintf(" with %s pages and a font size of %dpt", strings.ToUpper(pageSize), fontSize)
+	}
+	err = db.SetFormat(*user.ID, format)
+	if err == nil {
+		err = db.SetPDFLayout(*user.ID, pageSize, int32(fontSize))
+	}
+	if err != nil {
+		log.Printf("Error updating format: %s", err.Error())
+		content = "An internal error occurred while updating the format"
+	}
+	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: content,
+		},
+	})
 }
 
 func handleMail(s *discordgo.Session, i *discordgo.InteractionCreate) {
@@ -479,8 +849,21 @@ func initCommands(session *discordgo.Session) error {
 		registeredCommands[i] = cmd
 	}
 	session.AddHandler(func(s *discordgo.Session, i *discordgo.InteractionCreate) {
-		if h, ok := commandHandlers[i.ApplicationCommandData().Name]; ok {
-			h(s, i)
+		switch i.Type {
+		case discordgo.InteractionApplicationCommand, discordgo.InteractionApplicationCommandAutocomplete:
+			if h, ok := commandHandlers[i.ApplicationCommandData().Name]; ok {
+				h(s, i)
+			}
+		case discordgo.InteractionMessageComponent:
+			prefix, _, _ := strings.Cut(i.MessageComponentData().CustomID, ":")
+			if h, ok := componentHandlers[prefix]; ok {
+				h(s, i)
+			}
+		case discordgo.InteractionModalSubmit:
+			prefix, _, _ := strings.Cut(i.ModalSubmitData().CustomID, ":")
+			if h, ok := modalHandlers[prefix]; ok {
+				h(s, i)
+			}
 		}
 	})
 
@@ -0,0 +1,43 @@
+package discord
+
+import (
+	"github.com/bwmarrin/discordgo"
+	"kindExport/internal/config"
+	"kindExport/internal/db"
+	"log"
+)
+
+// handleDashboard sends a one-time login link to the web dashboard
+func handleDashboard(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	// The link logs in as the user, so it is only shown to them
+	respond := func(content string) {
+		s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{
+				Content: content,
+				Flags:   discordgo.MessageFlagsEphemeral,
+			},
+		})
+	}
+
+	conf, err := config.GetConfig()
+	if err != nil || conf.HTTPAddress == "" {
+		respond("The dashboard is not enabled on this bot")
+		return
+	}
+	user, err := db.GetOrCreateUser(i.Interaction.User.ID, i.User.Username)
+	if err != nil {
+		log.Printf("Error getting user: %s", err.Error())
+		respond("An internal error occurred")
+		return
+	}
+	token, err := db.CreateToken(*user.ID, db.TokenScopeLogin)
+	if err != nil {
+		log.Printf("Error creating token: %s", err.Error())
+		respond("An internal error occurred while creating the login link")
+		return
+	}
+
+	respond("Log in to your dashboard with this link, it can be used once within 15 minutes:\n" +
+		"<" + conf.PublicURL + "/dashboard/login/" + token + ">")
+}
@@ -0,0 +1,121 @@
+package discord
+
+import (
+	"fmt"
+	"github.com/bwmarrin/discordgo"
+	"kindExport/internal/db"
+	"log"
+	"strconv"
+	"strings"
+)
+
+// historyPageSize is the amount of articles shown on one page of the history
+const historyPageSize = 10
+
+func handleHistory(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	respondHistory(s, i, 0, discordgo.InteractionResponseChannelMessageWithSource)
+}
+
+// handleHistoryPage is called by the pagination buttons, the custom id contains the requested page
+func handleHistoryPage(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	_, pageValue, _ := strings.Cut(i.MessageComponentData().CustomID, ":")
+	page, err := strconv.Atoi(pageValue)
+	if err != nil {
+		page = 0
+	}
+	respondHistory(s, i, page, discordgo.InteractionResponseUpdateMessage)
+}
+
+func respondHistory(s *discordgo.Session, i *discordgo.InteractionCreate, page int, responseType discordgo.InteractionResponseType) {
+	respondError := func(content string) {
+		s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{
+				Content: content,
+			},
+		})
+	}
+
+	user, err := db.GetUser(i.Interaction.User.ID)
+	if err != nil {
+		log.Printf("Error querying user: %s", err.Error())
+		respondError("An internal error occurred")
+		return
+	}
+	if user == nil {
+		respondError("You did not export any articles yet")
+		return
+	}
+
+	total, err := db.CountHistory(*user.ID)
+	if err != nil {
+		log.Printf("Error counting history: %s", err.Error())
+		respondError("An internal error occurred")
+		return
+	}
+	if total == 0 {
+		respondError("You did not export any articles yet")
+		return
+	}
+
+	pages := int((total + historyPageSize - 1) / historyPageSize)
+	page = max(0, min(page, pages-1))
+
+	entries, err := db.GetHistory(*user.ID, historyPageSize, int64(page*historyPageSize))
+	if err != nil {
+		log.Printf("Error querying history: %s", err.Error())
+		respondError("An internal error occurred")
+		return
+	}
+
+	var description strings.Builder
+	for index, entry := range entries {
+		description.WriteString(fmt.Sprintf("**%d.** [%s](%s)\n%s · `%s` · %s\n",
+			page*historyPageSize+index+1,
+			escapeMarkdown(truncate(entry.Article.Title, 120)),
+			entry.Article.URL,
+			escapeMarkdown(truncate(entry.Article.Author, 80)),
+			entry.Status,
+			entry.CreatedAt.Format("Jan 02, 2006"),
+		))
+	}
+
+	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: responseType,
+		Data: &discordgo.InteractionResponseData{
+			Embeds: []*discordgo.MessageEmbed{
+				{
+					Title:       "Your exported articles",
+					Description: description.String(),
+					Footer: &discordgo.MessageEmbedFooter{
+						Text: fmt.Sprintf("Page %d of %d · %d articles", page+1, pages, total),
+					},
+				},
+			},
+			Components: []discordgo.MessageComponent{
+				discordgo.ActionsRow{
+					Components: []discordgo.MessageComponent{
+						discordgo.Button{
+							Label:    "Previous",
+							Style:    discordgo.SecondaryButton,
+							CustomID: fmt.Sprintf("history:%d", page-1),
+							Disabled: page == 0,
+						},
+						discordgo.Button{
+							Label:    "Next",
+							Style:    discordgo.SecondaryButton,
+							CustomID: fmt.Sprintf("history:%d", page+1),
+							Disabled: page >= pages-1,
+						},
+					},
+				},
+			},
+		},
+	})
+}
+
+// escapeMarkdown prevents titles from breaking the formatting of the message
+func escapeMarkdown(text string) string {
+	replacer := strings.NewReplacer("*", "\\*", "_", "\\_", "`", "\\`", "[", "\\[", "]", "\\]", "~", "\\~", "|", "\\|")
+	return replacer.Replace(text)
+}
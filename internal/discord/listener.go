@@ -2,11 +2,18 @@ package discord
 
 import (
 	"github.com/bwmarrin/discordgo"
+	"kindExport/generated/model"
+	"kindExport/internal/db"
+	"kindExport/internal/export"
 	"log"
 	"os"
 	"os/signal"
+	"time"
 )
 
+// interactionEditWindow is the time in which discord accepts edits of an interaction response
+const interactionEditWindow = 14 * time.Minute
+
 type Listener struct {
 	session *discordgo.Session
 }
@@ -35,6 +42,59 @@ func NewListener(token string) (Listener, error) {
 	}, nil
 }
 
+// SendDirectMessage sends a message to the discord user in a direct message channel
+func (l Listener) SendDirectMessage(discordID string, message string) error {
+	channel, err := l.session.UserChannelCreate(discordID)
+	if err != nil {
+		return err
+	}
+	_, err = l.session.ChannelMessageSend(channel.ID, message)
+	return err
+}
+
+// UpdateExport shows the progress of an export job by editing the response to the /export command.
+// Once the response cannot be edited anymore, only the final message is sent as a direct message.
+func (l Listener) UpdateExport(job model.ExportJobs, progress export.Progress) {
+	content := exportStatus(progress)
+	embeds := []*discordgo.MessageEmbed{}
+	if progress.Summary != nil {
+		embeds = append(embeds, exportSummary(*progress.Summary))
+	}
+
+	if job.ApplicationID != nil && job.InteractionToken != nil && time.Since(job.CreatedAt) < interactionEditWindow {
+		interaction := &discordgo.Interaction{
+			AppID: *job.ApplicationID,
+			Token: *job.InteractionToken,
+		}
+		_, err := l.session.InteractionResponseEdit(interaction, &discordgo.WebhookEdit{
+			Content: &content,
+			Embeds:  &embeds,
+		})
+		if err == nil {
+			return
+		}
+		log.Printf("Error editing export response: %s", err.Error())
+	}
+	if !progress.Final {
+		return
+	}
+	user, err := db.GetUserByID(job.UserID)
+	if err != nil {
+		log.Printf("Error querying user: %s", err.Error())
+		return
+	}
+	channel, err := l.session.UserChannelCreate(user.DiscordID)
+	if err == nil {
+		_, err = l.session.ChannelMessageSendComplex(channel.ID, &discordgo.MessageSend{
+			Content: content,
+			Embeds:  embeds,
+		})
+	}
+	if err != nil {
+		log.Printf("Error notifying user %s: %s", user.DiscordID, err.Error())
+	}
+}
+
 func (l Listener) Listen() {
 	err := initCommands(l.session)
 	if err != nil {
@@ -0,0 +1,196 @@
+package discord
+
+import (
+	"errors"
+	"github.com/bwmarrin/discordgo"
+	"kindExport/internal/db"
+	"kindExport/internal/substack"
+	"log"
+	"net/mail"
+	"strings"
+)
+
+// handleLogin asks for the Substack account of the user, the password is never stored
+func handleLogin(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseModal,
+		Data: &discordgo.InteractionResponseData{
+			CustomID: "login",
+			Title:    "Log in to Substack",
+			Components: []discordgo.MessageComponent{
+				discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+					discordgo.TextInput{
+						CustomID:    "email",
+						Label:       "Email address",
+						Style:       discordgo.TextInputShort,
+						Placeholder: "you@example.com",
+						Required:    true,
+						MaxLength:   254,
+					},
+				}},
+				discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+					discordgo.TextInput{
+						CustomID:    "password",
+						Label:       "Password (empty to get a login link by mail)",
+						Style:       discordgo.TextInputShort,
+						Placeholder: "Only used to log in, it is not stored",
+						Required:    false,
+						MaxLength:   200,
+					},
+				}},
+			},
+		},
+	})
+	if err != nil {
+		log.Printf("Error showing login form: %s", err.Error())
+	}
+}
+
+// handleLoginSubmit logs in with the password or sends the login link by mail
+func handleLoginSubmit(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	data := i.ModalSubmitData()
+	email := strings.TrimSpace(modalValue(data, "email"))
+	password := modalValue(data, "password")
+
+	if _, err := mail.ParseAddress(email); err != nil {
+		s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{
+				Content: "Invalid email address",
+				Flags:   discordgo.MessageFlagsEphemeral,
+			},
+		})
+		return
+	}
+
+	// Substack may take longer to answer than discord waits for a response
+	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Flags: discordgo.MessageFlagsEphemeral,
+		},
+	})
+
+	if password == "" {
+		content := "Substack sent a login link to " + email + ". Copy the link of the mail (do not open it) and enter it here:"
+		err := substack.RequestLoginLink(email)
+		if err != nil {
+			log.Printf("Error requesting login link: %s", err.Error())
+			content = "The login link could not be requested: " + err.Error()
+		}
+		edit := &discordgo.WebhookEdit{Content: &content}
+		if err == nil {
+			edit.Components = &[]discordgo.MessageComponent{
+				discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+					discordgo.Button{
+						Label:    "Enter login link",
+						Style:    discordgo.PrimaryButton,
+						CustomID: "loginlink",
+					},
+				}},
+			}
+		}
+		s.InteractionResponseEdit(i.Interaction, edit)
+		return
+	}
+
+	session, err := substack.Login(email, password)
+	storeLogin(s, i, session, err)
+}
+
+// handleLoginLinkButton asks for the login link Substack sent by mail
+func handleLoginLinkButton(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseModal,
+		Data: &discordgo.InteractionResponseData{
+			CustomID: "loginlink",
+			Title:    "Log in to Substack",
+			Components: []discordgo.MessageComponent{
+				discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+					discordgo.TextInput{
+						CustomID:    "link",
+						Label:       "Login link from the mail",
+						Style:       discordgo.TextInputParagraph,
+						Placeholder: "https://substack.com/...",
+						Required:    true,
+						MaxLength:   2000,
+					},
+				}},
+			},
+		},
+	})
+	if err != nil {
+		log.Printf("Error showing login link form: %s", err.Error())
+	}
+}
+
+// handleLoginLinkSubmit opens the login link in place of the user to create the session
+func handleLoginLinkSubmit(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Flags: discordgo.MessageFlagsEphemeral,
+		},
+	})
+	session, err := substack.ConfirmLoginLink(modalValue(i.ModalSubmitData(), "link"))
+	storeLogin(s, i, session, err)
+}
+
+// storeLogin stores the session of a successful login and reports the outcome to the user
+func storeLogin(s *discordgo.Session, i *discordgo.InteractionCreate, session *substack.Session, loginErr error) {
+	editResponse := func(content string) {
+		s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+			Content: &content,
+		})
+	}
+
+	switch {
+	case errors.Is(loginErr, substack.ErrInvalidCredentials), errors.Is(loginErr, substack.ErrInvalidLink):
+		editResponse("Login failed, " + loginErr.Error())
+		return
+	case errors.Is(loginErr, substack.ErrNoSession):
+		editResponse("Login failed, the link may have expired or was used already. Please try again with `/login`")
+		return
+	case loginErr != nil:
+		log.Printf("Error logging in to Substack: %s", loginErr.Error())
+		editResponse("Login failed: " + loginErr.Error())
+		return
+	}
+
+	user, err := db.GetOrCreateUser(i.Interaction.User.ID, i.User.Username)
+	if err != nil {
+		log.Printf("Error getting user: %s", err.Error())
+		editResponse("An internal error occurred")
+		return
+	}
+	err = db.SetSubstackSession(*user.ID, session.Cookie, session.Username)
+	if err != nil {
+		log.Printf("Error storing session: %s", err.Error())
+		editResponse("An internal error occurred while storing the session")
+		return
+	}
+
+	content := "Logged in to Substack, the session has been stored"
+	if account, err := substack.Validate(session.Cookie); err == nil {
+		content = "Logged in to Substack, the session has been stored.\n" + account.Summary()
+	} else if session.Username != "" {
+		content = "Logged in to Substack as " + session.Username + ", the session has been stored"
+	}
+	editResponse(content)
+}
+
+// modalValue returns the value of a text input of the submitted modal
+func modalValue(data discordgo.ModalSubmitInteractionData, customID string) string {
+	for _, row := range data.Components {
+		actionsRow, ok := row.(*discordgo.ActionsRow)
+		if !ok {
+			continue
+		}
+		for _, component := range actionsRow.Components {
+			if input, ok := component.(*discordgo.TextInput); ok && input.CustomID == customID {
+				return input.Value
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,76 @@
+package discord
+
+import (
+	"fmt"
+	"github.com/bwmarrin/discordgo"
+	"kindExport/internal/export"
+)
+
+// exportStatus describes the current stage of an export in a single line
+func exportStatus(progress export.Progress) string {
+	if progress.Message != "" {
+		if progress.Final && progress.Summary != nil {
+			return "✅ " + progress.Message
+		}
+		if progress.Final {
+			return "❌ " + progress.Message
+		}
+		return "⚠️ " + progress.Message
+	}
+
+	switch progress.Stage {
+	case export.StageResolving:
+		return "⏳ Resolving the URL..."
+	case export.StageScraping:
+		return "⏳ Fetching the article, this may take a few seconds..."
+	case export.StageImages:
+		return fmt.Sprintf("⏳ Downloading images (%d/%d)...", progress.ImagesDone, progress.ImagesTotal)
+	case export.StageBuilding:
+		if progress.ImagesTotal > 0 {
+			return fmt.Sprintf("⏳ Building the epub with %d images...", progress.ImagesTotal)
+		}
+		return "⏳ Building the epub..."
+	case export.StageSending:
+		return "⏳ Sending the epub to your kindle mail address..."
+	case export.StageDone:
+		return "✅ Done"
+	}
+	return "⏳ Export has been queued"
+}
+
+// exportSummary describes the exported article
+func exportSummary(summary export.Summary) *discordgo.MessageEmbed {
+	embed := &discordgo.MessageEmbed{
+		Title: truncate(summary.Title, 256),
+		Color: 0x2e7d32,
+		Fields: []*discordgo.MessageEmbedField{
+			{Name: "Author", Value: fieldValue(summary.Author), Inline: true},
+			{Name: "Size", Value: formatSize(summary.Size), Inline: true},
+			{Name: "Destination", Value: fieldValue(summary.Destination)},
+		},
+	}
+	if summary.Preview {
+		embed.Color = 0xf9a825
+		embed.Description = "Preview only, the rest of the article is reserved for paying subscribers"
+	}
+	return embed
+}
+
+// formatSize returns the size in bytes in a human readable form
+func formatSize(size int64) string {
+	switch {
+	case size >= 1024*1024:
+		return fmt.Sprintf("%.1f MB", float64(size)/1024/1024)
+	case size >= 1024:
+		return fmt.Sprintf("%.1f KB", float64(size)/1024)
+	}
+	return fmt.Sprintf("%d bytes", size)
+}
+
+// fieldValue prepares the text for an embed field, which must neither be empty nor too long
+func fieldValue(text string) string {
+	if text == "" {
+		return "Unknown"
+	}
+	return truncate(text, 1024)
+}
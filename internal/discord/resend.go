@@ -0,0 +1,117 @@
+package discord
+
+import (
+	"errors"
+	"fmt"
+	"github.com/bwmarrin/discordgo"
+	"kindExport/internal/db"
+	"kindExport/internal/export"
+	"kindExport/internal/mailer"
+	"log"
+	"strconv"
+)
+
+func handleResend(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	user, err := db.GetUser(i.Interaction.User.ID)
+	if err != nil {
+		log.Printf("Error querying user: %s", err.Error())
+	}
+
+	if i.Type == discordgo.InteractionApplicationCommandAutocomplete {
+		var choices []*discordgo.ApplicationCommandOptionChoice
+		if user != nil {
+			// Discord accepts at most 25 choices
+			entries, err := db.SearchHistory(*user.ID, i.ApplicationCommandData().Options[0].StringValue(), 25)
+			if err != nil {
+				log.Printf("Error querying history: %s", err.Error())
+			}
+			for _, entry := range entries {
+				choices = append(choices, &discordgo.ApplicationCommandOptionChoice{
+					Name:  truncate(fmt.Sprintf("%s (%s)", entry.Article.Title, entry.Article.Author), 100),
+					Value: strconv.Itoa(int(*entry.Article.ID)),
+				})
+			}
+		}
+		s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionApplicationCommandAutocompleteResult,
+			Data: &discordgo.InteractionResponseData{
+				Choices: choices,
+			},
+		})
+		return
+	}
+
+	respond := func(content string) {
+		s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{
+				Content: content,
+			},
+		})
+	}
+
+	id, parseErr := strconv.Atoi(i.ApplicationCommandData().Options[0].StringValue())
+	if err != nil || user == nil || parseErr != nil {
+		respond("Article not found, please pick one of the suggestions")
+		return
+	}
+	entry, err := db.GetHistoryEntry(*user.ID, int32(id))
+	if err != nil {
+		log.Printf("Error querying history: %s", err.Error())
+		respond("An internal error occurred")
+		return
+	}
+	if entry == nil {
+		respond("Article not found, please pick one of the suggestions")
+		return
+	}
+	if user.KindleMail == nil || *user.KindleMail == "" {
+		respond("Mail address is not configured, please set it with the `/mail` command first")
+		return
+	}
+
+	// Scraping and sending may take longer than discord waits for a response
+	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+	})
+	editResponse := func(content string) {
+		s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+			Content: &content,
+		})
+	}
+
+	ebookPath, err := export.HistoryEpub(user, *entry)
+	if errors.Is(err, export.ErrWithheld) {
+		editResponse("The article is behind a paywall, export it again once you provided a session with a subscription via the `/session` command")
+		return
+	}
+	if err != nil {
+		log.Printf("Error fetching article again: %s", err.Error())
+		editResponse("The epub is no longer available and could not be fetched again: " + err.Error())
+		return
+	}
+	renditionID := entry.Article.ID
+	if entry.Preview {
+		renditionID = nil
+	}
+	ebookPath, err = export.Rendition(renditionID, ebookPath, user)
+	if err != nil {
+		log.Printf("Error converting article: %s", err.Error())
+		editResponse("The epub could not be converted to " + user.Format + ": " + err.Error())
+		return
+	}
+
+	err = mailer.SendMail(*user.KindleMail, ebookPath)
+	if err != nil {
+		log.Printf("Error sending mail: %s", err.Error())
+		if err := db.RecordUserArticle(*user.ID, *entry.Article.ID, db.StatusFailed, entry.Preview); err != nil {
+			log.Printf("Error recording article for user: %s", err.Error())
+		}
+		editResponse("Error sending " + user.Format + " to kindle mail address: " + err.Error())
+		return
+	}
+	if err := db.RecordUserArticle(*user.ID, *entry.Article.ID, db.StatusSent, entry.Preview); err != nil {
+		log.Printf("Error recording article for user: %s", err.Error())
+	}
+	editResponse(fmt.Sprintf("Sent %s to your kindle mail address again", entry.Article.Title))
+}
@@ -0,0 +1,55 @@
+package discord
+
+import (
+	"github.com/bwmarrin/discordgo"
+	"kindExport/internal/config"
+	"kindExport/internal/db"
+	"log"
+	"strings"
+)
+
+// bookmarklet posts the URL and the rendered HTML of the current page to the share link in a new tab
+const bookmarklet = `javascript:(function(){var f=document.createElement('form');f.method='POST';f.action='SHARE_URL';` +
+	`f.target='_blank';f.enctype='multipart/form-data';f.acceptCharset='utf-8';f.style.display='none';` +
+	`[['url',location.href],['html',document.documentElement.outerHTML]].forEach(function(p){` +
+	`var t=document.createElement('textarea');t.name=p[0];t.value=p[1];f.appendChild(t)});` +
+	`document.body.appendChild(f);f.submit();f.remove()})()`
+
+// handleShare creates a personal share link for bookmarklets and share sheets, previous links of the user stop working
+func handleShare(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	// The link exports articles for the user, so it is only shown to them
+	respond := func(content string) {
+		s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{
+				Content: content,
+				Flags:   discordgo.MessageFlagsEphemeral,
+			},
+		})
+	}
+
+	conf, err := config.GetConfig()
+	if err != nil || conf.HTTPAddress == "" {
+		respond("Sharing is not enabled on this bot")
+		return
+	}
+	user, err := db.GetOrCreateUser(i.Interaction.User.ID, i.User.Username)
+	if err != nil {
+		log.Printf("Error getting user: %s", err.Error())
+		respond("An internal error occurred")
+		return
+	}
+	token, err := db.CreateToken(*user.ID, db.TokenScopeShare)
+	if err != nil {
+		log.Printf("Error creating token: %s", err.Error())
+		respond("An internal error occurred while creating the share link")
+		return
+	}
+
+	shareURL := conf.PublicURL + "/share/" + token
+	respond("Your personal share link is <" + shareURL + ">, links you created before no longer work.\n" +
+		"The link expires on " + db.TokenExpiry(db.TokenScopeShare).Format("Jan 02, 2006") + ", create a new one with `/share` afterwards.\n" +
+		"Add a bookmark with this address to export the page you are reading, including paywalled content you can see:\n" +
+		"```\n" + strings.Replace(bookmarklet, "SHARE_URL", shareURL, 1) + "\n```\n" +
+		"Share sheet apps like iOS Shortcuts can POST the link as form field `url` (or inside `text`) to the share link.")
+}
@@ -0,0 +1,152 @@
+package discord
+
+import (
+	"errors"
+	"fmt"
+	"github.com/bwmarrin/discordgo"
+	"kindExport/internal/db"
+	"kindExport/internal/feed"
+	"log"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+func handleSubscribe(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	urlValue := i.ApplicationCommandData().Options[0].StringValue()
+
+	if _, err := url.ParseRequestURI(urlValue); err != nil {
+		s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{
+				Content: "Invalid URL",
+			},
+		})
+		return
+	}
+
+	// Finding the feed takes a few requests, so the response is sent once it is done
+	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+	})
+	reply := func(content string) {
+		s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+			Content: &content,
+		})
+	}
+
+	user, err := db.GetOrCreateUser(i.Interaction.User.ID, i.User.Username)
+	if err != nil {
+		log.Printf("Error getting user: %s", err.Error())
+		reply("An internal error occurred")
+		return
+	}
+
+	feedURL, err := feed.Discover(urlValue)
+	if err != nil {
+		reply("Could not find a feed for this URL: " + err.Error())
+		return
+	}
+	parsed, err := feed.Fetch(feedURL)
+	if err != nil {
+		reply("Could not read the feed: " + err.Error())
+		return
+	}
+
+	title, err := feed.Subscribe(*user.ID, feedURL, parsed)
+	if errors.Is(err, feed.ErrAlreadySubscribed) {
+		reply("You are already subscribed to " + title)
+		return
+	}
+	if err != nil {
+		log.Printf("Error adding subscription: %s", err.Error())
+		reply("An internal error occurred while adding the subscription")
+		return
+	}
+
+	message := fmt.Sprintf("Subscribed to %s, new posts will be sent to your mail address", title)
+	if user.KindleMail == nil || *user.KindleMail == "" {
+		message += ". No mail address is configured yet, set it with the `/mail` command"
+	}
+	reply(message)
+}
+
+func handleUnsubscribe(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	user, err := db.GetUser(i.Interaction.User.ID)
+	if err != nil {
+		log.Printf("Error querying user: %s", err.Error())
+	}
+
+	if i.Type == discordgo.InteractionApplicationCommandAutocomplete {
+		var choices []*discordgo.ApplicationCommandOptionChoice
+		if user != nil {
+			typed := strings.ToLower(i.ApplicationCommandData().Options[0].StringValue())
+			subscriptions, err := db.GetSubscriptions(user.ID)
+			if err != nil {
+				log.Printf("Error querying subscriptions: %s", err.Error())
+			}
+			for _, subscription := range subscriptions {
+				if !strings.Contains(strings.ToLower(subscription.Title), typed) {
+					continue
+				}
+				choices = append(choices, &discordgo.ApplicationCommandOptionChoice{
+					Name:  truncate(subscription.Title, 100),
+					Value: strconv.Itoa(int(*subscription.ID)),
+				})
+				// Discord accepts at most 25 choices
+				if len(choices) == 25 {
+					break
+				}
+			}
+		}
+		s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionApplicationCommandAutocompleteResult,
+			Data: &discordgo.InteractionResponseData{
+				Choices: choices,
+			},
+		})
+		return
+	}
+
+	id, parseErr := strconv.Atoi(i.ApplicationCommandData().Options[0].StringValue())
+	if err != nil || user == nil || parseErr != nil {
+		s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{
+				Content: "Subscription not found, please pick one of the suggestions",
+			},
+		})
+		return
+	}
+
+	removed, err := db.RemoveSubscription(*user.ID, int32(id))
+	if err != nil {
+		log.Printf("Error removing subscription: %s", err.Error())
+		s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{
+				Content: "An internal error occurred",
+			},
+		})
+		return
+	}
+	content := "Subscription has been removed"
+	if !removed {
+		content = "Subscription not found, please pick one of the suggestions"
+	}
+	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: content,
+		},
+	})
+}
+
+// truncate shortens the text to the given amount of characters
+func truncate(text string, length int) string {
+	runes := []rune(text)
+	if len(runes) <= length {
+		return text
+	}
+	return string(runes[:length-1]) + "…"
+}
@@ -0,0 +1,32 @@
+package discord
+
+import (
+	"fmt"
+	"github.com/bwmarrin/discordgo"
+	"io"
+	"kindExport/internal/scrape"
+	"net/http"
+)
+
+// maxUploadSize limits the size of pages uploaded with the /export command
+const maxUploadSize = 10 << 20
+
+// readUpload downloads a page the user attached to the /export command
+func readUpload(attachment *discordgo.MessageAttachment) (*scrape.Page, error) {
+	resp, err := http.Get(attachment.URL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("downloading the attachment failed with status %s", resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxUploadSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxUploadSize {
+		return nil, fmt.Errorf("the attachment is larger than %d bytes", maxUploadSize)
+	}
+	return scrape.ParseUpload(attachment.Filename, data)
+}
@@ -0,0 +1,188 @@
+package ebook
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/JohannesKaufmann/html-to-markdown"
+	"github.com/JohannesKaufmann/html-to-markdown/plugin"
+	"github.com/PuerkitoBio/goquery"
+	"html"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// assetsDirectory is the folder next to archived articles containing their images
+const assetsDirectory = "assets"
+
+// Metadata describes the article in the front matter of Markdown files
+type Metadata struct {
+	Title       string
+	Author      string
+	URL         string
+	ReleaseDate time.Time
+	Paid        bool
+}
+
+// WriteArchive writes the document as Markdown or HTML file into the directory and returns its path.
+// The images are stored in the assets folder of the directory and referenced relatively, like note-taking apps expect.
+func WriteArchive(document *Document, dir string, format string, metadata Metadata) (string, error) {
+	err := os.MkdirAll(filepath.Join(dir, assetsDirectory), os.ModePerm)
+	if err != nil {
+		return "", err
+	}
+	if metadata.Title == "" {
+		metadata.Title = document.Title
+	}
+	if metadata.Author == "" {
+		metadata.Author = document.Author
+	}
+
+	var body strings.Builder
+	for _, section := range document.Sections {
+		content, err := archiveSection(document, section, dir)
+		if err != nil {
+			return "", err
+		}
+		body.WriteString(content)
+	}
+
+	var content []byte
+	switch format {
+	case FormatMarkdown:
+		converter := md.NewConverter("", true, nil)
+		converter.Use(plugin.GitHubFlavored())
+		markdown, err := converter.ConvertString(body.String())
+		if err != nil {
+			return "", err
+		}
+		content = []byte(frontMatter(metadata) + markdown + "\n")
+	case FormatHTML:
+		content = []byte(archiveHTML(metadata, body.String()))
+	default:
+		return "", fmt.Errorf("unsupported archive format %s", format)
+	}
+
+	target := filepath.Join(dir, filepath.Base(dir)+map[string]string{FormatMarkdown: ".md", FormatHTML: ".html"}[format])
+	return target, os.WriteFile(target, content, 0o644)
+}
+
+// archiveSection stores the images of the section in the assets folder and returns the rewritten body
+func archiveSection(document *Document, section Section, dir string) (string, error) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(section.Body))
+	if err != nil {
+		return "", err
+	}
+
+	var writeErr error
+	doc.Find("img").Each(func(i int, selection *goquery.Selection) {
+		src, _ := selection.Attr("src")
+		imagePath := section.ResolveImage(src)
+		content, ok := document.Images[imagePath]
+		if !ok {
+			selection.Remove()
+			return
+		}
+		name := path.Join(assetsDirectory, path.Base(imagePath))
+		if err := os.WriteFile(filepath.Join(dir, name), content, 0o644); err != nil {
+			writeErr = err
+			return
+		}
+		selection.SetAttr("src", name)
+	})
+	if writeErr != nil {
+		return "", writeErr
+	}
+	// Links to other files of the epub do not exist in the archive
+	doc.Find("a[href]").Each(func(i int, selection *goquery.Selection) {
+		href, _ := selection.Attr("href")
+		if !isExternalLink(href) {
+			selection.Contents().Unwrap()
+		}
+	})
+	return doc.Find("body").Html()
+}
+
+// frontMatter returns the YAML front matter of the Markdown file
+func frontMatter(metadata Metadata) string {
+	var buf strings.Builder
+	buf.WriteString("---\n")
+	buf.WriteString("title: " + yamlString(metadata.Title) + "\n")
+	buf.WriteString("author: " + yamlString(metadata.Author) + "\n")
+	if metadata.URL != "" {
+		buf.WriteString("url: " + yamlString(metadata.URL) + "\n")
+	}
+	if !metadata.ReleaseDate.IsZero() {
+		buf.WriteString("release_date: " + metadata.ReleaseDate.UTC().Format(time.DateOnly) + "\n")
+	}
+	buf.WriteString(fmt.Sprintf("paid: %t\n", metadata.Paid))
+	buf.WriteString("---\n\n")
+	return buf.String()
+}
+
+// yamlString quotes the value, JSON strings are valid double-quoted YAML scalars
+func yamlString(value string) string {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	_ = encoder.Encode(value)
+	return strings.TrimSpace(buf.String())
+}
+
+func archiveHTML(metadata Metadata, body string) string {
+	var buf strings.Builder
+	buf.WriteString("<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\"/>\n")
+	buf.WriteString("<title>" + html.EscapeString(metadata.Title) + "</title>\n")
+	buf.WriteString("<meta name=\"author\" content=\"" + html.EscapeString(metadata.Author) + "\"/>\n")
+	if metadata.URL != "" {
+		buf.WriteString("<link rel=\"canonical\" href=\"" + html.EscapeString(metadata.URL) + "\"/>\n")
+	}
+	if !metadata.ReleaseDate.IsZero() {
+		buf.WriteString("<meta name=\"date\" content=\"" + metadata.ReleaseDate.UTC().Format(time.RFC3339) + "\"/>\n")
+	}
+	buf.WriteString("<style>body { max-width: 40em; margin: 2em auto; padding: 0 1em; font-family: sans-serif; line-height: 1.5; } img { max-width: 100%; height: auto; }</style>\n")
+	buf.WriteString("</head>\n<body>\n" + body + "\n</body>\n</html>\n")
+	return buf.String()
+}
+
+// zipArchive packs the archived file together with the assets folder for the delivery as a single file
+func zipArchive(file string, target string) error {
+	output, err := os.Create(target)
+	if err != nil {
+		return err
+	}
+	defer output.Close()
+
+	archive := zip.NewWriter(output)
+	dir := filepath.Dir(file)
+	files := []string{filepath.Base(file)}
+	assets, err := os.ReadDir(filepath.Join(dir, assetsDirectory))
+	if err != nil {
+		return err
+	}
+	for _, asset := range assets {
+		files = append(files, path.Join(assetsDirectory, asset.Name()))
+	}
+
+	for _, name := range files {
+		writer, err := archive.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: time.Now()})
+		if err != nil {
+			return err
+		}
+		input, err := os.Open(filepath.Join(dir, name))
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(writer, input)
+		input.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return archive.Close()
+}
@@ -0,0 +1,147 @@
+package ebook
+
+import (
+	"fmt"
+	"github.com/PuerkitoBio/goquery"
+	"log"
+	"strings"
+)
+
+const (
+	FormatEPUB = "epub"
+	FormatAZW3 = "azw3"
+	FormatMOBI = "mobi"
+	FormatPDF  = "pdf"
+	// FormatMarkdown and FormatHTML are archives with the article and its assets folder
+	FormatMarkdown = "markdown"
+	FormatHTML     = "html"
+)
+
+// Formats lists the supported formats, the first one is the default
+var Formats = []string{FormatEPUB, FormatAZW3, FormatMOBI, FormatPDF, FormatMarkdown, FormatHTML}
+
+// Options configure the conversion of an epub
+type Options struct {
+	// PageSize is one of PageSizes, used for formats with fixed pages
+	PageSize string
+	// FontSize is the size of the body text in points, used for formats with fixed pages
+	FontSize int
+	// Metadata is written into the front matter of archives, missing values are taken from the epub
+	Metadata Metadata
+}
+
+// Variant identifies the rendition of a format, formats with fixed pages differ by their layout
+func Variant(format string, options Options) string {
+	if format == FormatPDF {
+		return fmt.Sprintf("%s-%s-%d", format, options.PageSize, options.FontSize)
+	}
+	return format
+}
+
+// Convert writes the epub in the given format next to it and returns the path of the new file
+func Convert(epubPath string, format string, options Options) (string, error) {
+	if format == FormatEPUB {
+		return epubPath, nil
+	}
+
+	document, err := Read(epubPath)
+	if err != nil {
+		return "", err
+	}
+	target := strings.TrimSuffix(epubPath, ".epub") + "." + format
+	switch format {
+	case FormatAZW3:
+		err = WriteAZW3(document, target)
+	case FormatMOBI:
+		err = WriteMOBI(document, target)
+	case FormatPDF:
+		target = fmt.Sprintf("%s %s %dpt.pdf", strings.TrimSuffix(epubPath, ".epub"), strings.ToUpper(options.PageSize), options.FontSize)
+		err = WritePDF(document, target, options)
+	case FormatMarkdown, FormatHTML:
+		// The archive is kept unpacked in the directory of the article and delivered as zip file
+		var file string
+		file, err = WriteArchive(document, strings.TrimSuffix(epubPath, ".epub"), format, options.Metadata)
+		if err == nil {
+			target = fmt.Sprintf("%s %s.zip", strings.TrimSuffix(epubPath, ".epub"), format)
+			err = zipArchive(file, target)
+		}
+	default:
+		return "", fmt.Errorf("unsupported format %s", format)
+	}
+	if err != nil {
+		return "", err
+	}
+	return target, nil
+}
+
+// resources holds the images of a document as records of a MOBI file
+type resources struct {
+	document *Document
+	// Records contains the prepared images in the order they are referenced
+	Records [][]byte
+	// Cover is the 1-based index of the cover image, 0 if the document has none
+	Cover     int
+	indices   map[string]int
+	mimeTypes map[string]string
+}
+
+func newResources(document *Document) *resources {
+	res := &resources{
+		document:  document,
+		indices:   map[string]int{},
+		mimeTypes: map[string]string{},
+	}
+	if document.Cover != "" {
+		if index, _, ok := res.add(document.Cover); ok {
+			res.Cover = index
+		}
+	}
+	return res
+}
+
+// add returns the 1-based index and mime type of the image, adding it to the records on first use
+func (r *resources) add(imagePath string) (int, string, bool) {
+	if index, ok := r.indices[imagePath]; ok {
+		return index, r.mimeTypes[imagePath], true
+	}
+	content, ok := r.document.Images[imagePath]
+	if !ok {
+		return 0, "", false
+	}
+	data, mimeType, err := prepareImage(content)
+	if err != nil {
+		log.Printf("Error preparing image %s: %s", imagePath, err.Error())
+		return 0, "", false
+	}
+	r.Records = append(r.Records, data)
+	r.indices[imagePath] = len(r.Records)
+	r.mimeTypes[imagePath] = mimeType
+	return len(r.Records), mimeType, true
+}
+
+// rewriteBody replaces the source of every image with the attribute returned by reference and removes
+// links to other files of the epub which do not exist in the converted book
+func rewriteBody(section Section, attribute string, reference func(index int, mimeType string) string, res *resources) (string, error) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(section.Body))
+	if err != nil {
+		return "", err
+	}
+	doc.Find("img").Each(func(i int, selection *goquery.Selection) {
+		src, _ := selection.Attr("src")
+		index, mimeType, ok := res.add(section.ResolveImage(src))
+		if !ok {
+			selection.Remove()
+			return
+		}
+		selection.RemoveAttr("src")
+		selection.SetAttr(attribute, reference(index, mimeType))
+	})
+	doc.Find("a[href]").Each(func(i int, selection *goquery.Selection) {
+		href, _ := selection.Attr("href")
+		if isExternalLink(href) {
+			return
+		}
+		selection.Contents().Unwrap()
+	})
+	return doc.Find("body").Html()
+}
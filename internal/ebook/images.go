@@ -0,0 +1,67 @@
+package ebook
+
+import (
+	"bytes"
+	"golang.org/x/image/draw"
+	"image"
+	_ "image/gif"
+	"image/jpeg"
+	_ "image/png"
+	"net/http"
+
+	_ "golang.org/x/image/webp"
+)
+
+const (
+	// maxImageSize is the largest image older Kindle devices display
+	maxImageSize   = 127 * 1024
+	maxImageWidth  = 1072
+	maxImageHeight = 1448
+)
+
+// prepareImage returns the image in a format supported by Kindle devices together with its mime type.
+// Images in other formats or above the size limit are scaled down and encoded as JPEG.
+func prepareImage(data []byte) ([]byte, string, error) {
+	mimeType := http.DetectContentType(data)
+	switch mimeType {
+	case "image/jpeg", "image/png", "image/gif":
+		if len(data) <= maxImageSize {
+			return data, mimeType, nil
+		}
+	}
+
+	data, err := encodeJPEG(data)
+	if err != nil {
+		return nil, "", err
+	}
+	return data, "image/jpeg", nil
+}
+
+// encodeJPEG decodes the image and encodes it as JPEG within the size limit
+func encodeJPEG(data []byte) ([]byte, error) {
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	bounds := img.Bounds()
+	scale := min(1, float64(maxImageWidth)/float64(bounds.Dx()), float64(maxImageHeight)/float64(bounds.Dy()))
+	for {
+		width, height := max(1, int(float64(bounds.Dx())*scale)), max(1, int(float64(bounds.Dy())*scale))
+		scaled := image.NewRGBA(image.Rect(0, 0, width, height))
+		// JPEG has no transparency, so transparent images are put on a white background
+		draw.Draw(scaled, scaled.Bounds(), image.White, image.Point{}, draw.Src)
+		draw.CatmullRom.Scale(scaled, scaled.Bounds(), img, bounds, draw.Over, nil)
+
+		for _, quality := range []int{85, 70, 50} {
+			var buf bytes.Buffer
+			err = jpeg.Encode(&buf, scaled, &jpeg.Options{Quality: quality})
+			if err != nil {
+				return nil, err
+			}
+			if buf.Len() <= maxImageSize {
+				return buf.Bytes(), nil
+			}
+		}
+		scale *= 0.75
+	}
+}
@@ -0,0 +1,226 @@
+package ebook
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"math/bits"
+	"strings"
+)
+
+// kf8Stylesheet is the only CSS flow of the generated books
+const kf8Stylesheet = `img { max-width: 100%; height: auto; }
+pre { white-space: pre-wrap; }
+blockquote { margin-left: 1em; font-style: italic; }
+`
+
+// indexTag describes a tag of the entries of an index, see the TAGX section of the MOBI format
+type indexTag struct {
+	Number         byte
+	ValuesPerEntry byte
+	Mask           byte
+}
+
+// indexEntry has one list of values per tag of the index, in the order of the tags
+type indexEntry struct {
+	Label  string
+	Values [][]uint32
+}
+
+var (
+	// The skeleton index lists the files of the book and where their skeleton is stored in the text
+	skeletonTags = []indexTag{{Number: 1, ValuesPerEntry: 1, Mask: 3}, {Number: 6, ValuesPerEntry: 2, Mask: 12}}
+	// The fragment index lists the content that is inserted into the skeletons
+	fragmentTags = []indexTag{{Number: 2, ValuesPerEntry: 1, Mask: 1}, {Number: 3, ValuesPerEntry: 1, Mask: 2}, {Number: 4, ValuesPerEntry: 1, Mask: 4}, {Number: 6, ValuesPerEntry: 2, Mask: 8}}
+)
+
+// WriteAZW3 writes the document as KF8 book, the format of AZW3 files.
+// Every section becomes a file of the book consisting of a skeleton and a single fragment with the body.
+func WriteAZW3(document *Document, target string) error {
+	res := newResources(document)
+
+	var markup bytes.Buffer
+	var skeletons, fragments []indexEntry
+	var selectors [][]byte
+	for i, section := range document.Sections {
+		body, err := rewriteBody(section, "src", func(index int, mimeType string) string {
+			return fmt.Sprintf("kindle:embed:%s?mime=%s", toBase32(index, 4), mimeType)
+		}, res)
+		if err != nil {
+			return err
+		}
+
+		title := section.Title
+		if title == "" {
+			title = document.Title
+		}
+		aid := toBase32(i, 1)
+		head := fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?><html xmlns="http://www.w3.org/1999/xhtml"><head><title>%s</title>`+
+			`<link href="kindle:flow:0001?mime=text/css" rel="stylesheet" type="text/css"/></head><body aid="%s">`, html.EscapeString(title), aid)
+		tail := "</body></html>"
+
+		start := uint32(markup.Len())
+		skeletonLength := uint32(len(head) + len(tail))
+		markup.WriteString(head + tail + body)
+
+		skeletons = append(skeletons, indexEntry{
+			Label: fmt.Sprintf("SKEL%010d", i),
+			// The values are repeated like in the books created by kindlegen
+			Values: [][]uint32{{1, 1}, {start, skeletonLength, start, skeletonLength}},
+		})
+		cncxOffset := uint32(0)
+		for _, selector := range selectors {
+			cncxOffset += uint32(len(selector))
+		}
+		selector := []byte(fmt.Sprintf("P-//*[@aid='%s']", aid))
+		selectors = append(selectors, append(encodeInt(uint32(len(selector))), selector...))
+		insertPosition := start + uint32(len(head))
+		fragments = append(fragments, indexEntry{
+			Label:  fmt.Sprintf("%010d", insertPosition),
+			Values: [][]uint32{{cncxOffset}, {uint32(i)}, {uint32(i)}, {0, uint32(len(body))}},
+		})
+	}
+	if markup.Len() == 0 {
+		return fmt.Errorf("the book has no content")
+	}
+
+	text := append(markup.Bytes(), kf8Stylesheet...)
+	flows := [][2]uint32{{0, uint32(markup.Len())}, {uint32(markup.Len()), uint32(len(text))}}
+
+	records := [][]byte{nil}
+	records = append(records, textRecords(text)...)
+	firstNonText := uint32(len(records))
+
+	fragmentIndex := uint32(len(records))
+	records = append(records, buildIndex(fragmentTags, fragments, [][]byte{alignBlock(bytes.Join(selectors, nil))})...)
+	skeletonIndex := uint32(len(records))
+	records = append(records, buildIndex(skeletonTags, skeletons, nil)...)
+
+	firstResource := uint32(len(records))
+	records = append(records, res.Records...)
+
+	fdst := uint32(len(records))
+	var fdstRecord bytes.Buffer
+	fdstRecord.WriteString("FDST")
+	write(&fdstRecord, uint32(12), uint32(len(flows)))
+	for _, flow := range flows {
+		write(&fdstRecord, flow[0], flow[1])
+	}
+	records = append(records, fdstRecord.Bytes())
+	flis := uint32(len(records))
+	records = append(records, flisRecord)
+	fcis := uint32(len(records))
+	records = append(records, fcisRecord(len(text)), eofRecord)
+
+	exth := []exthRecord{
+		exthString(100, document.Author),
+		exthString(503, document.Title),
+		exthString(524, "en"),
+		exthString(501, "EBOK"),
+		exthNumber(125, uint32(len(res.Records))),
+	}
+	if res.Cover > 0 {
+		exth = append(exth,
+			exthNumber(201, uint32(res.Cover-1)),
+			exthString(129, "kindle:embed:"+toBase32(res.Cover, 4)),
+		)
+	}
+	records[0] = mobiHeader{
+		Version:        8,
+		TextLength:     len(text),
+		TextRecords:    int(firstNonText) - 1,
+		FirstNonText:   firstNonText,
+		FirstResource:  firstResource,
+		ExtraDataFlags: extraDataMultibyte,
+		Title:          document.Title,
+		Exth:           exth,
+		FCIS:           fcis,
+		FLIS:           flis,
+		FDST:           fdst,
+		FDSTCount:      uint32(len(flows)),
+		FragmentIndex:  fragmentIndex,
+		SkeletonIndex:  skeletonIndex,
+	}.record0()
+
+	return writePalmDB(target, document.Title, records)
+}
+
+// buildIndex creates the header record and a single data record of an index, followed by the CNCX records
+func buildIndex(tags []indexTag, entries []indexEntry, cncx [][]byte) [][]byte {
+	const headerLength = 192
+
+	var entryData bytes.Buffer
+	var offsets []uint16
+	for _, entry := range entries {
+		offsets = append(offsets, uint16(headerLength+entryData.Len()))
+		entryData.WriteByte(byte(len(entry.Label)))
+		entryData.WriteString(entry.Label)
+		controlByte := byte(0)
+		for i, tag := range tags {
+			count := byte(len(entry.Values[i]) / int(tag.ValuesPerEntry))
+			controlByte |= tag.Mask & (count << bits.TrailingZeros8(tag.Mask))
+		}
+		entryData.WriteByte(controlByte)
+		for _, values := range entry.Values {
+			for _, value := range values {
+				entryData.Write(encodeInt(value))
+			}
+		}
+	}
+	entryBlock := alignBlock(entryData.Bytes())
+	var idxt bytes.Buffer
+	idxt.WriteString("IDXT")
+	for _, offset := range offsets {
+		write(&idxt, offset)
+	}
+
+	var data bytes.Buffer
+	data.WriteString("INDX")
+	write(&data, uint32(headerLength), uint32(0), uint32(1), uint32(0), uint32(headerLength+len(entryBlock)), uint32(len(entries)))
+	write(&data, uint32(null), uint32(null))
+	data.Write(make([]byte, headerLength-data.Len()))
+	data.Write(entryBlock)
+	data.Write(alignBlock(idxt.Bytes()))
+
+	var tagx bytes.Buffer
+	tagx.WriteString("TAGX")
+	write(&tagx, uint32(12+4*(len(tags)+1)), uint32(1))
+	for _, tag := range tags {
+		tagx.Write([]byte{tag.Number, tag.ValuesPerEntry, tag.Mask, 0})
+	}
+	tagx.Write([]byte{0, 0, 0, 1})
+
+	// The header lists the last label and the amount of entries of every data record
+	lastLabel := entries[len(entries)-1].Label
+	var geometry bytes.Buffer
+	geometry.WriteByte(byte(len(lastLabel)))
+	geometry.WriteString(lastLabel)
+	write(&geometry, uint16(len(entries)))
+	geometryBlock := alignBlock(geometry.Bytes())
+
+	var header bytes.Buffer
+	header.WriteString("INDX")
+	idxtOffset := uint32(headerLength + tagx.Len() + len(geometryBlock))
+	write(&header, uint32(headerLength), uint32(0), uint32(0), uint32(2), idxtOffset, uint32(1), uint32(65001), uint32(null), uint32(len(entries)))
+	write(&header, uint32(0), uint32(0), uint32(0), uint32(len(cncx)))
+	header.Write(make([]byte, 124))
+	write(&header, uint32(headerLength), uint32(0), uint32(0))
+	header.Write(tagx.Bytes())
+	header.Write(geometryBlock)
+	header.WriteString("IDXT")
+	write(&header, uint16(headerLength+tagx.Len()))
+
+	records := [][]byte{alignBlock(header.Bytes()), data.Bytes()}
+	return append(records, cncx...)
+}
+
+// toBase32 formats the number with the digits used by kindle references, padded to the given length
+func toBase32(number int, length int) string {
+	const digits = "0123456789ABCDEFGHIJKLMNOPQRSTUV"
+	var result []byte
+	for number > 0 {
+		result = append([]byte{digits[number%32]}, result...)
+		number /= 32
+	}
+	return strings.Repeat("0", max(0, length-len(result))) + string(result)
+}
@@ -0,0 +1,96 @@
+package ebook
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteAZW3(t *testing.T) {
+	document := testDocument(t)
+	target := filepath.Join(t.TempDir(), "book.azw3")
+	if err := WriteAZW3(document, target); err != nil {
+		t.Fatalf("WriteAZW3 failed: %s", err)
+	}
+	book := readPalmDB(t, target)
+	record0 := book.Records[0]
+
+	if length := field32(record0, offsetMobiHeaderLen); length != 0x108 {
+		t.Errorf("MOBI header length = %#x, want 0x108", length)
+	}
+	if version, minVersion := field32(record0, offsetVersion), field32(record0, offsetMinVersion); version != 8 || minVersion != 8 {
+		t.Errorf("version = %d, minimum version = %d, want 8", version, minVersion)
+	}
+
+	text := readText(t, book)
+	if !bytes.Contains(text, []byte("kindle:embed:0001?mime=image/png")) {
+		t.Errorf("image is not referenced as kindle:embed")
+	}
+	if !bytes.HasSuffix(text, []byte(kf8Stylesheet)) {
+		t.Errorf("stylesheet is not the last flow")
+	}
+
+	fdst := book.Records[field32(record0, offsetFDST)]
+	if !bytes.HasPrefix(fdst, []byte("FDST")) || field32(record0, offsetFDSTCount) != 2 || field32(fdst, 8) != 2 {
+		t.Fatalf("FDST index does not point to an FDST record with two flows")
+	}
+	if end := field32(fdst, 24); int(end) != len(text) {
+		t.Errorf("last flow ends at %d, text has %d bytes", end, len(text))
+	}
+
+	for name, offset := range map[string]int{"fragment": offsetFragmentIndex, "skeleton": offsetSkeletonIndex} {
+		header := book.Records[field32(record0, offset)]
+		if !bytes.HasPrefix(header, []byte("INDX")) {
+			t.Errorf("%s index does not point to an INDX record", name)
+			continue
+		}
+		if entries := field32(header, 36); int(entries) != len(document.Sections) {
+			t.Errorf("%s index has %d entries, want one per section", name, entries)
+		}
+		if !bytes.HasPrefix(book.Records[field32(record0, offset)+1], []byte("INDX")) {
+			t.Errorf("%s index is not followed by its data record", name)
+		}
+	}
+
+	firstResource := int(field32(record0, offsetFirstResource))
+	if !bytes.HasPrefix(book.Records[firstResource], pngSignature) {
+		t.Errorf("record %d is not the image", firstResource)
+	}
+	if fcis := book.Records[field32(record0, offsetFCIS)]; !bytes.HasPrefix(fcis, []byte("FCIS")) {
+		t.Errorf("FCIS index does not point to the FCIS record")
+	}
+	if flis := book.Records[field32(record0, offsetFLIS)]; !bytes.HasPrefix(flis, []byte("FLIS")) {
+		t.Errorf("FLIS index does not point to the FLIS record")
+	}
+
+	exth := readExth(t, record0)
+	if string(exth[100]) != "Jane Doe" || string(exth[503]) != "Round Trip" {
+		t.Errorf("EXTH author = %q, title = %q", exth[100], exth[503])
+	}
+	if resources := exth[125]; len(resources) != 4 || field32(resources, 0) != 1 {
+		t.Errorf("EXTH resource count = %x, want 1", resources)
+	}
+	if cover := string(exth[129]); cover != "kindle:embed:0001" {
+		t.Errorf("EXTH cover = %q", cover)
+	}
+
+	checkCalibreMetadata(t, target)
+}
+
+func TestToBase32(t *testing.T) {
+	tests := []struct {
+		number int
+		length int
+		want   string
+	}{
+		{number: 0, length: 1, want: "0"},
+		{number: 1, length: 4, want: "0001"},
+		{number: 31, length: 4, want: "000V"},
+		{number: 32, length: 4, want: "0010"},
+	}
+	for _, test := range tests {
+		if got := toBase32(test.number, test.length); got != test.want {
+			t.Errorf("toBase32(%d, %d) = %q, want %q", test.number, test.length, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package ebook
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+)
+
+// WriteMOBI writes the document as MOBI 6 book for Kindle devices without KF8 support.
+// The sections are combined into a single HTML document separated by page breaks.
+func WriteMOBI(document *Document, target string) error {
+	res := newResources(document)
+
+	var markup bytes.Buffer
+	markup.WriteString(fmt.Sprintf("<html><head><title>%s</title><guide></guide></head><body>", html.EscapeString(document.Title)))
+	for i, section := range document.Sections {
+		body, err := rewriteBody(section, "recindex", func(index int, mimeType string) string {
+			return fmt.Sprintf("%05d", index)
+		}, res)
+		if err != nil {
+			return err
+		}
+		if i > 0 {
+			markup.WriteString("<mbp:pagebreak/>")
+		}
+		markup.WriteString(body)
+	}
+	markup.WriteString("</body></html>")
+
+	text := markup.Bytes()
+
+	records := [][]byte{nil}
+	records = append(records, textRecords(text)...)
+	firstNonText := uint32(len(records))
+	firstResource := uint32(len(records))
+	records = append(records, res.Records...)
+	lastContent := uint32(len(records) - 1)
+
+	flis := uint32(len(records))
+	records = append(records, flisRecord)
+	fcis := uint32(len(records))
+	records = append(records, fcisRecord(len(text)), eofRecord)
+
+	exth := []exthRecord{
+		exthString(100, document.Author),
+		exthString(503, document.Title),
+		exthString(524, "en"),
+		exthString(501, "EBOK"),
+	}
+	if res.Cover > 0 {
+		exth = append(exth, exthNumber(201, uint32(res.Cover-1)))
+	}
+	records[0] = mobiHeader{
+		Version:        6,
+		TextLength:     len(text),
+		TextRecords:    int(firstNonText) - 1,
+		FirstNonText:   firstNonText,
+		FirstResource:  firstResource,
+		ExtraDataFlags: extraDataMultibyte,
+		Title:          document.Title,
+		Exth:           exth,
+		FCIS:           fcis,
+		FLIS:           flis,
+		LastContent:    lastContent,
+	}.record0()
+
+	return writePalmDB(target, document.Title, records)
+}
@@ -0,0 +1,97 @@
+package ebook
+
+import (
+	"bytes"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+var pngSignature = []byte{0x89, 'P', 'N', 'G'}
+
+func TestWriteMOBI(t *testing.T) {
+	target := filepath.Join(t.TempDir(), "book.mobi")
+	if err := WriteMOBI(testDocument(t), target); err != nil {
+		t.Fatalf("WriteMOBI failed: %s", err)
+	}
+	book := readPalmDB(t, target)
+	record0 := book.Records[0]
+
+	if book.Name != "Round_Trip" {
+		t.Errorf("database name = %q", book.Name)
+	}
+	if string(record0[16:20]) != "MOBI" {
+		t.Fatalf("MOBI header not found")
+	}
+	if length := field32(record0, offsetMobiHeaderLen); length != 0xE8 {
+		t.Errorf("MOBI header length = %#x, want 0xE8", length)
+	}
+	if version, minVersion := field32(record0, offsetVersion), field32(record0, offsetMinVersion); version != 6 || minVersion != 6 {
+		t.Errorf("version = %d, minimum version = %d, want 6", version, minVersion)
+	}
+	nameStart := int(field32(record0, offsetFullName))
+	if name := string(record0[nameStart : nameStart+int(field32(record0, offsetFullNameLen))]); name != "Round Trip" {
+		t.Errorf("full name = %q", name)
+	}
+
+	firstNonText := int(field32(record0, offsetFirstNonText))
+	if textRecords := int(field16(record0, offsetTextRecords)); textRecords != firstNonText-1 {
+		t.Errorf("%d text records, but the first non-text record is %d", textRecords, firstNonText)
+	}
+	text := readText(t, book)
+	if !bytes.Contains(text, []byte("Round trip body")) || !bytes.Contains(text, []byte("a"+strings.Repeat("äöü", 2000))) {
+		t.Errorf("text does not contain both sections")
+	}
+	if !bytes.Contains(text, []byte(`recindex="00001"`)) {
+		t.Errorf("image is not referenced by its record index")
+	}
+
+	firstResource := int(field32(record0, offsetFirstResource))
+	if !bytes.HasPrefix(book.Records[firstResource], pngSignature) {
+		t.Errorf("record %d is not the image", firstResource)
+	}
+	// The cover and the image in the text are the same file, so it is stored once
+	if lastContent := int(field16(record0, offsetLastContent)); lastContent != firstResource {
+		t.Errorf("last content record = %d, want %d", lastContent, firstResource)
+	}
+	if flis := book.Records[field32(record0, offsetFLIS)]; !bytes.HasPrefix(flis, []byte("FLIS")) {
+		t.Errorf("FLIS index does not point to the FLIS record")
+	}
+	fcis := book.Records[field32(record0, offsetFCIS)]
+	if !bytes.HasPrefix(fcis, []byte("FCIS")) || int(field32(fcis, 20)) != len(text) {
+		t.Errorf("FCIS index does not point to an FCIS record with the text length")
+	}
+	if last := book.Records[len(book.Records)-1]; !bytes.Equal(last, eofRecord) {
+		t.Errorf("last record is %x, want the EOF record", last)
+	}
+
+	exth := readExth(t, record0)
+	if string(exth[100]) != "Jane Doe" || string(exth[503]) != "Round Trip" {
+		t.Errorf("EXTH author = %q, title = %q", exth[100], exth[503])
+	}
+	if cover, ok := exth[201]; !ok || field32(cover, 0) != 0 {
+		t.Errorf("EXTH cover offset = %x, want 0", cover)
+	}
+
+	checkCalibreMetadata(t, target)
+}
+
+// checkCalibreMetadata reads the book with ebook-meta of calibre, if it is installed
+func checkCalibreMetadata(t *testing.T, target string) {
+	t.Helper()
+	if _, err := exec.LookPath("ebook-meta"); err != nil {
+		t.Log("ebook-meta is not installed, skipping the check with calibre")
+		return
+	}
+	output, err := exec.Command("ebook-meta", target).CombinedOutput()
+	if err != nil {
+		t.Fatalf("ebook-meta failed: %s\n%s", err, output)
+	}
+	for _, pattern := range []string{`(?m)^Title\s*:\s*Round Trip$`, `(?m)^Author\(s\)\s*:\s*Jane Doe`, `(?m)^Languages\s*:\s*eng`} {
+		if !regexp.MustCompile(pattern).Match(output) {
+			t.Errorf("ebook-meta output does not match %s:\n%s", pattern, output)
+		}
+	}
+}
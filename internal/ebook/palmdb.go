@@ -0,0 +1,209 @@
+package ebook
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	// textRecordSize is the amount of text stored in a single record
+	textRecordSize = 4096
+	// null marks unused record indices in the headers
+	null = 0xFFFFFFFF
+	// extraDataMultibyte signals that text records end with the bytes of a character split between two records
+	extraDataMultibyte = 0b1
+)
+
+var (
+	eofRecord  = []byte{0xE9, 0x8E, 0x0D, 0x0A}
+	flisRecord = []byte{
+		'F', 'L', 'I', 'S', 0, 0, 0, 8, 0, 0x41, 0, 0, 0, 0, 0, 0,
+		0xFF, 0xFF, 0xFF, 0xFF, 0, 1, 0, 3, 0, 0, 0, 3, 0, 0, 0, 1,
+		0xFF, 0xFF, 0xFF, 0xFF,
+	}
+)
+
+// writePalmDB writes the records into a Palm database, the container format of MOBI files
+func writePalmDB(target string, name string, records [][]byte) error {
+	var buf bytes.Buffer
+	now := uint32(time.Now().Unix())
+
+	var dbName [32]byte
+	copy(dbName[:31], strings.ReplaceAll(name, " ", "_"))
+	buf.Write(dbName[:])
+	write(&buf, uint16(0), uint16(0), now, now, uint32(0), uint32(0), uint32(0), uint32(0))
+	buf.WriteString("BOOKMOBI")
+	write(&buf, uint32(2*len(records)-1), uint32(0), uint16(len(records)))
+
+	offset := uint32(buf.Len() + 8*len(records) + 2)
+	for i, record := range records {
+		id := uint32(2 * i)
+		write(&buf, offset, uint32(id&0x00FFFFFF))
+		offset += uint32(len(record))
+	}
+	buf.Write([]byte{0, 0})
+	for _, record := range records {
+		buf.Write(record)
+	}
+	return os.WriteFile(target, buf.Bytes(), 0o644)
+}
+
+// textRecords splits the text into records and appends the multibyte trailing entry to each record
+func textRecords(text []byte) [][]byte {
+	var records [][]byte
+	for start := 0; start < len(text); start += textRecordSize {
+		end := min(start+textRecordSize, len(text))
+		record := append([]byte{}, text[start:end]...)
+		// Repeat the rest of a character which continues in the next record
+		overlap := 0
+		for end+overlap < len(text) && overlap < 3 && !utf8.RuneStart(text[end+overlap]) {
+			overlap++
+		}
+		record = append(record, text[end:end+overlap]...)
+		record = append(record, byte(overlap))
+		records = append(records, record)
+	}
+	return records
+}
+
+// exthRecord is a single metadata entry of the EXTH header
+type exthRecord struct {
+	Type uint32
+	Data []byte
+}
+
+func exthString(recordType uint32, value string) exthRecord {
+	return exthRecord{Type: recordType, Data: []byte(value)}
+}
+
+func exthNumber(recordType uint32, value uint32) exthRecord {
+	return exthRecord{Type: recordType, Data: binary.BigEndian.AppendUint32(nil, value)}
+}
+
+func exthHeader(records []exthRecord) []byte {
+	var body bytes.Buffer
+	for _, record := range records {
+		write(&body, record.Type, uint32(len(record.Data)+8))
+		body.Write(record.Data)
+	}
+	var buf bytes.Buffer
+	buf.WriteString("EXTH")
+	write(&buf, uint32(12+body.Len()), uint32(len(records)))
+	buf.Write(body.Bytes())
+	return alignBlock(buf.Bytes())
+}
+
+// mobiHeader holds the values of record 0 which differ between MOBI 6 and KF8
+type mobiHeader struct {
+	Version        uint32
+	TextLength     int
+	TextRecords    int
+	FirstNonText   uint32
+	FirstResource  uint32
+	ExtraDataFlags uint32
+	Title          string
+	Exth           []exthRecord
+	FCIS           uint32
+	FLIS           uint32
+	LastContent    uint32
+	FDST           uint32
+	FDSTCount      uint32
+	FragmentIndex  uint32
+	SkeletonIndex  uint32
+}
+
+// record0 builds the first record containing the PalmDOC header, the MOBI header, the EXTH header and the title
+func (h mobiHeader) record0() []byte {
+	headerLength := uint32(0xE8)
+	if h.Version >= 8 {
+		headerLength = 0x108
+	}
+	exth := exthHeader(h.Exth)
+
+	var buf bytes.Buffer
+	// PalmDOC header without compression
+	write(&buf, uint16(1), uint16(0), uint32(h.TextLength), uint16(h.TextRecords), uint16(textRecordSize), uint16(0), uint16(0))
+
+	buf.WriteString("MOBI")
+	write(&buf, headerLength, uint32(2), uint32(65001), uint32(time.Now().UnixNano()), h.Version)
+	// Orthographic, inflection, index names, index keys and extra indices
+	for i := 0; i < 10; i++ {
+		write(&buf, uint32(null))
+	}
+	write(&buf, h.FirstNonText, uint32(16)+headerLength+uint32(len(exth)), uint32(len(h.Title)))
+	// English, no dictionary languages
+	write(&buf, uint32(9), uint32(0), uint32(0), h.Version, h.FirstResource)
+	// No HUFF/CDIC compression and no DATP
+	write(&buf, uint32(0), uint32(0), uint32(0), uint32(0))
+	// EXTH header present
+	write(&buf, uint32(0x50))
+	buf.Write(make([]byte, 32))
+	write(&buf, uint32(null))
+	// No DRM
+	write(&buf, uint32(null), uint32(0), uint32(0), uint32(0))
+	buf.Write(make([]byte, 8))
+	if h.Version >= 8 {
+		write(&buf, h.FDST, h.FDSTCount)
+	} else {
+		write(&buf, uint16(1), uint16(h.LastContent), uint32(1))
+	}
+	write(&buf, h.FCIS, uint32(1), h.FLIS, uint32(1))
+	buf.Write(make([]byte, 8))
+	write(&buf, uint32(null), uint32(0), uint32(null), uint32(null))
+	write(&buf, h.ExtraDataFlags)
+	if h.Version >= 8 {
+		// NCX, fragment, skeleton, DATP and guide index
+		write(&buf, uint32(null), h.FragmentIndex, h.SkeletonIndex, uint32(null), uint32(null))
+		write(&buf, uint32(null), uint32(0), uint32(null), uint32(0))
+	} else {
+		// No NCX index
+		write(&buf, uint32(null))
+	}
+
+	buf.Write(exth)
+	buf.WriteString(h.Title)
+	// Readers expect some padding after the title
+	buf.Write(make([]byte, 2))
+	return alignBlock(buf.Bytes())
+}
+
+// fcisRecord returns the FCIS record which repeats the length of the text
+func fcisRecord(textLength int) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("FCIS")
+	write(&buf, uint32(0x14), uint32(0x10), uint32(1), uint32(0), uint32(textLength))
+	write(&buf, uint32(0), uint32(0x20), uint32(8), uint16(1), uint16(1), uint32(0))
+	return buf.Bytes()
+}
+
+// encodeInt encodes the value as forward variable width integer, the last byte has the high bit set
+func encodeInt(value uint32) []byte {
+	var encoded []byte
+	for {
+		encoded = append([]byte{byte(value & 0x7F)}, encoded...)
+		value >>= 7
+		if value == 0 {
+			break
+		}
+	}
+	encoded[len(encoded)-1] |= 0x80
+	return encoded
+}
+
+// alignBlock pads the data with zeros to a multiple of four bytes
+func alignBlock(data []byte) []byte {
+	if extra := len(data) % 4; extra != 0 {
+		data = append(data, make([]byte, 4-extra)...)
+	}
+	return data
+}
+
+func write(buf *bytes.Buffer, values ...interface{}) {
+	for _, value := range values {
+		_ = binary.Write(buf, binary.BigEndian, value)
+	}
+}
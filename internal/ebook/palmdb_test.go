@@ -0,0 +1,209 @@
+package ebook
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+// palmBook is a Palm database read back from disk
+type palmBook struct {
+	Name    string
+	Records [][]byte
+}
+
+// readPalmDB parses the file written by writePalmDB and checks the record list on the way
+func readPalmDB(t *testing.T, path string) palmBook {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("reading %s failed: %s", path, err)
+	}
+	if len(data) < 78 || string(data[60:68]) != "BOOKMOBI" {
+		t.Fatalf("missing BOOKMOBI type and creator")
+	}
+	count := int(binary.BigEndian.Uint16(data[76:78]))
+	if seed := binary.BigEndian.Uint32(data[68:72]); seed != uint32(2*count-1) {
+		t.Errorf("unique id seed = %d, want %d", seed, 2*count-1)
+	}
+
+	offsets := make([]int, count+1)
+	for i := 0; i < count; i++ {
+		entry := data[78+8*i:]
+		offsets[i] = int(binary.BigEndian.Uint32(entry))
+		if id := binary.BigEndian.Uint32(entry[4:]) & 0x00FFFFFF; id != uint32(2*i) {
+			t.Errorf("record %d has id %d, want %d", i, id, 2*i)
+		}
+	}
+	offsets[count] = len(data)
+	if offsets[0] != 78+8*count+2 {
+		t.Errorf("first record starts at %d, want %d", offsets[0], 78+8*count+2)
+	}
+	book := palmBook{Name: strings.TrimRight(string(data[:32]), "\x00")}
+	for i := 0; i < count; i++ {
+		if offsets[i] > offsets[i+1] {
+			t.Fatalf("record %d starts at %d after the next record at %d", i, offsets[i], offsets[i+1])
+		}
+		book.Records = append(book.Records, data[offsets[i]:offsets[i+1]])
+	}
+	return book
+}
+
+// Offsets of the fields in record 0, relative to the start of the record
+const (
+	offsetTextLength    = 4
+	offsetTextRecords   = 8
+	offsetMobiHeaderLen = 20
+	offsetVersion       = 36
+	offsetFirstNonText  = 80
+	offsetFullName      = 84
+	offsetFullNameLen   = 88
+	offsetMinVersion    = 104
+	offsetFirstResource = 108
+	offsetExthFlags     = 128
+	offsetFDST          = 192
+	offsetFDSTCount     = 196
+	offsetLastContent   = 194
+	offsetFCIS          = 200
+	offsetFLIS          = 208
+	offsetExtraData     = 240
+	offsetFragmentIndex = 248
+	offsetSkeletonIndex = 252
+)
+
+func field32(record []byte, offset int) uint32 {
+	return binary.BigEndian.Uint32(record[offset:])
+}
+
+func field16(record []byte, offset int) uint16 {
+	return binary.BigEndian.Uint16(record[offset:])
+}
+
+// readExth returns the EXTH records of record 0 by their type
+func readExth(t *testing.T, record0 []byte) map[uint32][]byte {
+	t.Helper()
+	if field32(record0, offsetExthFlags)&0x40 == 0 {
+		t.Fatalf("EXTH flag is not set")
+	}
+	start := 16 + int(field32(record0, offsetMobiHeaderLen))
+	exth := record0[start:]
+	if string(exth[:4]) != "EXTH" {
+		t.Fatalf("EXTH header not found at %d", start)
+	}
+	length, count := int(field32(exth, 4)), int(field32(exth, 8))
+	records := map[uint32][]byte{}
+	position := 12
+	for i := 0; i < count; i++ {
+		recordType, recordLength := field32(exth, position), int(field32(exth, position+4))
+		records[recordType] = exth[position+8 : position+recordLength]
+		position += recordLength
+	}
+	if position != length {
+		t.Errorf("EXTH records end at %d, header length is %d", position, length)
+	}
+	return records
+}
+
+// readText joins the text records of the book without their trailing entries
+func readText(t *testing.T, book palmBook) []byte {
+	t.Helper()
+	record0 := book.Records[0]
+	if flags := field32(record0, offsetExtraData); flags != extraDataMultibyte {
+		t.Fatalf("extra data flags = %b, want only the multibyte flag", flags)
+	}
+	var text []byte
+	for _, record := range book.Records[1 : 1+int(field16(record0, offsetTextRecords))] {
+		trailing := int(record[len(record)-1]&0b11) + 1
+		text = append(text, record[:len(record)-trailing]...)
+	}
+	if length := field32(record0, offsetTextLength); int(length) != len(text) {
+		t.Errorf("text length in the header = %d, text records contain %d bytes", length, len(text))
+	}
+	return text
+}
+
+// testDocument returns a document with a cover, an image in the text and characters split between records
+func testDocument(t *testing.T) *Document {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, 4, 4))
+	img.Set(1, 1, color.RGBA{R: 255, A: 255})
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatalf("encoding the image failed: %s", err)
+	}
+	return &Document{
+		Title:  "Round Trip",
+		Author: "Jane Doe",
+		Sections: []Section{
+			{Title: "First", Path: "EPUB/xhtml/first.xhtml", Body: `<h1>First</h1><p>Round trip body</p><img src="../images/dot.png"/>`},
+			{Title: "Second", Path: "EPUB/xhtml/second.xhtml", Body: "<p>a" + strings.Repeat("äöü", 2000) + "</p>"},
+		},
+		Images: map[string][]byte{"EPUB/images/dot.png": buf.Bytes()},
+		Cover:  "EPUB/images/dot.png",
+	}
+}
+
+func TestTextRecords(t *testing.T) {
+	text := []byte("a" + strings.Repeat("ä", 3000))
+	records := textRecords(text)
+	if len(records) != 2 {
+		t.Fatalf("got %d records, want 2", len(records))
+	}
+	// The first record ends within an ä, its second byte is repeated so the record ends with a whole character
+	if overlap := records[0][len(records[0])-1]; overlap != 1 || !utf8.Valid(records[0][:len(records[0])-1]) {
+		t.Errorf("first record repeats %d bytes and does not end with a whole character", overlap)
+	}
+	var joined []byte
+	for _, record := range records {
+		overlap := int(record[len(record)-1])
+		content := record[:len(record)-1]
+		joined = append(joined, content[:len(content)-overlap]...)
+	}
+	if !bytes.Equal(joined, text) {
+		t.Errorf("joined records differ from the text")
+	}
+}
+
+func TestEncodeInt(t *testing.T) {
+	tests := []struct {
+		value uint32
+		want  []byte
+	}{
+		{value: 0, want: []byte{0x80}},
+		{value: 0x7F, want: []byte{0xFF}},
+		{value: 0x80, want: []byte{0x01, 0x80}},
+		{value: 300, want: []byte{0x02, 0xAC}},
+	}
+	for _, test := range tests {
+		if got := encodeInt(test.value); !bytes.Equal(got, test.want) {
+			t.Errorf("encodeInt(%d) = %x, want %x", test.value, got, test.want)
+		}
+	}
+}
+
+func TestWritePalmDB(t *testing.T) {
+	target := filepath.Join(t.TempDir(), "book.mobi")
+	records := [][]byte{[]byte("zero"), []byte("first record"), {}, []byte("last")}
+	if err := writePalmDB(target, "A long book name that does not fit", records); err != nil {
+		t.Fatalf("writePalmDB failed: %s", err)
+	}
+	book := readPalmDB(t, target)
+	if book.Name != "A_long_book_name_that_does_not_" {
+		t.Errorf("name = %q, want the first 31 characters without spaces", book.Name)
+	}
+	if len(book.Records) != len(records) {
+		t.Fatalf("got %d records, want %d", len(book.Records), len(records))
+	}
+	for i, record := range records {
+		if !bytes.Equal(book.Records[i], record) {
+			t.Errorf("record %d = %q, want %q", i, book.Records[i], record)
+		}
+	}
+}
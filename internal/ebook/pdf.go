@@ -0,0 +1,414 @@
+package ebook
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/go-pdf/fpdf"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/gofont/gobolditalic"
+	"golang.org/x/image/font/gofont/goitalic"
+	"golang.org/x/image/font/gofont/gomono"
+	"golang.org/x/image/font/gofont/gomonobold"
+	"golang.org/x/image/font/gofont/gomonobolditalic"
+	"golang.org/x/image/font/gofont/gomonoitalic"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+	"log"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+const (
+	PageSizeA4     = "a4"
+	PageSizeA5     = "a5"
+	PageSizeA6     = "a6"
+	PageSizeLetter = "letter"
+)
+
+// PageSizes lists the supported page sizes of PDF files
+var PageSizes = []string{PageSizeA4, PageSizeA5, PageSizeA6, PageSizeLetter}
+
+const (
+	// pointSize converts font sizes in points to millimeters, the unit of the layout
+	pointSize = 25.4 / 72
+	// lineSpacing is the height of a line relative to the font size
+	lineSpacing = 1.4
+)
+
+var (
+	headingScales = map[atom.Atom]float64{
+		atom.H1: 1.6, atom.H2: 1.4, atom.H3: 1.2, atom.H4: 1.1, atom.H5: 1, atom.H6: 1,
+	}
+	whitespace = regexp.MustCompile(`[ \t\r\n\f]+`)
+)
+
+// pdfWriter renders the HTML of the sections as flowing text onto the pages
+type pdfWriter struct {
+	pdf      *fpdf.Fpdf
+	document *Document
+	section  Section
+	fontSize float64
+	// scale is the font size of the current heading relative to the body text
+	scale float64
+	// bold, italic and mono count the nested elements with the style
+	bold, italic, mono int
+	// link is the target of the surrounding link, empty outside of links
+	link   string
+	margin float64
+	indent float64
+	// lineStart is true if nothing was written on the current line yet
+	lineStart bool
+	// spaced is true if the space after the last block was added already
+	spaced bool
+	// space is true if the last written text ended with a space
+	space bool
+}
+
+// WritePDF writes the document as PDF with the page and font size of the options.
+// Every section starts on a new page.
+func WritePDF(document *Document, target string, options Options) error {
+	pdf := fpdf.New("P", "mm", options.PageSize, "")
+	if err := pdf.Error(); err != nil {
+		return err
+	}
+	pdf.SetTitle(document.Title, true)
+	pdf.SetAuthor(document.Author, true)
+	pdf.SetCreator("kindExport", true)
+	// The core fonts of PDF only cover Latin-1, the Go fonts are embedded for everything else
+	pdf.AddUTF8FontFromBytes("go", "", goregular.TTF)
+	pdf.AddUTF8FontFromBytes("go", "B", gobold.TTF)
+	pdf.AddUTF8FontFromBytes("go", "I", goitalic.TTF)
+	pdf.AddUTF8FontFromBytes("go", "BI", gobolditalic.TTF)
+	pdf.AddUTF8FontFromBytes("gomono", "", gomono.TTF)
+	pdf.AddUTF8FontFromBytes("gomono", "B", gomonobold.TTF)
+	pdf.AddUTF8FontFromBytes("gomono", "I", gomonoitalic.TTF)
+	pdf.AddUTF8FontFromBytes("gomono", "BI", gomonobolditalic.TTF)
+
+	pageWidth, _ := pdf.GetPageSize()
+	margin := pageWidth * 0.08
+	pdf.SetMargins(margin, margin, margin)
+	pdf.SetAutoPageBreak(true, margin)
+	pdf.SetFooterFunc(func() {
+		pdf.SetY(-margin * 0.75)
+		pdf.SetFont("go", "", float64(options.FontSize)*0.8)
+		pdf.SetTextColor(128, 128, 128)
+		pdf.CellFormat(0, 4, fmt.Sprint(pdf.PageNo()), "", 0, "C", false, 0, "")
+	})
+
+	w := &pdfWriter{
+		pdf:      pdf,
+		document: document,
+		fontSize: float64(options.FontSize),
+		scale:    1,
+		margin:   margin,
+	}
+	for _, section := range document.Sections {
+		nodes, err := html.ParseFragment(strings.NewReader(section.Body), &html.Node{
+			Type:     html.ElementNode,
+			Data:     "body",
+			DataAtom: atom.Body,
+		})
+		if err != nil {
+			return err
+		}
+		pdf.AddPage()
+		w.section = section
+		w.lineStart, w.spaced, w.space = true, true, false
+		for _, node := range nodes {
+			w.render(node)
+		}
+	}
+	return pdf.OutputFileAndClose(target)
+}
+
+func (w *pdfWriter) render(node *html.Node) {
+	if node.Type == html.TextNode {
+		w.text(node.Data)
+		return
+	}
+	if node.Type != html.ElementNode {
+		w.children(node)
+		return
+	}
+
+	switch node.DataAtom {
+	case atom.Script, atom.Style, atom.Noscript, atom.Svg:
+	case atom.Br:
+		w.newline()
+	case atom.Img:
+		w.image(node)
+	case atom.Hr:
+		w.endBlock()
+		pageWidth, _ := w.pdf.GetPageSize()
+		y := w.pdf.GetY()
+		w.pdf.SetDrawColor(160, 160, 160)
+		w.pdf.Line(w.margin+w.indent, y, pageWidth-w.margin, y)
+		w.pdf.Ln(w.lineHeight() * 0.5)
+	case atom.Pre:
+		w.endBlock()
+		w.code(textContent(node))
+		w.endBlock()
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6:
+		w.endBlock()
+		w.scale = headingScales[node.DataAtom]
+		w.bold++
+		w.children(node)
+		w.endBlock()
+		w.bold--
+		w.scale = 1
+	case atom.Blockquote:
+		indent := w.fontSize * pointSize * 2
+		w.endBlock()
+		w.setIndent(indent)
+		w.italic++
+		w.children(node)
+		w.endBlock()
+		w.italic--
+		w.setIndent(-indent)
+	case atom.Figcaption:
+		w.endBlock()
+		w.italic++
+		w.children(node)
+		w.endBlock()
+		w.italic--
+	case atom.Ul, atom.Ol:
+		w.list(node)
+	case atom.P, atom.Div, atom.Figure, atom.Section, atom.Article, atom.Header, atom.Footer,
+		atom.Table, atom.Tr, atom.Li, atom.Dl, atom.Dt, atom.Dd:
+		w.endBlock()
+		w.children(node)
+		w.endBlock()
+	case atom.B, atom.Strong:
+		w.bold++
+		w.children(node)
+		w.bold--
+	case atom.I, atom.Em, atom.Cite:
+		w.italic++
+		w.children(node)
+		w.italic--
+	case atom.Code, atom.Kbd, atom.Samp, atom.Tt:
+		w.mono++
+		w.children(node)
+		w.mono--
+	case atom.A:
+		previous := w.link
+		if href := attribute(node, "href"); isExternalLink(href) {
+			w.link = href
+		}
+		w.children(node)
+		w.link = previous
+	default:
+		w.children(node)
+	}
+}
+
+func (w *pdfWriter) children(node *html.Node) {
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		w.render(child)
+	}
+}
+
+// text writes the text with collapsed whitespace like a browser
+func (w *pdfWriter) text(data string) {
+	text := whitespace.ReplaceAllString(data, " ")
+	if w.lineStart || w.space {
+		text = strings.TrimLeft(text, " ")
+	}
+	if text == "" {
+		return
+	}
+
+	w.setFont()
+	if w.link != "" {
+		w.pdf.WriteLinkString(w.lineHeight(), text, w.link)
+	} else {
+		w.pdf.Write(w.lineHeight(), text)
+	}
+	w.lineStart, w.spaced = false, false
+	w.space = strings.HasSuffix(text, " ")
+}
+
+func (w *pdfWriter) newline() {
+	w.pdf.Ln(w.lineHeight())
+	w.lineStart, w.space = true, false
+}
+
+// endBlock finishes the current line and adds the space between blocks once
+func (w *pdfWriter) endBlock() {
+	if !w.lineStart {
+		w.newline()
+	}
+	if !w.spaced {
+		w.pdf.Ln(w.lineHeight() * 0.5)
+		w.spaced = true
+	}
+}
+
+func (w *pdfWriter) setIndent(delta float64) {
+	w.indent += delta
+	w.pdf.SetLeftMargin(w.margin + w.indent)
+	if w.lineStart {
+		w.pdf.SetX(w.margin + w.indent)
+	}
+}
+
+func (w *pdfWriter) setFont() {
+	family, style, size := "go", "", w.fontSize*w.scale
+	if w.mono > 0 {
+		family = "gomono"
+		size *= 0.85
+	}
+	if w.bold > 0 {
+		style += "B"
+	}
+	if w.italic > 0 {
+		style += "I"
+	}
+	w.pdf.SetFont(family, style, size)
+	if w.link != "" {
+		w.pdf.SetTextColor(30, 80, 160)
+	} else {
+		w.pdf.SetTextColor(0, 0, 0)
+	}
+}
+
+func (w *pdfWriter) lineHeight() float64 {
+	return w.fontSize * w.scale * pointSize * lineSpacing
+}
+
+// list writes the items with a bullet or their number in front of them
+func (w *pdfWriter) list(node *html.Node) {
+	indent := w.fontSize * pointSize * 2
+	w.endBlock()
+	w.setIndent(indent)
+
+	number := 0
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		if child.DataAtom != atom.Li {
+			w.render(child)
+			continue
+		}
+		number++
+		marker := "•"
+		if node.DataAtom == atom.Ol {
+			marker = fmt.Sprintf("%d.", number)
+		}
+		if !w.lineStart {
+			w.newline()
+		}
+		w.setFont()
+		w.pdf.SetX(w.margin + w.indent - indent*0.75)
+		w.pdf.Write(w.lineHeight(), marker)
+		w.pdf.SetX(max(w.pdf.GetX(), w.margin+w.indent))
+		// The content of the item continues on the line of the marker
+		w.lineStart, w.spaced, w.space = true, true, false
+		w.children(child)
+	}
+
+	w.endBlock()
+	w.setIndent(-indent)
+}
+
+// code writes a preformatted block on a gray background
+func (w *pdfWriter) code(text string) {
+	w.mono++
+	w.setFont()
+	w.pdf.SetFillColor(240, 240, 240)
+	text = strings.TrimRight(strings.ReplaceAll(text, "\t", "    "), "\n")
+	w.pdf.MultiCell(0, w.lineHeight(), text, "", "L", true)
+	w.mono--
+	w.lineStart, w.spaced, w.space = true, false, false
+}
+
+// image places the image centered on its own line, scaled down to fit onto the page
+func (w *pdfWriter) image(node *html.Node) {
+	imagePath := w.section.ResolveImage(attribute(node, "src"))
+	data, ok := w.document.Images[imagePath]
+	if !ok {
+		return
+	}
+	info := w.registerImage(imagePath, data)
+	if info == nil {
+		return
+	}
+	if !w.lineStart {
+		w.newline()
+	}
+
+	pageWidth, pageHeight := w.pdf.GetPageSize()
+	_, top, right, bottom := w.pdf.GetMargins()
+	maxWidth := pageWidth - right - w.margin - w.indent
+	maxHeight := pageHeight - top - bottom
+	scale := min(1, maxWidth/info.Width(), maxHeight/info.Height())
+	width, height := info.Width()*scale, info.Height()*scale
+	if w.pdf.GetY()+height > pageHeight-bottom {
+		w.pdf.AddPage()
+	}
+	y := w.pdf.GetY()
+	w.pdf.ImageOptions(imagePath, w.margin+w.indent+(maxWidth-width)/2, y, width, height, false, fpdf.ImageOptions{}, 0, "")
+	w.pdf.SetY(y + height)
+	w.lineStart, w.spaced, w.space = true, false, false
+}
+
+// registerImage adds the image to the PDF, formats PDF does not support are converted to JPEG
+func (w *pdfWriter) registerImage(name string, data []byte) *fpdf.ImageInfoType {
+	if info := w.pdf.GetImageInfo(name); info != nil {
+		return info
+	}
+
+	imageType := map[string]string{"image/jpeg": "JPG", "image/png": "PNG", "image/gif": "GIF"}[http.DetectContentType(data)]
+	if imageType != "" {
+		info := w.pdf.RegisterImageOptionsReader(name, fpdf.ImageOptions{ImageType: imageType}, bytes.NewReader(data))
+		if w.pdf.Ok() {
+			info.SetDpi(96)
+			return info
+		}
+		// e.g. interlaced PNG files are not supported
+		w.pdf.ClearError()
+	}
+
+	converted, err := encodeJPEG(data)
+	if err != nil {
+		log.Printf("Error converting image %s: %s", name, err.Error())
+		return nil
+	}
+	info := w.pdf.RegisterImageOptionsReader(name, fpdf.ImageOptions{ImageType: "JPG"}, bytes.NewReader(converted))
+	if !w.pdf.Ok() {
+		log.Printf("Error adding image %s: %s", name, w.pdf.Error().Error())
+		w.pdf.ClearError()
+		return nil
+	}
+	info.SetDpi(96)
+	return info
+}
+
+func textContent(node *html.Node) string {
+	if node.Type == html.TextNode {
+		return node.Data
+	}
+	if node.DataAtom == atom.Br {
+		return "\n"
+	}
+	var text strings.Builder
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		text.WriteString(textContent(child))
+	}
+	return text.String()
+}
+
+func attribute(node *html.Node, name string) string {
+	for _, attr := range node.Attr {
+		if attr.Key == name {
+			return attr.Val
+		}
+	}
+	return ""
+}
+
+func isExternalLink(href string) bool {
+	target, err := url.Parse(href)
+	return err == nil && (target.Scheme == "http" || target.Scheme == "https" || target.Scheme == "mailto")
+}
@@ -0,0 +1,181 @@
+package ebook
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"github.com/PuerkitoBio/goquery"
+	"io"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// Document is the content of an epub file
+type Document struct {
+	Title    string
+	Author   string
+	Sections []Section
+	// Images maps the path of an image inside the epub to its content
+	Images map[string][]byte
+	// Cover is the path of the cover image, empty if the epub has none
+	Cover string
+}
+
+// Section is a single xhtml file of the epub in reading order
+type Section struct {
+	Title string
+	// Path is the location of the xhtml file inside the epub
+	Path string
+	// Body is the inner HTML of the <body> element
+	Body string
+}
+
+type container struct {
+	Rootfiles []struct {
+		FullPath string `xml:"full-path,attr"`
+	} `xml:"rootfiles>rootfile"`
+}
+
+type packageDocument struct {
+	Title   string `xml:"metadata>title"`
+	Creator string `xml:"metadata>creator"`
+	Meta    []struct {
+		Name    string `xml:"name,attr"`
+		Content string `xml:"content,attr"`
+	} `xml:"metadata>meta"`
+	Manifest []struct {
+		ID         string `xml:"id,attr"`
+		Href       string `xml:"href,attr"`
+		MediaType  string `xml:"media-type,attr"`
+		Properties string `xml:"properties,attr"`
+	} `xml:"manifest>item"`
+	Spine []struct {
+		IDRef string `xml:"idref,attr"`
+	} `xml:"spine>itemref"`
+}
+
+// Read parses the epub at the given path
+func Read(epubPath string) (*Document, error) {
+	archive, err := zip.OpenReader(epubPath)
+	if err != nil {
+		return nil, err
+	}
+	defer archive.Close()
+
+	files := map[string]*zip.File{}
+	for _, file := range archive.File {
+		files[file.Name] = file
+	}
+
+	var containerFile container
+	if err := readXML(files, "META-INF/container.xml", &containerFile); err != nil {
+		return nil, err
+	}
+	if len(containerFile.Rootfiles) == 0 {
+		return nil, fmt.Errorf("the epub has no package document")
+	}
+	opfPath := containerFile.Rootfiles[0].FullPath
+	opfDir := path.Dir(opfPath)
+
+	var opf packageDocument
+	if err := readXML(files, opfPath, &opf); err != nil {
+		return nil, err
+	}
+
+	document := &Document{
+		Title:  strings.TrimSpace(opf.Title),
+		Author: strings.TrimSpace(opf.Creator),
+		Images: map[string][]byte{},
+	}
+
+	hrefs := map[string]string{}
+	for _, item := range opf.Manifest {
+		itemPath := resolve(opfDir, item.Href)
+		hrefs[item.ID] = itemPath
+		if strings.HasPrefix(item.MediaType, "image/") {
+			content, err := readFile(files, itemPath)
+			if err != nil {
+				return nil, err
+			}
+			document.Images[itemPath] = content
+			if strings.Contains(item.Properties, "cover-image") {
+				document.Cover = itemPath
+			}
+		}
+		// The navigation document is generated from the sections again
+		if strings.Contains(item.Properties, "nav") {
+			hrefs[item.ID] = ""
+		}
+	}
+
+	// EPUB 2 declares the cover in the metadata instead of the manifest properties
+	for _, meta := range opf.Meta {
+		if document.Cover == "" && meta.Name == "cover" {
+			if _, ok := document.Images[hrefs[meta.Content]]; ok {
+				document.Cover = hrefs[meta.Content]
+			}
+		}
+	}
+
+	for _, itemRef := range opf.Spine {
+		sectionPath := hrefs[itemRef.IDRef]
+		if sectionPath == "" {
+			continue
+		}
+		content, err := readFile(files, sectionPath)
+		if err != nil {
+			return nil, err
+		}
+		doc, err := goquery.NewDocumentFromReader(bytes.NewReader(content))
+		if err != nil {
+			return nil, err
+		}
+		body, err := doc.Find("body").Html()
+		if err != nil {
+			return nil, err
+		}
+		document.Sections = append(document.Sections, Section{
+			Title: strings.TrimSpace(doc.Find("title").Text()),
+			Path:  sectionPath,
+			Body:  body,
+		})
+	}
+
+	return document, nil
+}
+
+// ResolveImage returns the path inside the epub of an image referenced by a section
+func (s Section) ResolveImage(src string) string {
+	return resolve(path.Dir(s.Path), src)
+}
+
+func resolve(dir string, href string) string {
+	unescaped, err := url.PathUnescape(href)
+	if err == nil {
+		href = unescaped
+	}
+	return path.Clean(path.Join(dir, href))
+}
+
+func readFile(files map[string]*zip.File, name string) ([]byte, error) {
+	file, ok := files[name]
+	if !ok {
+		return nil, fmt.Errorf("%s is missing in the epub", name)
+	}
+	reader, err := file.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	return io.ReadAll(reader)
+}
+
+func readXML(files map[string]*zip.File, name string, target interface{}) error {
+	content, err := readFile(files, name)
+	if err != nil {
+		return err
+	}
+	return xml.Unmarshal(content, target)
+}
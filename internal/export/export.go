@@ -0,0 +1,154 @@
+package export
+
+import (
+	"errors"
+	"fmt"
+	"kindExport/generated/model"
+	"kindExport/internal/db"
+	"kindExport/internal/mailer"
+	"kindExport/internal/scrape"
+	"log"
+	"net/url"
+	"os"
+)
+
+// Result is the outcome of an export job
+type Result struct {
+	// Message is shown to the user once the job is finished
+	Message   string
+	ArticleID *int32
+	// Summary is set if the article was exported successfully
+	Summary *Summary
+}
+
+// permanentError marks failures that will not go away by trying again
+type permanentError struct {
+	error
+}
+
+func permanent(err error) error {
+	return permanentError{err}
+}
+
+// export fetches the article of the job and delivers it to the kindle mail address of the user
+func export(job model.ExportJobs, progress *reporter) (Result, error) {
+	var result Result
+	progress.stage(StageResolving)
+
+	user, err := db.GetUserByID(job.UserID)
+	if err != nil {
+		return result, err
+	}
+
+	if _, err := url.Parse(job.URL); err != nil {
+		return result, permanent(errors.New("invalid URL"))
+	}
+	urlValue := job.URL
+	// The browser of the user followed the redirects of supplied pages already
+	if job.PageHTML == nil {
+		urlValue, err = scrape.ResolveRedirects(job.URL)
+		if err != nil {
+			return result, fmt.Errorf("URL could not be resolved: %w", err)
+		}
+	}
+	urlValue = scrape.NormalizeURL(urlValue)
+
+	scrapeOptions, err := db.ScrapeOptions(user)
+	if err != nil {
+		log.Printf("Error querying sessions: %s", err.Error())
+	}
+	scrapeOptions.Progress = progress.scrapeProgress
+	if job.PageHTML != nil {
+		scrapeOptions.Page = &scrape.Page{URL: urlValue, HTML: *job.PageHTML}
+	}
+	scraper, err := scrape.ForURL(urlValue, scrapeOptions)
+	if err != nil {
+		return result, fmt.Errorf("the page could not be loaded: %w", err)
+	}
+
+	// Fetch the epub (if necessary), supplied pages are kept to the user who sent them
+	var article *Article
+	if job.PageHTML != nil {
+		article, err = FetchPage(scraper, urlValue, func() { progress.stage(StageScraping) })
+	} else {
+		article, err = Fetch(user, scraper, urlValue, func() { progress.stage(StageScraping) })
+	}
+	if errors.Is(err, scrape.ErrPaywall) {
+		return result, permanent(err)
+	}
+	if err != nil {
+		return result, fmt.Errorf("error fetching newsletter: %w", err)
+	}
+	result.ArticleID = article.ID
+	ebookPath := article.Path
+	summary := &Summary{Title: article.Title, Author: article.Author, Preview: article.Preview}
+	if info, err := os.Stat(ebookPath); err == nil {
+		summary.Size = info.Size()
+	}
+
+	// Keep track of the articles that were exported for the user
+	record := func(status string) {
+		if result.ArticleID == nil {
+			return
+		}
+		err := db.RecordUserArticle(*user.ID, *result.ArticleID, status, article.Preview)
+		if err != nil {
+			log.Printf("Error recording article for user: %s", err.Error())
+		}
+	}
+
+	// Paid articles are only handed out to users who can read them, unless they asked for previews
+	if article.Preview && (!user.ReceivePreviews || article.Path == "") {
+		record(db.StatusSkippedPaywall)
+		result.Message = "Article is behind a paywall. As no session with a subscription is provided, the article will not be sent to the Kindle mail address." +
+			" To access the article, please subscribe to the newsletter and provide the session cookie to the bot via the `/session` command," +
+			" or receive the preview with the `/previews` command"
+		return result, nil
+	}
+
+	// Send the epub to the user's kindle mail address
+	if user.KindleMail == nil || *user.KindleMail == "" {
+		record(db.StatusFetched)
+		result.Message = "Mail address is not configured." +
+			" Epub has been fetched, but cannot be sent to kindle mail."
+		summary.Destination = "Not sent, no mail address configured"
+		result.Summary = summary
+		return result, nil
+	}
+
+	// Users with a digest receive the article with the next digest instead
+	digestSettings, err := db.GetDigestSettings(*user.ID)
+	if err != nil {
+		log.Printf("Error querying digest settings: %s", err.Error())
+	}
+	if digestSettings != nil && result.ArticleID != nil {
+		record(db.StatusFetched)
+		result.Message = "Added the article to your next " + digestSettings.Frequency + " digest"
+		summary.Destination = "Next " + digestSettings.Frequency + " digest"
+		result.Summary = summary
+		return result, nil
+	}
+
+	ebookPath, err = Rendition(article.RenditionID(), ebookPath, user)
+	if err != nil {
+		return result, fmt.Errorf("error converting the epub to %s: %w", user.Format, err)
+	}
+	if info, err := os.Stat(ebookPath); err == nil {
+		summary.Size = info.Size()
+	}
+
+	progress.stage(StageSending)
+	err = mailer.SendMail(*user.KindleMail, ebookPath)
+	if err != nil {
+		record(db.StatusFailed)
+		return result, fmt.Errorf("error sending %s to kindle mail address: %w", user.Format, err)
+	}
+	record(db.StatusSent)
+	result.Message = "Sent " + user.Format + " to kindle mail address"
+	if article.Preview {
+		result.Message = "Sent the preview as " + user.Format + " to kindle mail address"
+	}
+	summary.Destination = *user.KindleMail
+	result.Summary = summary
+	return result, nil
+}
@@ -0,0 +1,152 @@
+package export
+
+import (
+	"fmt"
+	"kindExport/generated/model"
+	"kindExport/internal/db"
+	"kindExport/internal/scrape"
+	"log"
+)
+
+// Article is the stored epub of an article as seen by the user who requested it
+type Article struct {
+	// ID is nil if the article could not be stored
+	ID *int32
+	// Path is empty if there is no epub the user may receive
+	Path   string
+	Title  string
+	Author string
+	// Preview is set if the user can only receive the preview of a paid article
+	Preview bool
+}
+
+// RenditionID returns the id conversions of the epub are stored for, previews are converted every time
+func (a *Article) RenditionID() *int32 {
+	if a.Preview {
+		return nil
+	}
+	return a.ID
+}
+
+// Fetch returns the stored article of the URL and scrapes it if it was not stored yet.
+// Users without access to a paid article only get its preview, which is scraped with their
+// sessions if they receive previews. A stored preview is scraped again for users with a subscription.
+// scraping is called before the article is scraped, it may be nil.
+func Fetch(user *model.Users, scraper scrape.Scraper, urlValue string, scraping func()) (*Article, error) {
+	stored, err := db.GetArticleByURL(urlValue)
+	if err != nil {
+		return nil, err
+	}
+	if stored != nil {
+		ok, err := userAccessible(user, scraper, *stored)
+		if err != nil {
+			return nil, err
+		}
+		article := &Article{ID: stored.ID, Title: stored.Title, Author: stored.Author}
+		if ok && stored.Completeness == scrape.CompletenessFull {
+			article.Path = stored.LocalPath
+			return article, nil
+		}
+		if !ok {
+			article.Path, article.Preview = db.PreviewPath(*stored), true
+			if article.Path != "" || !user.ReceivePreviews {
+				return article, nil
+			}
+		}
+	}
+
+	if scraping != nil {
+		scraping()
+	}
+	book, err := scraper.Scrape(&urlValue)
+	if err != nil {
+		return nil, err
+	}
+	ok, err := accessible(scraper, urlValue, book.Paid)
+	if err != nil {
+		return nil, err
+	}
+	// The paywall check of the platform catches previews the content heuristics missed
+	if !ok {
+		book.Completeness = scrape.CompletenessPreview
+	}
+	preview := book.Completeness == scrape.CompletenessPreview
+	article := &Article{Path: *book.Path, Title: book.Book.Title(), Author: book.Book.Author(), Preview: preview}
+
+	switch {
+	case stored == nil:
+		var id int32
+		id, err = db.InsertBook(*book, *user.ID)
+		if err == nil {
+			article.ID = &id
+		} else if existing, lookupErr := db.GetArticleByURL(*book.Permalink); lookupErr == nil && existing != nil {
+			// The article may be stored with the permalink of the publication instead
+			article.ID, err = existing.ID, nil
+		}
+	case stored.Completeness == scrape.CompletenessPreview:
+		// The full article replaces the stored preview, which is kept for users without access
+		article.ID = stored.ID
+		err = db.UpdateArticleBook(*stored.ID, *book, *user.ID)
+		if err == nil && !preview {
+			err = db.SetArticlePreview(*stored.ID, stored.LocalPath)
+		}
+	default:
+		// The stored full article is kept for the users who can read it
+		article.ID = stored.ID
+		err = db.SetArticlePreview(*stored.ID, *book.Path)
+	}
+	if err != nil {
+		log.Printf("Error storing article: %s", err.Error())
+	}
+	return article, nil
+}
+
+// FetchPage builds the article from a page the user supplied. The content of the page cannot be verified,
+// so the book is only delivered to the user and never stored as the article of the URL other users get.
+// scraping is called before the article is scraped, it may be nil.
+func FetchPage(scraper scrape.Scraper, urlValue string, scraping func()) (*Article, error) {
+	if scraping != nil {
+		scraping()
+	}
+	book, err := scraper.Scrape(&urlValue)
+	if err != nil {
+		return nil, err
+	}
+	return &Article{
+		Path:    *book.Path,
+		Title:   book.Book.Title(),
+		Author:  book.Book.Author(),
+		Preview: book.Completeness == scrape.CompletenessPreview,
+	}, nil
+}
+
+// userAccessible checks whether the user may receive the full stored article. Users who scraped the full
+// article or received it completely before have access, everyone else is checked with their own sessions.
+func userAccessible(user *model.Users, scraper scrape.Scraper, article model.Articles) (bool, error) {
+	if !article.Paid {
+		return true, nil
+	}
+	if article.Completeness == scrape.CompletenessFull && article.ScrapedBy != nil && *article.ScrapedBy == *user.ID {
+		return true, nil
+	}
+	entry, err := db.GetHistoryEntry(*user.ID, *article.ID)
+	if err != nil {
+		return false, err
+	}
+	if entry != nil && entry.Status != db.StatusSkippedPaywall && !entry.Preview {
+		return true, nil
+	}
+	return accessible(scraper, article.URL, true)
+}
+
+// accessible checks whether the sessions of the scraper can read the article, free articles are always accessible
+func accessible(scraper scrape.Scraper, urlValue string, paid bool) (bool, error) {
+	if !paid {
+		return true, nil
+	}
+	accessible, err := scraper.CheckPaywallAccessible(urlValue)
+	if err != nil {
+		return false, fmt.Errorf("error checking paywall: %w", err)
+	}
+	return accessible, nil
+}
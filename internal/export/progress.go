@@ -0,0 +1,89 @@
+package export
+
+import (
+	"kindExport/generated/model"
+	"kindExport/internal/scrape"
+	"sync"
+	"time"
+)
+
+const (
+	StageResolving = "resolving"
+	StageScraping  = "scraping"
+	StageImages    = scrape.StageImages
+	StageBuilding  = scrape.StageBuilding
+	StageSending   = "sending"
+	StageDone      = "done"
+
+	// imageUpdateInterval limits the edits of the status message while images are downloaded
+	imageUpdateInterval = 2 * time.Second
+)
+
+// Stages lists the stages of an export in the order they are passed
+var Stages = []string{StageResolving, StageScraping, StageImages, StageBuilding, StageSending, StageDone}
+
+// Progress is the state of a job that is shown to the user
+type Progress struct {
+	Stage string
+	// ImagesDone and ImagesTotal count the images of the article while they are downloaded
+	ImagesDone  int
+	ImagesTotal int
+	// Message describes the outcome of the job or of a failed attempt
+	Message string
+	// Final is set for the last update of the job
+	Final bool
+	// Summary describes the exported article once the job succeeded
+	Summary *Summary
+}
+
+// Summary describes an exported article
+type Summary struct {
+	Title  string
+	Author string
+	// Size is the size of the epub in bytes
+	Size int64
+	// Destination describes where the article was delivered to
+	Destination string
+	// Preview is set if only the preview of a paywalled article was delivered
+	Preview bool
+}
+
+// Updater shows the progress of a job to the user
+type Updater func(job model.ExportJobs, progress Progress)
+
+// reporter forwards the progress of a single job and drops image updates that follow too quickly
+type reporter struct {
+	job        model.ExportJobs
+	update     Updater
+	mutex      sync.Mutex
+	current    Progress
+	lastUpdate time.Time
+}
+
+func (r *reporter) stage(stage string) {
+	r.report(Progress{Stage: stage})
+}
+
+// scrapeProgress receives the progress of the scraper
+func (r *reporter) scrapeProgress(stage string, done int, total int) {
+	r.report(Progress{Stage: stage, ImagesDone: done, ImagesTotal: total})
+}
+
+func (r *reporter) report(progress Progress) {
+	if r.update == nil {
+		return
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	sameStage := progress.Stage == r.current.Stage
+	// Remember the images for the stages after the download
+	if progress.ImagesTotal == 0 {
+		progress.ImagesDone, progress.ImagesTotal = r.current.ImagesDone, r.current.ImagesTotal
+	}
+	r.current = progress
+	if sameStage && !progress.Final && progress.ImagesDone < progress.ImagesTotal && time.Since(r.lastUpdate) < imageUpdateInterval {
+		return
+	}
+	r.lastUpdate = time.Now()
+	r.update(r.job, progress)
+}
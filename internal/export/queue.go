@@ -0,0 +1,152 @@
+package export
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"kindExport/generated/model"
+	"kindExport/internal/db"
+	"kindExport/internal/scrape"
+	"log"
+	"net/url"
+	"sync"
+	"time"
+)
+
+const (
+	// maxAttempts is the amount of tries before a job fails
+	maxAttempts = 5
+	// retryDelay is the wait after the first failed attempt, it doubles with every further attempt
+	retryDelay = 30 * time.Second
+	// pollInterval is the time between two checks for due jobs, new jobs wake up the workers immediately
+	pollInterval = 10 * time.Second
+)
+
+// wake signals the workers that a new job was queued
+var wake = make(chan struct{}, 1)
+
+// ErrInvalidURL is returned for URLs that cannot be exported
+var ErrInvalidURL = errors.New("invalid URL")
+
+// ValidateURL checks whether the URL can be queued for export.
+// URLs of hosts in the network of the bot are rejected, they must not be fetched for users.
+func ValidateURL(urlValue string) error {
+	parsed, err := url.Parse(urlValue)
+	if err != nil || parsed.Hostname() == "" || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+		return ErrInvalidURL
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := scrape.CheckHost(ctx, parsed.Hostname()); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidURL, err)
+	}
+	return nil
+}
+
+// Enqueue stores a new export job, the interaction is used to show the progress of the job.
+// Jobs without an interaction only notify the user once they are finished.
+func Enqueue(userID int32, url string, applicationID string, interactionToken string) (int32, error) {
+	id, err := db.InsertExportJob(userID, url, applicationID, interactionToken, "")
+	if err != nil {
+		return 0, err
+	}
+	wakeWorkers()
+	return id, nil
+}
+
+// EnqueuePage stores a new export job for a page the user supplied, the HTML is used instead of fetching the URL
+func EnqueuePage(userID int32, url string, pageHTML string, applicationID string, interactionToken string) (int32, error) {
+	id, err := db.InsertExportJob(userID, url, applicationID, interactionToken, pageHTML)
+	if err != nil {
+		return 0, err
+	}
+	wakeWorkers()
+	return id, nil
+}
+
+func wakeWorkers() {
+	select {
+	case wake <- struct{}{}:
+	default:
+	}
+}
+
+// Queue processes the stored export jobs with a fixed amount of workers
+type Queue struct {
+	workers int
+	update  Updater
+	// claim prevents two workers from picking up the same job
+	claim sync.Mutex
+}
+
+func NewQueue(workers int, update Updater) *Queue {
+	return &Queue{workers: workers, update: update}
+}
+
+// Run starts the workers, jobs which were running when the bot stopped are picked up again
+func (q *Queue) Run() {
+	requeued, err := db.RequeueRunningExportJobs()
+	if err != nil {
+		log.Printf("Error requeueing interrupted export jobs: %s", err.Error())
+	} else if requeued > 0 {
+		log.Printf("Requeued %d interrupted export jobs", requeued)
+	}
+	for i := 0; i < q.workers; i++ {
+		go q.work()
+	}
+}
+
+func (q *Queue) work() {
+	for {
+		q.claim.Lock()
+		job, err := db.ClaimExportJob()
+		q.claim.Unlock()
+		if err != nil {
+			log.Printf("Error claiming export job: %s", err.Error())
+		}
+		if job == nil {
+			select {
+			case <-wake:
+			case <-time.After(pollInterval):
+			}
+			continue
+		}
+		q.process(*job)
+	}
+}
+
+func (q *Queue) process(job model.ExportJobs) {
+	progress := &reporter{job: job, update: q.update}
+	result, err := export(job, progress)
+	if err == nil {
+		err = db.FinishExportJob(*job.ID, db.JobDone, result.Message, result.ArticleID)
+		if err != nil {
+			log.Printf("Error finishing export job %d: %s", *job.ID, err.Error())
+		}
+		progress.report(Progress{Stage: StageDone, Message: result.Message, Final: true, Summary: result.Summary})
+		return
+	}
+
+	log.Printf("Export job %d failed in attempt %d: %s", *job.ID, job.Attempts, err.Error())
+	var permanent permanentError
+	if errors.As(err, &permanent) || job.Attempts >= maxAttempts {
+		message := "Error exporting the article: " + err.Error()
+		err = db.FinishExportJob(*job.ID, db.JobFailed, message, result.ArticleID)
+		if err != nil {
+			log.Printf("Error finishing export job %d: %s", *job.ID, err.Error())
+		}
+		progress.report(Progress{Stage: progress.current.Stage, Message: message, Final: true})
+		return
+	}
+
+	delay := retryDelay << (job.Attempts - 1)
+	retryErr := db.RetryExportJob(*job.ID, time.Now().Add(delay), err.Error())
+	if retryErr != nil {
+		log.Printf("Error queueing export job %d again: %s", *job.ID, retryErr.Error())
+		return
+	}
+	progress.report(Progress{
+		Stage:   progress.current.Stage,
+		Message: fmt.Sprintf("%s\nTrying again in %s (attempt %d of %d)", err.Error(), delay, job.Attempts+1, maxAttempts),
+	})
+}
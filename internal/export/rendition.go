@@ -0,0 +1,57 @@
+package export
+
+import (
+	"kindExport/generated/model"
+	"kindExport/internal/db"
+	"kindExport/internal/ebook"
+	"log"
+	"os"
+)
+
+// Rendition returns the path of the article in the format of the user and converts the epub if necessary.
+// Conversions of stored articles are kept, so they are only converted once per format and layout.
+func Rendition(articleID *int32, epubPath string, user *model.Users) (string, error) {
+	if user.Format == "" || user.Format == ebook.FormatEPUB {
+		return epubPath, nil
+	}
+
+	options := db.ConvertOptions(user)
+	variant := ebook.Variant(user.Format, options)
+	if articleID != nil {
+		rendition, err := db.GetRendition(*articleID, variant)
+		if err != nil {
+			log.Printf("Error querying rendition: %s", err.Error())
+		}
+		if rendition != nil {
+			if _, err := os.Stat(rendition.LocalPath); err == nil {
+				return rendition.LocalPath, nil
+			}
+		}
+
+		// Archives describe the article in their front matter
+		article, err := db.GetArticle(*articleID)
+		if err != nil {
+			log.Printf("Error querying article: %s", err.Error())
+		}
+		if article != nil {
+			options.Metadata = ebook.Metadata{
+				Title:       article.Title,
+				Author:      article.Author,
+				URL:         article.URL,
+				ReleaseDate: article.ReleaseDate,
+				Paid:        article.Paid,
+			}
+		}
+	}
+
+	path, err := ebook.Convert(epubPath, user.Format, options)
+	if err != nil {
+		return "", err
+	}
+	if articleID != nil {
+		if err := db.SetRendition(*articleID, variant, path); err != nil {
+			log.Printf("Error storing rendition: %s", err.Error())
+		}
+	}
+	return path, nil
+}
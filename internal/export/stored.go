@@ -0,0 +1,86 @@
+package export
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"kindExport/generated/model"
+	"kindExport/internal/db"
+	"kindExport/internal/scrape"
+	"log"
+	"os"
+)
+
+// ErrWithheld is returned for articles that were not delivered to the user because of a paywall
+var ErrWithheld = errors.New("the article is behind a paywall your sessions cannot read")
+
+// HistoryEpub returns the epub of an article from the history of the user.
+// Users who only received the preview of an article get the preview.
+func HistoryEpub(user *model.Users, entry db.HistoryEntry) (string, error) {
+	if !entry.Accessible() {
+		return "", ErrWithheld
+	}
+	if !entry.Preview {
+		return StoredEpub(user, entry.Article)
+	}
+	// Previews are not scraped again, the user would be sent the preview with the next export anyway
+	previewPath := entry.Path()
+	if previewPath == "" {
+		return "", errors.New("the preview is no longer available")
+	}
+	if _, err := os.Stat(previewPath); err != nil {
+		return "", err
+	}
+	return previewPath, nil
+}
+
+// StoredEpub returns the path of the stored epub of the full article.
+// If the file is missing on disk, the article is scraped again with the sessions of the user. Sessions that
+// only read the preview do not replace the full article for other users, the preview is stored next to it
+// and ErrWithheld is returned.
+func StoredEpub(user *model.Users, article model.Articles) (string, error) {
+	_, err := os.Stat(article.LocalPath)
+	if err == nil {
+		return article.LocalPath, nil
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		return "", err
+	}
+
+	scrapeOptions, err := db.ScrapeOptions(user)
+	if err != nil {
+		log.Printf("Error querying sessions: %s", err.Error())
+	}
+	scraper, err := scrape.ForURL(article.URL, scrapeOptions)
+	if err != nil {
+		return "", err
+	}
+	book, err := scraper.Scrape(&article.URL)
+	if err != nil {
+		return "", err
+	}
+	ok, err := accessible(scraper, article.URL, book.Paid)
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		book.Completeness = scrape.CompletenessPreview
+	}
+
+	if book.Completeness == scrape.CompletenessPreview {
+		if article.Completeness == scrape.CompletenessFull {
+			err = db.SetArticlePreview(*article.ID, *book.Path)
+		} else {
+			err = db.UpdateArticleBook(*article.ID, *book, *user.ID)
+		}
+		if err != nil {
+			return "", fmt.Errorf("error storing the preview: %w", err)
+		}
+		return "", ErrWithheld
+	}
+	err = db.UpdateArticleBook(*article.ID, *book, *user.ID)
+	if err != nil {
+		return "", fmt.Errorf("error updating article: %w", err)
+	}
+	return *book.Path, nil
+}
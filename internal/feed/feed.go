@@ -0,0 +1,201 @@
+package feed
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"github.com/PuerkitoBio/goquery"
+	"io"
+	"kindExport/internal/scrape"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Feed is the common representation of RSS and Atom feeds
+type Feed struct {
+	Title string
+	Items []Item
+}
+
+// Item is a single post of a feed
+type Item struct {
+	Title     string
+	URL       string
+	Published time.Time
+}
+
+type rssFeed struct {
+	Channel struct {
+		Title string `xml:"title"`
+		Items []struct {
+			Title   string `xml:"title"`
+			Link    string `xml:"link"`
+			GUID    string `xml:"guid"`
+			PubDate string `xml:"pubDate"`
+		} `xml:"item"`
+	} `xml:"channel"`
+}
+
+type atomFeed struct {
+	Title   string `xml:"title"`
+	Entries []struct {
+		Title string `xml:"title"`
+		Links []struct {
+			Href string `xml:"href,attr"`
+			Rel  string `xml:"rel,attr"`
+		} `xml:"link"`
+		Published string `xml:"published"`
+		Updated   string `xml:"updated"`
+	} `xml:"entry"`
+}
+
+var dateLayouts = []string{
+	time.RFC1123Z,
+	time.RFC1123,
+	"Mon, 2 Jan 2006 15:04:05 -0700",
+	"Mon, 2 Jan 2006 15:04:05 MST",
+	time.RFC822Z,
+	time.RFC822,
+	time.RFC3339,
+}
+
+func parseDate(value string) time.Time {
+	value = strings.TrimSpace(value)
+	for _, layout := range dateLayouts {
+		parsed, err := time.Parse(layout, value)
+		if err == nil {
+			return parsed
+		}
+	}
+	return time.Time{}
+}
+
+// Parse reads an RSS or Atom feed
+func Parse(data []byte) (*Feed, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	// Feeds in the wild are often not strictly valid XML
+	decoder.Strict = false
+	decoder.Entity = xml.HTMLEntity
+	decoder.CharsetReader = func(charset string, input io.Reader) (io.Reader, error) {
+		return input, nil
+	}
+
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, fmt.Errorf("not a RSS or Atom feed")
+		}
+		start, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		switch start.Name.Local {
+		case "rss":
+			var rss rssFeed
+			if err := decoder.DecodeElement(&rss, &start); err != nil {
+				return nil, err
+			}
+			feed := &Feed{Title: strings.TrimSpace(rss.Channel.Title)}
+			for _, item := range rss.Channel.Items {
+				link := strings.TrimSpace(item.Link)
+				if link == "" && strings.HasPrefix(item.GUID, "http") {
+					link = strings.TrimSpace(item.GUID)
+				}
+				feed.Items = append(feed.Items, Item{
+					Title:     strings.TrimSpace(item.Title),
+					URL:       link,
+					Published: parseDate(item.PubDate),
+				})
+			}
+			return feed, nil
+		case "feed":
+			var atom atomFeed
+			if err := decoder.DecodeElement(&atom, &start); err != nil {
+				return nil, err
+			}
+			feed := &Feed{Title: strings.TrimSpace(atom.Title)}
+			for _, entry := range atom.Entries {
+				item := Item{
+					Title:     strings.TrimSpace(entry.Title),
+					Published: parseDate(entry.Published),
+				}
+				if item.Published.IsZero() {
+					item.Published = parseDate(entry.Updated)
+				}
+				for _, link := range entry.Links {
+					if link.Rel == "" || link.Rel == "alternate" {
+						item.URL = strings.TrimSpace(link.Href)
+						break
+					}
+				}
+				feed.Items = append(feed.Items, item)
+			}
+			return feed, nil
+		default:
+			return nil, fmt.Errorf("not a RSS or Atom feed")
+		}
+	}
+}
+
+func get(targetUrl string) ([]byte, *url.URL, error) {
+	resp, err := scrape.Client.Get(targetUrl)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, nil, fmt.Errorf("fetching %s failed with status %s", targetUrl, resp.Status)
+	}
+	body, err := io.ReadAll(resp.Body)
+	return body, resp.Request.URL, err
+}
+
+// Fetch downloads and parses the feed
+func Fetch(feedURL string) (*Feed, error) {
+	body, _, err := get(feedURL)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(body)
+}
+
+// Discover returns the feed of the given URL. The URL may point to a feed itself,
+// a Substack publication or any website that announces its feed in a <link> element.
+func Discover(targetUrl string) (string, error) {
+	u, err := url.Parse(targetUrl)
+	if err != nil {
+		return "", err
+	}
+	host := strings.ToLower(u.Hostname())
+	if strings.HasSuffix(host, ".substack.com") {
+		// All Substack publications expose their posts at /feed
+		return "https://" + host + "/feed", nil
+	}
+
+	body, finalURL, err := get(targetUrl)
+	if err != nil {
+		return "", err
+	}
+	if _, err := Parse(body); err == nil {
+		return finalURL.String(), nil
+	}
+
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	href, ok := doc.Find("link[rel=\"alternate\"][type=\"application/rss+xml\"], link[rel=\"alternate\"][type=\"application/atom+xml\"]").First().Attr("href")
+	if ok {
+		feedURL, err := finalURL.Parse(href)
+		if err == nil {
+			return feedURL.String(), nil
+		}
+	}
+	if doc.Find("link[href*=\"substackcdn.com\"], script[src*=\"substackcdn.com\"]").Length() > 0 {
+		return finalURL.Scheme + "://" + finalURL.Host + "/feed", nil
+	}
+
+	return "", fmt.Errorf("no feed found for %s", targetUrl)
+}
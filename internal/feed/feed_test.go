@@ -0,0 +1,101 @@
+package feed
+
+import (
+	"testing"
+	"time"
+)
+
+const substackRSS = `<?xml version="1.0" encoding="UTF-8"?><rss xmlns:dc="http://purl.org/dc/elements/1.1/" xmlns:content="http://purl.org/rss/1.0/modules/content/" xmlns:atom="http://www.w3.org/2005/Atom" version="2.0">
+<channel><title><![CDATA[The Weekly Letter]]></title><link>https://janedoe.substack.com</link>
+<item><title><![CDATA[Local news, one year later]]></title><link>https://janedoe.substack.com/p/local-news-one-year-later</link>
+<guid isPermaLink="false">https://janedoe.substack.com/p/local-news-one-year-later</guid><dc:creator><![CDATA[Jane Doe]]></dc:creator>
+<pubDate>Tue, 06 Oct 2026 13:02:11 GMT</pubDate></item>
+<item><title>Q&amp;A with readers &ndash; part 2</title><guid>https://janedoe.substack.com/p/qa-part-2</guid>
+<pubDate>Mon, 5 Oct 2026 08:00:00 +0200</pubDate></item>
+<item><title>Podcast episode</title><guid isPermaLink="false">episode-42</guid><pubDate>Sun, 04 Oct 2026 08:00:00 +0000</pubDate></item>
+</channel></rss>`
+
+const ghostAtom = `<?xml version="1.0" encoding="utf-8"?><feed xmlns="http://www.w3.org/2005/Atom">
+<title type="text">Field Notes</title><link href="https://fieldnotes.ghost.io/" rel="alternate"/>
+<entry><title>First snow</title><link rel="replies" href="https://fieldnotes.ghost.io/first-snow/#comments"/>
+<link href="https://fieldnotes.ghost.io/first-snow/" rel="alternate"/><published>2026-10-01T07:30:00+02:00</published><updated>2026-10-02T09:00:00Z</updated></entry>
+<entry><title> Closed pass </title><link href="https://fieldnotes.ghost.io/closed-pass/"/><updated>2026-10-08T18:00:00Z</updated></entry>
+</feed>`
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name  string
+		data  string
+		title string
+		items []Item
+	}{
+		{
+			name:  "substack rss",
+			data:  substackRSS,
+			title: "The Weekly Letter",
+			items: []Item{
+				{Title: "Local news, one year later", URL: "https://janedoe.substack.com/p/local-news-one-year-later", Published: time.Date(2026, 10, 6, 13, 2, 11, 0, time.UTC)},
+				{Title: "Q&A with readers – part 2", URL: "https://janedoe.substack.com/p/qa-part-2", Published: time.Date(2026, 10, 5, 6, 0, 0, 0, time.UTC)},
+				{Title: "Podcast episode", Published: time.Date(2026, 10, 4, 8, 0, 0, 0, time.UTC)},
+			},
+		},
+		{
+			name:  "ghost atom",
+			data:  ghostAtom,
+			title: "Field Notes",
+			items: []Item{
+				{Title: "First snow", URL: "https://fieldnotes.ghost.io/first-snow/", Published: time.Date(2026, 10, 1, 5, 30, 0, 0, time.UTC)},
+				{Title: "Closed pass", URL: "https://fieldnotes.ghost.io/closed-pass/", Published: time.Date(2026, 10, 8, 18, 0, 0, 0, time.UTC)},
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			feed, err := Parse([]byte(test.data))
+			if err != nil {
+				t.Fatalf("Parse failed: %s", err)
+			}
+			if feed.Title != test.title {
+				t.Errorf("title = %q, want %q", feed.Title, test.title)
+			}
+			if len(feed.Items) != len(test.items) {
+				t.Fatalf("got %d items, want %d", len(feed.Items), len(test.items))
+			}
+			for i, want := range test.items {
+				got := feed.Items[i]
+				if got.Title != want.Title || got.URL != want.URL || !got.Published.Equal(want.Published) {
+					t.Errorf("item %d = %+v, want %+v", i, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestParseRejectsOtherDocuments(t *testing.T) {
+	for _, data := range []string{
+		`<!DOCTYPE html><html><head><title>Blog</title></head><body></body></html>`,
+		`{"items": []}`,
+		``,
+	} {
+		if _, err := Parse([]byte(data)); err == nil {
+			t.Errorf("Parse(%q) succeeded, want an error", data)
+		}
+	}
+}
+
+func TestParseDate(t *testing.T) {
+	want := time.Date(2026, 10, 6, 13, 2, 11, 0, time.UTC)
+	for _, value := range []string{
+		"Tue, 06 Oct 2026 13:02:11 +0000",
+		"Tue, 06 Oct 2026 13:02:11 GMT",
+		"Tue, 6 Oct 2026 15:02:11 +0200",
+		" 2026-10-06T13:02:11Z ",
+	} {
+		if got := parseDate(value); !got.Equal(want) {
+			t.Errorf("parseDate(%q) = %s, want %s", value, got, want)
+		}
+	}
+	if got := parseDate("yesterday"); !got.IsZero() {
+		t.Errorf("parseDate of an unknown format = %s, want the zero time", got)
+	}
+}
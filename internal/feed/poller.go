@@ -0,0 +1,146 @@
+package feed
+
+import (
+	"fmt"
+	"kindExport/generated/model"
+	"kindExport/internal/db"
+	"kindExport/internal/export"
+	"kindExport/internal/scrape"
+	"log"
+	"sort"
+	"time"
+)
+
+// Notifier sends a direct message to a discord user
+type Notifier func(discordID string, message string) error
+
+// Poller periodically checks the subscribed feeds and delivers new posts to the subscribers
+type Poller struct {
+	interval time.Duration
+	notify   Notifier
+}
+
+func NewPoller(interval time.Duration, notify Notifier) *Poller {
+	return &Poller{
+		interval: interval,
+		notify:   notify,
+	}
+}
+
+// Run checks all subscriptions in the configured interval, it never returns
+func (p *Poller) Run() {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+	for {
+		p.poll()
+		<-ticker.C
+	}
+}
+
+func (p *Poller) poll() {
+	subscriptions, err := db.GetSubscriptions(nil)
+	if err != nil {
+		log.Printf("Error querying subscriptions: %s", err.Error())
+		return
+	}
+	for _, subscription := range subscriptions {
+		p.check(subscription)
+	}
+}
+
+func (p *Poller) check(subscription model.Subscriptions) {
+	feed, err := Fetch(subscription.FeedURL)
+	if err != nil {
+		log.Printf("Error fetching feed %s: %s", subscription.FeedURL, err.Error())
+		return
+	}
+
+	if !subscription.ItemsRecorded {
+		err = db.RecordSubscriptionItems(*subscription.ID, undatedURLs(feed))
+		if err != nil {
+			log.Printf("Error recording posts of subscription %d: %s", *subscription.ID, err.Error())
+			return
+		}
+	}
+	delivered, err := db.GetSubscriptionItems(*subscription.ID)
+	if err != nil {
+		log.Printf("Error querying delivered posts of subscription %d: %s", *subscription.ID, err.Error())
+		return
+	}
+	items := newItems(feed, subscription.LastPublishedAt, delivered)
+
+	user, err := db.GetUserByID(subscription.UserID)
+	if err != nil {
+		log.Printf("Error querying user of subscription %d: %s", *subscription.ID, err.Error())
+		return
+	}
+
+	lastPublished := subscription.LastPublishedAt
+	for _, item := range items {
+		// Posts are exported by the queue like every other export, it retries failed posts
+		// and notifies the user once they were delivered or failed for good
+		if err = export.ValidateURL(item.URL); err != nil {
+			// The post can never be exported, so it is skipped
+			log.Printf("Error queueing %s: %s", item.URL, err.Error())
+			p.sendNotification(user, fmt.Sprintf("New post \"%s\" of %s could not be delivered: %s", item.Title, feed.Title, err.Error()))
+		} else if _, err = export.Enqueue(*user.ID, item.URL, "", ""); err != nil {
+			// This and the following posts are queued with the next check
+			log.Printf("Error queueing %s: %s", item.URL, err.Error())
+			break
+		}
+		// Posts without publication date are remembered by their URL instead
+		if item.Published.IsZero() {
+			err = db.AddSubscriptionItems(*subscription.ID, []string{item.URL})
+			if err != nil {
+				log.Printf("Error recording delivered post %s: %s", item.URL, err.Error())
+			}
+			continue
+		}
+		lastPublished = item.Published
+	}
+
+	err = db.UpdateSubscriptionProgress(*subscription.ID, lastPublished)
+	if err != nil {
+		log.Printf("Error updating subscription %d: %s", *subscription.ID, err.Error())
+	}
+}
+
+// newItems returns the posts published after the given time in the order they were published.
+// Posts without publication date are returned first, unless they were delivered already.
+// Posts listed several times, for example with and without tracking parameters, are only returned once.
+func newItems(feed *Feed, since time.Time, delivered map[string]bool) []Item {
+	var items []Item
+	seen := map[string]bool{}
+	for _, item := range feed.Items {
+		if item.URL == "" {
+			continue
+		}
+		item.URL = scrape.NormalizeURL(item.URL)
+		if item.Published.IsZero() {
+			if delivered[item.URL] {
+				continue
+			}
+		} else if !item.Published.After(since) {
+			continue
+		}
+		if seen[item.URL] {
+			continue
+		}
+		seen[item.URL] = true
+		items = append(items, item)
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].Published.Before(items[j].Published)
+	})
+	return items
+}
+
+func (p *Poller) sendNotification(user *model.Users, message string) {
+	if p.notify == nil {
+		return
+	}
+	err := p.notify(user.DiscordID, message)
+	if err != nil {
+		log.Printf("Error notifying user %s: %s", user.DiscordID, err.Error())
+	}
+}
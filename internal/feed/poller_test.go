@@ -0,0 +1,76 @@
+package feed
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestNewItems(t *testing.T) {
+	since := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	day := func(d int) time.Time {
+		return since.AddDate(0, 0, d)
+	}
+	tests := []struct {
+		name      string
+		items     []Item
+		delivered map[string]bool
+		want      []string
+	}{
+		{
+			name: "only posts published after the last check",
+			items: []Item{
+				{URL: "https://example.com/p/old", Published: day(-1)},
+				{URL: "https://example.com/p/same-time", Published: since},
+				{URL: "https://example.com/p/new", Published: day(1)},
+			},
+			want: []string{"https://example.com/p/new"},
+		},
+		{
+			name: "oldest post first",
+			items: []Item{
+				{URL: "https://example.com/p/third", Published: day(3)},
+				{URL: "https://example.com/p/first", Published: day(1)},
+				{URL: "https://example.com/p/second", Published: day(2)},
+			},
+			want: []string{"https://example.com/p/first", "https://example.com/p/second", "https://example.com/p/third"},
+		},
+		{
+			name: "posts without link",
+			items: []Item{
+				{Title: "Podcast episode", Published: day(1)},
+			},
+			want: nil,
+		},
+		{
+			name: "normalized and deduplicated",
+			items: []Item{
+				{URL: "http://example.com/p/post/?utm_source=rss", Published: day(1)},
+				{URL: "https://example.com/p/post#comments", Published: day(2)},
+				{URL: "https://example.com/p/other", Published: day(2)},
+			},
+			want: []string{"https://example.com/p/post", "https://example.com/p/other"},
+		},
+		{
+			name: "posts without publication date first, unless delivered",
+			items: []Item{
+				{URL: "https://example.com/p/dated", Published: day(1)},
+				{URL: "https://example.com/p/undated"},
+				{URL: "https://example.com/p/delivered?utm_source=rss"},
+			},
+			delivered: map[string]bool{"https://example.com/p/delivered": true},
+			want:      []string{"https://example.com/p/undated", "https://example.com/p/dated"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var urls []string
+			for _, item := range newItems(&Feed{Items: test.items}, since, test.delivered) {
+				urls = append(urls, item.URL)
+			}
+			if !reflect.DeepEqual(urls, test.want) {
+				t.Errorf("newItems() = %v, want %v", urls, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,49 @@
+package feed
+
+import (
+	"errors"
+	"kindExport/internal/db"
+	"kindExport/internal/scrape"
+	"time"
+)
+
+// ErrAlreadySubscribed is returned if the user is subscribed to the feed already
+var ErrAlreadySubscribed = errors.New("already subscribed to the feed")
+
+// Subscribe subscribes the user to the fetched feed and returns the title of the subscription.
+// Only posts published after subscribing are delivered.
+func Subscribe(userID int32, feedURL string, feed *Feed) (string, error) {
+	subscriptions, err := db.GetSubscriptions(&userID)
+	if err != nil {
+		return "", err
+	}
+	for _, subscription := range subscriptions {
+		if subscription.FeedURL == feedURL {
+			return subscription.Title, ErrAlreadySubscribed
+		}
+	}
+
+	lastPublished := time.Now()
+	for _, item := range feed.Items {
+		if item.Published.After(lastPublished) {
+			lastPublished = item.Published
+		}
+	}
+
+	title := feed.Title
+	if title == "" {
+		title = feedURL
+	}
+	return title, db.AddSubscription(userID, feedURL, title, lastPublished, undatedURLs(feed))
+}
+
+// undatedURLs returns the normalized URLs of the posts without publication date
+func undatedURLs(feed *Feed) []string {
+	var urls []string
+	for _, item := range feed.Items {
+		if item.Published.IsZero() && item.URL != "" {
+			urls = append(urls, scrape.NormalizeURL(item.URL))
+		}
+	}
+	return urls
+}
@@ -1,4 +1,4 @@
-package discord
+package mailer
 
 import (
 	"crypto/tls"
@@ -44,7 +44,7 @@ func CheckMailConfig() error {
 	return nil
 }
 
-func sendMail(address string, epubPath string) error {
+func SendMail(address string, ebookPath string) error {
 	conf, _ := config.GetConfig()
 
 	message := mail.NewMsg()
@@ -52,7 +52,7 @@ func sendMail(address string, epubPath string) error {
 	message.To(address)
 	message.Subject("Your newsletter article is ready")
 	message.SetBodyString(mail.TypeTextPlain, "See attached for the newsletter article")
-	message.AttachFile(epubPath)
+	message.AttachFile(ebookPath)
 	client, err := mail.NewClient(conf.MailServer, mail.WithSMTPAuth(mail.SMTPAuthPlain),
 		mail.WithUsername(conf.MailUser), mail.WithPassword(conf.MailPassword), mail.WithPort(conf.MailPort))
 	if err != nil {
@@ -0,0 +1,74 @@
+package scrape
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+)
+
+// ErrPrivateAddress is returned for hosts that resolve to the network of the bot itself
+var ErrPrivateAddress = errors.New("the address points to a private network")
+
+// sharedAddressSpace is the carrier-grade NAT range, which is not covered by net.IP.IsPrivate
+var sharedAddressSpace = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// Transport is used for every request to a host named by the users of the bot. It only connects to
+// public addresses, checking the address when dialing also covers redirects and changed DNS records.
+var Transport http.RoundTripper = newGuardedTransport()
+
+// Client sends requests through Transport
+var Client = &http.Client{Transport: Transport}
+
+func newGuardedTransport() *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control:   checkDialAddress,
+	}
+	transport.DialContext = dialer.DialContext
+	return transport
+}
+
+// IsPublicIP reports whether the address can be reached from the internet
+func IsPublicIP(ip net.IP) bool {
+	return !(ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified() || sharedAddressSpace.Contains(ip))
+}
+
+// CheckHost resolves the host and fails if one of its addresses is not public.
+// Hosts that cannot be resolved are left to fail when the page is fetched.
+func CheckHost(ctx context.Context, host string) error {
+	if ip := net.ParseIP(host); ip != nil {
+		if !IsPublicIP(ip) {
+			return ErrPrivateAddress
+		}
+		return nil
+	}
+	addresses, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil
+	}
+	for _, address := range addresses {
+		if !IsPublicIP(address.IP) {
+			return ErrPrivateAddress
+		}
+	}
+	return nil
+}
+
+// checkDialAddress refuses connections to addresses that are not public
+func checkDialAddress(network string, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || !IsPublicIP(ip) {
+		return ErrPrivateAddress
+	}
+	return nil
+}
@@ -0,0 +1,25 @@
+package scrape
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientRefusesPrivateAddresses(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	_, err := Client.Get(server.URL)
+	if !errors.Is(err, ErrPrivateAddress) {
+		t.Errorf("Client.Get(%s) = %v, want %v", server.URL, err, ErrPrivateAddress)
+	}
+
+	// Other clients of the process are not restricted
+	resp, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatalf("http.Get(%s) failed: %s", server.URL, err)
+	}
+	resp.Body.Close()
+}
@@ -0,0 +1,89 @@
+package scrape
+
+import (
+	"fmt"
+	"github.com/PuerkitoBio/goquery"
+	"net/http"
+	"strings"
+)
+
+// BeehiivScraper exports posts of newsletters hosted on beehiiv
+type BeehiivScraper struct {
+	// BeehiivSessionCookie is the Cookie header of a logged in subscriber of the publication
+	BeehiivSessionCookie *string
+	Progress             ProgressFunc
+	Page                 *Page
+}
+
+// Texts beehiiv shows in place of the rest of a premium post
+var beehiivPaywallMarkers = []string{
+	"This post is for paid subscribers",
+	"This post is for paying subscribers",
+	"to read the rest.",
+	"Upgrade to read the rest",
+}
+
+func init() {
+	Register(Registration{
+		Name: PlatformBeehiiv,
+		MatchHost: func(host string) bool {
+			return strings.HasSuffix(host, ".beehiiv.com")
+		},
+		MatchPage: func(doc *goquery.Document) bool {
+			return doc.Find("link[href*=\"beehiiv\"], script[src*=\"beehiiv\"], img[src*=\"media.beehiiv.com\"]").Length() > 0
+		},
+		New: func(host string, opts Options) Scraper {
+			// Every publication has its own login, so sessions are stored per host
+			return BeehiivScraper{BeehiivSessionCookie: opts.session(PlatformBeehiiv, host), Progress: opts.Progress, Page: opts.Page}
+		},
+	})
+}
+
+func (b BeehiivScraper) cookies() []*http.Cookie {
+	return ParseCookies(b.BeehiivSessionCookie, "")
+}
+
+// isBeehiivPaywalled checks whether the post ends with the upgrade prompt for premium subscriptions
+func isBeehiivPaywalled(doc *goquery.Document) bool {
+	// The prompt follows the content blocks, markers quoted in the post itself are ignored
+	page := doc.Find("body").Clone()
+	page.Find("#content-blocks").Remove()
+	text := page.Text()
+	for _, marker := range beehiivPaywallMarkers {
+		if strings.Contains(text, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+func (b BeehiivScraper) CheckPaywallAccessible(targetUrl string) (bool, error) {
+	doc, err := fetchDocument(targetUrl, b.Page, b.cookies()...)
+	if err != nil {
+		return false, err
+	}
+	return !isBeehiivPaywalled(doc), nil
+}
+
+func (b BeehiivScraper) Scrape(url *string) (*Book, error) {
+	doc, err := fetchDocument(*url, b.Page, b.cookies()...)
+	if err != nil {
+		return nil, err
+	}
+	meta := extractMetadata(doc)
+
+	meta.Paywalled = isBeehiivPaywalled(doc)
+
+	content := doc.Find("#content-blocks").First()
+	if content.Length() == 0 {
+		if meta.Paywalled {
+			return nil, ErrPaywall
+		}
+		return nil, fmt.Errorf("failed to parse the beehiiv newsletter correctly")
+	}
+
+	// Subscribe forms and share buttons embedded between the content blocks
+	content.Find("form, .subscribe-widget, [class*=\"recommend\"]").Remove()
+
+	return buildBook(*url, meta, doc, content, b.Progress, b.Page != nil)
+}
@@ -0,0 +1,145 @@
+package scrape
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"github.com/go-shiori/go-epub"
+	"github.com/google/uuid"
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+	"google.golang.org/appengine/log"
+	"kindExport/internal/config"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode"
+)
+
+type Book struct {
+	Book        *epub.Epub
+	Path        *string
+	Permalink   *string
+	Paid        bool
+	ReleaseDate time.Time
+	// Completeness tells whether the book contains the whole article or only its preview
+	Completeness string
+}
+
+func generateUUID(filename string) string {
+	imgUUID := uuid.New().String()
+	// Get file ending from src
+	if strings.Contains(filename, ".") {
+		splitted := strings.Split(filename, ".")
+		ending := splitted[len(splitted)-1]
+		imgUUID = fmt.Sprintf("%s.%s", imgUUID, ending)
+	} else {
+		imgUUID = fmt.Sprintf("%s.jpg", imgUUID)
+	}
+	return imgUUID
+}
+
+func normalizeStr(str string) string {
+	// We want to have a lowercase string with space replaced by - and all special characters removed
+	t := transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
+	str, _, _ = transform.String(t, str)
+	str = strings.ToLower(str)
+	str = strings.ReplaceAll(str, " ", "-")
+	str = strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' {
+			return r
+		}
+		if r >= '0' && r <= '9' {
+			return r
+		}
+		if r == '-' || r == '_' {
+			return r
+		}
+		return -1
+	}, str)
+	return str
+}
+
+// addArticleSection adds the content as section of the book, prefixed by a title block.
+// Previews start with a banner page telling that the rest of the article is missing.
+func addArticleSection(book *epub.Epub, content string, releaseDate time.Time, completeness string) error {
+	if completeness == CompletenessPreview {
+		if err := addPreviewBanner(book); err != nil {
+			return err
+		}
+	}
+
+	// We want to format the releasedate for 25th February 2025 to "Feb 25, 2025"
+	content = fmt.Sprintf("<h1>%s</h1><p>By %s<em><br/>Published at %s</em></p><hr/>%s", book.Title(), book.Author(), releaseDate.Format("Jan 02, 2006"), content)
+
+	_, err := book.AddSection(content, book.Title(), fmt.Sprintf("%s.xhtml", normalizeStr(book.Title())), "")
+	return err
+}
+
+// maxFileNameLength limits the part of the file name that is taken from the title
+const maxFileNameLength = 80
+
+// bookFileName returns the file name of the epub without extension. The title is only used in its
+// normalized form, the hash of the permalink keeps articles with the same title apart.
+// Books of supplied pages get a file of their own, so they never replace the fetched article.
+func bookFileName(title string, permalink string, completeness string, supplied bool) string {
+	name := strings.Trim(normalizeStr(title), "-_")
+	if len(name) > maxFileNameLength {
+		name = strings.TrimRight(name[:maxFileNameLength], "-_")
+	}
+	if name == "" {
+		name = "article"
+	}
+	hash := sha256.Sum256([]byte(permalink))
+	name = fmt.Sprintf("%s-%s", name, hex.EncodeToString(hash[:4]))
+	// The preview is stored next to the full article, which other users may have access to
+	if completeness == CompletenessPreview {
+		name += "-preview"
+	}
+	if supplied {
+		name += "-upload-" + strings.ReplaceAll(uuid.New().String(), "-", "")[:12]
+	}
+	return name
+}
+
+// writeBook writes the epub to the output directory and wraps it into a Book.
+// supplied is set if the book was built from a page the user supplied.
+func writeBook(book *epub.Epub, permalink string, paid bool, completeness string, releaseDate time.Time, supplied bool) (*Book, error) {
+	conf, _ := config.GetConfig()
+	epubPath := filepath.Join(conf.OutputDirectory, bookFileName(book.Title(), permalink, completeness, supplied)+".epub")
+	if !insideDirectory(conf.OutputDirectory, epubPath) {
+		return nil, fmt.Errorf("the file name of %q leaves the output directory", book.Title())
+	}
+
+	// Create output dir if not already existing, conversions of the book are stored in it
+	err := os.MkdirAll(strings.TrimSuffix(epubPath, ".epub"), os.ModePerm)
+	if err != nil {
+		log.Errorf(nil, "Failed to create output directory: %s", err.Error())
+		return nil, err
+	}
+
+	// We can now create an EPUB from the parsed HTML content
+	reportBuilding(book)
+	err = book.Write(epubPath)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &Book{
+		Book:         book,
+		Path:         &epubPath,
+		Permalink:    &permalink,
+		Paid:         paid,
+		ReleaseDate:  releaseDate,
+		Completeness: completeness,
+	}, nil
+}
+
+// insideDirectory reports whether the path is located in the directory
+func insideDirectory(dir string, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) && !filepath.IsAbs(rel)
+}
@@ -0,0 +1,85 @@
+package scrape
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBookFileName(t *testing.T) {
+	tests := []struct {
+		name         string
+		title        string
+		completeness string
+		prefix       string
+		suffix       string
+	}{
+		{name: "plain title", title: "Leaving Big Tech", prefix: "leaving-big-tech-"},
+		{name: "path traversal", title: "../../etc/passwd", prefix: "etcpasswd-"},
+		{name: "slashes", title: "A/B \\ C", prefix: "ab--c-"},
+		{name: "no latin characters", title: "文章", prefix: "article-"},
+		{name: "empty title", title: "", prefix: "article-"},
+		{name: "preview", title: "Paid Post", completeness: CompletenessPreview, prefix: "paid-post-", suffix: "-preview"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			name := bookFileName(test.title, "https://example.com/p/post", test.completeness, false)
+			if !strings.HasPrefix(name, test.prefix) || !strings.HasSuffix(name, test.suffix) {
+				t.Errorf("bookFileName(%q) = %q, want prefix %q and suffix %q", test.title, name, test.prefix, test.suffix)
+			}
+			if strings.ContainsAny(name, "/\\.") {
+				t.Errorf("bookFileName(%q) = %q contains path characters", test.title, name)
+			}
+		})
+	}
+}
+
+func TestBookFileNameSeparatesArticles(t *testing.T) {
+	first := bookFileName("Weekly Update", "https://a.example.com/p/weekly-update", CompletenessFull, false)
+	second := bookFileName("Weekly Update", "https://b.example.com/p/weekly-update", CompletenessFull, false)
+	if first == second {
+		t.Errorf("articles with the same title share the file name %q", first)
+	}
+	if again := bookFileName("Weekly Update", "https://a.example.com/p/weekly-update", CompletenessFull, false); again != first {
+		t.Errorf("file name of the same article changed from %q to %q", first, again)
+	}
+}
+
+func TestBookFileNameOfSuppliedPages(t *testing.T) {
+	fetched := bookFileName("Paid Post", "https://example.com/p/paid-post", CompletenessFull, false)
+	first := bookFileName("Paid Post", "https://example.com/p/paid-post", CompletenessFull, true)
+	second := bookFileName("Paid Post", "https://example.com/p/paid-post", CompletenessFull, true)
+	if first == fetched || second == fetched {
+		t.Errorf("supplied page uses the file %q of the fetched article", fetched)
+	}
+	if first == second {
+		t.Errorf("supplied pages share the file name %q", first)
+	}
+}
+
+func TestBookFileNameLength(t *testing.T) {
+	name := bookFileName(strings.Repeat("word ", 100), "https://example.com/p/long", CompletenessFull, false)
+	if len(name) > maxFileNameLength+9 {
+		t.Errorf("file name has %d characters, want at most %d", len(name), maxFileNameLength+9)
+	}
+}
+
+func TestInsideDirectory(t *testing.T) {
+	dir := filepath.FromSlash("/data/output")
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{path: "/data/output/article.epub", want: true},
+		{path: "/data/output/sub/article.epub", want: true},
+		{path: "/data/output/../article.epub", want: false},
+		{path: "/data/other/article.epub", want: false},
+		{path: "/data/output..x/article.epub", want: false},
+		{path: "/data/output/..article.epub", want: true},
+	}
+	for _, test := range tests {
+		if got := insideDirectory(dir, filepath.FromSlash(test.path)); got != test.want {
+			t.Errorf("insideDirectory(%q) = %v, want %v", test.path, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package scrape
+
+import (
+	"fmt"
+	"github.com/PuerkitoBio/goquery"
+	"github.com/go-shiori/go-epub"
+	"html"
+	"strings"
+)
+
+const (
+	// CompletenessFull is used for books that contain the whole article
+	CompletenessFull = "full"
+	// CompletenessPreview is used for books that only contain the preview of a paywalled article
+	CompletenessPreview = "preview"
+)
+
+const (
+	// Elements publications render in place of the rest of a paid article
+	paywallSelector = ".paywall, .paywall-title, .paywall-cta, [data-testid=\"paywall\"]"
+	// Some publications wrap the whole paid content into a .paywall block for search engines,
+	// so only short blocks with a paywall marker count as call to action
+	maxCallToActionWords = 80
+	// The article is considered cut off if less than this share of the words announced in the ld+json were found
+	minWordCountRatio = 0.6
+	// Word counts of short articles are too imprecise to compare
+	minAnnouncedWordCount = 150
+)
+
+// paywallMarkers are texts that are shown instead of the rest of a paid article
+var paywallMarkers = []string{
+	"This post is for paid subscribers",
+	"This post is for paying subscribers",
+	"This post is for subscribers",
+	"This post is for members",
+	"Keep reading with a 7-day free trial",
+	"Subscribe to keep reading",
+	"Upgrade to read the rest",
+	"This content is for paying subscribers",
+	"The rest of this post is for paid subscribers",
+}
+
+// previewBanner is the first page of books that only contain the preview of an article
+const previewBanner = `<h1>Preview only</h1>
+<p>This book only contains the preview of <em>%s</em>, the rest of the article is reserved for paying subscribers of the publication.</p>
+<p>To receive the full article, subscribe to the publication and provide your session to the bot via the <code>/session</code> command.</p>`
+
+// detectCompleteness checks whether only the preview of a paywalled article was found.
+// page is the whole page, content the element containing the article and wordCount the length
+// of the article according to the structured data of the page, 0 if it is unknown.
+// Markers in the text of the article itself are ignored, authors quote them too.
+func detectCompleteness(page *goquery.Selection, content *goquery.Selection, wordCount int) string {
+	if hasCallToAction(page.Find(paywallSelector)) {
+		return CompletenessPreview
+	}
+	if wordCount >= minAnnouncedWordCount && float64(countWords(content.Text())) < float64(wordCount)*minWordCountRatio {
+		return CompletenessPreview
+	}
+	return CompletenessFull
+}
+
+// hasCallToAction checks whether one of the blocks is a short call to action with a paywall marker
+func hasCallToAction(blocks *goquery.Selection) bool {
+	found := false
+	blocks.EachWithBreak(func(i int, block *goquery.Selection) bool {
+		text := block.Text()
+		found = countWords(text) <= maxCallToActionWords && containsPaywallMarker(text)
+		return !found
+	})
+	return found
+}
+
+func containsPaywallMarker(text string) bool {
+	text = strings.ToLower(text)
+	for _, marker := range paywallMarkers {
+		if strings.Contains(text, strings.ToLower(marker)) {
+			return true
+		}
+	}
+	return false
+}
+
+func countWords(text string) int {
+	return len(strings.Fields(text))
+}
+
+// addPreviewBanner adds the page telling the reader that the book is only a preview
+func addPreviewBanner(book *epub.Epub) error {
+	_, err := book.AddSection(fmt.Sprintf(previewBanner, html.EscapeString(book.Title())), "Preview only", "preview.xhtml", "")
+	return err
+}
@@ -0,0 +1,159 @@
+package scrape
+
+import (
+	"github.com/PuerkitoBio/goquery"
+	"strings"
+	"testing"
+)
+
+// The pages below are shortened posts with the markup Substack, Ghost and beehiiv publish around the content
+
+const substackPreviewPage = `<html><head><title>The Weekly Letter - by Jane Doe</title></head><body>
+<div class="single-post"><article class="typography newsletter-post post">
+<div class="post-header"><h1 class="post-title published">The Weekly Letter</h1></div>
+<div class="available-content"><div dir="auto" class="body markup">
+<p>This week I finally finished the piece on local news that I promised you in spring.</p>
+<p>It took longer than planned, mostly because three of the papers I wanted to visit closed before I got there.</p>
+</div></div>
+<div data-testid="paywall" data-component-name="Paywall" class="paywall"><h2 class="paywall-title">This post is for paid subscribers</h2>
+<div class="paywall-cta"><a class="button primary" href="https://janedoe.substack.com/subscribe"><span>Subscribe</span></a></div>
+<div class="paywall-login">Already a paid subscriber? <a href="https://substack.com/sign-in">Sign in</a></div></div>
+</article></div></body></html>`
+
+const substackTrialPreviewPage = `<html><head><title>Notes on Markets</title></head><body>
+<article class="typography newsletter-post post">
+<div class="available-content"><div dir="auto" class="body markup">
+<p>Rates did not move this month, but the reasons behind it changed completely.</p>
+</div></div>
+<div data-testid="paywall" class="paywall"><h2 class="paywall-title">Keep reading with a 7-day free trial</h2>
+<p class="paywall-content">Subscribe to Notes on Markets to keep reading this post and get 7 days of free access to the full post archives.</p>
+<a class="button primary" href="/subscribe?coupon=trial">Start trial</a></div>
+</article></body></html>`
+
+const substackFullPage = `<html><head><title>The Weekly Letter - by Jane Doe</title></head><body>
+<div class="single-post"><article class="typography newsletter-post post">
+<div class="available-content"><div dir="auto" class="body markup">
+<p>This week I finally finished the piece on local news that I promised you in spring.</p>
+<p>Readers keep forwarding me newsletters that end with "Upgrade to read the rest" after a single paragraph. I promise this one never will.</p>
+<p>The rest of the story follows below, with all the numbers I collected.</p>
+</div></div>
+<div class="subscription-widget-wrap"><div class="subscription-widget show-subscribe"><div class="preamble"><p>Thanks for reading! Subscribe for free to receive new posts and support my work.</p></div>
+<form class="subscription-widget-subscribe"><input type="email" placeholder="Type your email..."/><input type="submit" value="Subscribe"/></form></div></div>
+<div class="paywall-cta"><a class="button" href="/subscribe">Subscribe</a></div>
+</article></div></body></html>`
+
+// Some publications keep the whole paid post in a .paywall block for search engines
+const substackIndexedPage = `<html><head><title>Long Read</title></head><body><article class="post">
+<div class="available-content"><div class="body markup"><div class="paywall">
+<p>` + longParagraph + `</p><p>` + longParagraph + `</p><p>Upgrade to read the rest of my archive, this post is for paid subscribers and stays that way.</p>
+</div></div></div></article></body></html>`
+
+const longParagraph = `When the paper stopped printing on Tuesdays, nobody in town noticed for a week. Then the obituaries ` +
+	`started to arrive late, the council minutes were no longer summarized anywhere, and the school board meeting ` +
+	`that decided on the new building went by without a single reporter in the room.`
+
+const ghostPreviewPage = `<html><head><meta name="generator" content="Ghost 5.82"/><title>Field Notes</title></head><body class="post-template">
+<main id="site-main" class="site-main"><article class="article post tag-essays post-access-paid">
+<header class="article-header gh-canvas"><h1 class="article-title">Field Notes</h1></header>
+<section class="gh-content gh-canvas">
+<p>The first snow came early this year and caught most of the valley unprepared.</p>
+<aside class="gh-post-upgrade-cta"><div class="gh-post-upgrade-cta-content" style="background-color: #15171A">
+<h2>This post is for paying subscribers only</h2>
+<a class="gh-btn" data-portal="signup" href="#/portal/signup" style="color:#15171A">Subscribe now</a>
+<p><small>Already have an account? <a data-portal="signin" href="#/portal/signin">Sign in</a></small></p>
+</div></aside></section></article></main></body></html>`
+
+const ghostMembersPreviewPage = `<html><head><meta name="generator" content="Ghost 5.82"/></head><body>
+<article class="gh-article post post-access-members"><section class="gh-content gh-canvas">
+<p>A short teaser for members.</p>
+<section class="gh-cta"><div class="gh-cta-inner"><h2 class="gh-cta-title">This post is for subscribers only</h2>
+<a class="gh-button" href="#/portal/signup">Subscribe now</a></div></section>
+</section></article></body></html>`
+
+const ghostFullPage = `<html><head><meta name="generator" content="Ghost 5.82"/><title>Field Notes</title></head><body class="post-template">
+<main id="site-main" class="site-main"><article class="article post tag-essays post-access-paid">
+<section class="gh-content gh-canvas">
+<p>The first snow came early this year and caught most of the valley unprepared.</p>
+<p>By the second week the pass was closed, and the bus only ran to the lower village.</p>
+</section>
+<section class="gh-cta"><div class="gh-cta-inner"><h2 class="gh-cta-title">Subscribe to Field Notes</h2>
+<p class="gh-cta-description">Get the latest posts delivered right to your inbox.</p>
+<a class="gh-button" href="#/portal/signup">Subscribe</a></div></section>
+</article></main></body></html>`
+
+const beehiivPreviewPage = `<html><head><link rel="preconnect" href="https://media.beehiiv.com"/><title>The Build</title></head><body>
+<div class="mx-auto max-w-2xl"><h1 class="text-3xl font-bold">The Build #42</h1>
+<div id="content-blocks"><div class="dream-post-content-doc">
+<p>Three founders told me the same thing this week, and none of them knew the others had said it.</p>
+</div></div>
+<div class="relative rounded-wt p-6 text-center"><h2 class="text-2xl font-bold">Subscribe to Premium to read the rest.</h2>
+<p>Become a paying subscriber of The Build to get access to this post and other subscriber-only content.</p>
+<a class="rounded-wt px-4 py-2" href="/upgrade">Upgrade</a>
+<p class="text-sm">Already a paying subscriber? <a href="/login">Sign In</a>.</p></div>
+</div></body></html>`
+
+const beehiivFullPage = `<html><head><link rel="preconnect" href="https://media.beehiiv.com"/><title>The Build</title></head><body>
+<div class="mx-auto max-w-2xl"><h1 class="text-3xl font-bold">The Build #42</h1>
+<div id="content-blocks"><div class="dream-post-content-doc">
+<p>Three founders told me the same thing this week, and none of them knew the others had said it.</p>
+<p>One of them pays for a newsletter that asks her to upgrade to read the rest. She never did.</p>
+</div></div>
+<div class="subscribe-widget"><h3>Join 12,000 builders</h3><form><input type="email"/><button>Subscribe</button></form></div>
+</div></body></html>`
+
+func parseTestPage(t *testing.T, page string) *goquery.Document {
+	t.Helper()
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(page))
+	if err != nil {
+		t.Fatalf("parsing the page failed: %s", err)
+	}
+	return doc
+}
+
+func TestDetectCompleteness(t *testing.T) {
+	tests := []struct {
+		name      string
+		page      string
+		content   string
+		wordCount int
+		want      string
+	}{
+		{name: "substack preview", page: substackPreviewPage, content: ".available-content", want: CompletenessPreview},
+		{name: "substack trial preview", page: substackTrialPreviewPage, content: ".available-content", want: CompletenessPreview},
+		{name: "substack full post quoting a marker", page: substackFullPage, content: ".available-content", want: CompletenessFull},
+		{name: "substack full post in paywall block", page: substackIndexedPage, content: ".available-content", wordCount: 150, want: CompletenessFull},
+		{name: "substack cut off without call to action", page: substackFullPage, content: ".available-content", wordCount: 2400, want: CompletenessPreview},
+		{name: "beehiiv full post", page: beehiivFullPage, content: "#content-blocks", want: CompletenessFull},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			doc := parseTestPage(t, test.page)
+			got := detectCompleteness(doc.Selection, doc.Find(test.content).First(), test.wordCount)
+			if got != test.want {
+				t.Errorf("detectCompleteness() = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestPlatformPaywalls(t *testing.T) {
+	tests := []struct {
+		name      string
+		page      string
+		paywalled func(doc *goquery.Document) bool
+		want      bool
+	}{
+		{name: "ghost paid preview", page: ghostPreviewPage, paywalled: isGhostPaywalled, want: true},
+		{name: "ghost members preview", page: ghostMembersPreviewPage, paywalled: isGhostPaywalled, want: true},
+		{name: "ghost full post with sign up", page: ghostFullPage, paywalled: isGhostPaywalled, want: false},
+		{name: "beehiiv preview", page: beehiivPreviewPage, paywalled: isBeehiivPaywalled, want: true},
+		{name: "beehiiv full post quoting a marker", page: beehiivFullPage, paywalled: isBeehiivPaywalled, want: false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := test.paywalled(parseTestPage(t, test.page)); got != test.want {
+				t.Errorf("paywalled = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
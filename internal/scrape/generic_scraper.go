@@ -0,0 +1,564 @@
+package scrape
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/PuerkitoBio/goquery"
+	"github.com/go-shiori/go-epub"
+	"golang.org/x/net/html"
+	"math"
+	"net/url"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// GenericScraper extracts the main content of arbitrary article pages with readability heuristics.
+// It is used for all websites that are not handled by a dedicated scraper.
+type GenericScraper struct {
+	Progress ProgressFunc
+	Page     *Page
+}
+
+func init() {
+	Register(Registration{
+		Name:     "generic",
+		Fallback: true,
+		New: func(host string, opts Options) Scraper {
+			return GenericScraper{Progress: opts.Progress, Page: opts.Page}
+		},
+	})
+}
+
+// pageMetadata is the article information found in the ld+json and meta tags of a page
+type pageMetadata struct {
+	Title       string
+	Author      string
+	Publisher   string
+	Description string
+	Published   time.Time
+	Free        bool
+	// WordCount is the length of the article according to the ld+json, 0 if it is unknown
+	WordCount int
+	// Paywalled is set by scrapers that detected the paywall of their platform
+	Paywalled bool
+}
+
+var (
+	// Elements matching these class names or ids are most likely not part of the article
+	unlikelyCandidates = regexp.MustCompile(`(?i)-ad-|ai2html|banner|breadcrumbs|combx|comment|community|cover-wrap|disqus|extra|footer|gdpr|header|legends|menu|related|remark|replies|rss|shoutbox|sidebar|skyscraper|social|sponsor|supplemental|ad-break|agegate|pagination|pager|popup|cookie|subscribe|share`)
+	// Elements matching these class names or ids are kept even if they match unlikelyCandidates
+	maybeCandidates    = regexp.MustCompile(`(?i)and|article|body|column|content|main|shadow`)
+	positiveClassNames = regexp.MustCompile(`(?i)article|body|content|entry|hentry|h-entry|main|page|post|text|blog|story`)
+	negativeClassNames = regexp.MustCompile(`(?i)hidden|banner|combx|comment|com-|contact|foot|footer|footnote|masthead|media|meta|outbrain|promo|related|scroll|share|shoutbox|sidebar|skyscraper|sponsor|shopping|tags|tool|widget|subscribe|popup|modal|cookie`)
+
+	articleTypes = map[string]bool{
+		"Article":             true,
+		"NewsArticle":         true,
+		"BlogPosting":         true,
+		"TechArticle":         true,
+		"Report":              true,
+		"ScholarlyArticle":    true,
+		"SocialMediaPosting":  true,
+		"AnalysisNewsArticle": true,
+	}
+
+	// Only these attributes survive the cleanup of the article content
+	keptAttributes = map[string]bool{
+		"href":  true,
+		"src":   true,
+		"alt":   true,
+		"title": true,
+	}
+)
+
+// CheckPaywallAccessible fetches the page without a session. Paid pages are only accessible if
+// the fetched page contains the whole article, which then can be read by anyone.
+func (g GenericScraper) CheckPaywallAccessible(targetUrl string) (bool, error) {
+	doc, err := fetchDocument(targetUrl, g.Page)
+	if err != nil {
+		return false, err
+	}
+	meta := extractMetadata(doc)
+	if meta.Free {
+		return true, nil
+	}
+	if meta.Paywalled {
+		return false, nil
+	}
+	content := extractContent(doc)
+	if content == nil {
+		return false, nil
+	}
+	return detectCompleteness(doc.Selection, content, meta.WordCount) == CompletenessFull, nil
+}
+
+func (g GenericScraper) Scrape(url *string) (*Book, error) {
+	doc, err := fetchDocument(*url, g.Page)
+	if err != nil {
+		return nil, err
+	}
+
+	meta := extractMetadata(doc)
+	content := extractContent(doc)
+	return buildBook(*url, meta, doc, content, g.Progress, g.Page != nil)
+}
+
+// buildBook creates the book of an article from its metadata and the element containing the article.
+// supplied is set if the page was supplied by the user instead of being fetched.
+func buildBook(permalink string, meta pageMetadata, doc *goquery.Document, content *goquery.Selection, progress ProgressFunc, supplied bool) (*Book, error) {
+	if meta.Title == "" {
+		return nil, fmt.Errorf("failed to find the title of the article")
+	}
+	completeness := CompletenessPreview
+	if !meta.Paywalled && content != nil {
+		completeness = detectCompleteness(doc.Selection, content, meta.WordCount)
+	}
+	if content == nil || strings.TrimSpace(content.Text()) == "" {
+		// Some paid articles do not even have a preview
+		if completeness == CompletenessPreview {
+			return nil, ErrPaywall
+		}
+		return nil, fmt.Errorf("failed to find the content of the article")
+	}
+	// Articles that are cut off are paid, even if the page does not say so
+	if completeness == CompletenessPreview {
+		meta.Free = false
+	}
+	base := doc.Url
+
+	book, err := newBook(meta.Title, progress)
+	if err != nil {
+		return nil, err
+	}
+	book.SetDescription(meta.Description)
+	book.SetIdentifier(permalink)
+
+	author := meta.Author
+	if author == "" {
+		author = meta.Publisher
+	}
+	if author == "" && base != nil {
+		author = base.Hostname()
+	}
+	if meta.Publisher != "" && meta.Publisher != author {
+		author = fmt.Sprintf("%s - %s", meta.Publisher, author)
+	}
+	book.SetAuthor(author)
+
+	// The title is part of the generated title block already
+	content.Find("h1").Each(func(i int, selection *goquery.Selection) {
+		if strings.TrimSpace(selection.Text()) == meta.Title {
+			selection.Remove()
+		}
+	})
+	cleanContent(content)
+	embedImages(book, content, base)
+
+	body, err := content.Html()
+	if err != nil {
+		return nil, err
+	}
+
+	err = addArticleSection(book, body, meta.Published, completeness)
+	if err != nil {
+		return nil, err
+	}
+
+	return writeBook(book, permalink, !meta.Free, completeness, meta.Published, supplied)
+}
+
+// extractMetadata collects the article metadata from ld+json, OpenGraph and common meta tags.
+// Structured data takes precedence over the meta tags.
+func extractMetadata(doc *goquery.Document) pageMetadata {
+	meta := pageMetadata{Free: true}
+
+	doc.Find("script[type=\"application/ld+json\"]").Each(func(i int, selection *goquery.Selection) {
+		var data interface{}
+		if err := json.Unmarshal([]byte(selection.Text()), &data); err != nil {
+			return
+		}
+		walkLinkedData(data, &meta)
+	})
+
+	metaContent := func(selectors ...string) string {
+		for _, selector := range selectors {
+			value, _ := doc.Find(selector).First().Attr("content")
+			if value = strings.TrimSpace(value); value != "" {
+				return value
+			}
+		}
+		return ""
+	}
+
+	if meta.Title == "" {
+		meta.Title = metaContent("meta[property=\"og:title\"]", "meta[name=\"twitter:title\"]")
+	}
+	if meta.Title == "" {
+		meta.Title = strings.TrimSpace(doc.Find("h1").First().Text())
+	}
+	if meta.Title == "" {
+		meta.Title = strings.TrimSpace(doc.Find("title").First().Text())
+	}
+	if meta.Author == "" {
+		meta.Author = metaContent("meta[name=\"author\"]", "meta[property=\"article:author\"]", "meta[name=\"twitter:creator\"]")
+		// article:author is often a link to the profile of the author
+		if strings.HasPrefix(meta.Author, "http") {
+			meta.Author = ""
+		}
+	}
+	if meta.Author == "" {
+		meta.Author = strings.TrimSpace(doc.Find("[rel=\"author\"], .author, .byline").First().Text())
+	}
+	if meta.Publisher == "" {
+		meta.Publisher = metaContent("meta[property=\"og:site_name\"]", "meta[name=\"application-name\"]")
+	}
+	if meta.Description == "" {
+		meta.Description = metaContent("meta[property=\"og:description\"]", "meta[name=\"description\"]")
+	}
+	if meta.Published.IsZero() {
+		meta.Published = parseDate(metaContent("meta[property=\"article:published_time\"]", "meta[name=\"date\"]", "meta[itemprop=\"datePublished\"]"))
+	}
+	if meta.Published.IsZero() {
+		datetime, _ := doc.Find("time[datetime]").First().Attr("datetime")
+		meta.Published = parseDate(datetime)
+	}
+	if meta.Published.IsZero() {
+		meta.Published = time.Now()
+	}
+
+	return meta
+}
+
+// walkLinkedData searches ld+json data for an article and copies its values into meta
+func walkLinkedData(data interface{}, meta *pageMetadata) {
+	switch value := data.(type) {
+	case []interface{}:
+		for _, entry := range value {
+			walkLinkedData(entry, meta)
+		}
+	case map[string]interface{}:
+		if graph, ok := value["@graph"]; ok {
+			walkLinkedData(graph, meta)
+		}
+		if !isArticleType(value["@type"]) {
+			return
+		}
+		if meta.Title == "" {
+			meta.Title = stringValue(value["headline"])
+		}
+		if meta.Title == "" {
+			meta.Title = stringValue(value["name"])
+		}
+		if meta.Author == "" {
+			meta.Author = personNames(value["author"])
+		}
+		if meta.Publisher == "" {
+			meta.Publisher = personNames(value["publisher"])
+		}
+		if meta.Description == "" {
+			meta.Description = stringValue(value["description"])
+		}
+		if meta.Published.IsZero() {
+			meta.Published = parseDate(stringValue(value["datePublished"]))
+		}
+		if meta.WordCount == 0 {
+			meta.WordCount = intValue(value["wordCount"])
+		}
+		switch free := value["isAccessibleForFree"].(type) {
+		case bool:
+			meta.Free = free
+		case string:
+			meta.Free = !strings.EqualFold(free, "false")
+		}
+	}
+}
+
+func isArticleType(value interface{}) bool {
+	switch t := value.(type) {
+	case string:
+		return articleTypes[t]
+	case []interface{}:
+		for _, entry := range t {
+			if isArticleType(entry) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// intValue reads numbers that are given as number or string
+func intValue(value interface{}) int {
+	switch v := value.(type) {
+	case float64:
+		return int(v)
+	case string:
+		n, _ := strconv.Atoi(strings.TrimSpace(v))
+		return n
+	}
+	return 0
+}
+
+func stringValue(value interface{}) string {
+	if s, ok := value.(string); ok {
+		return strings.TrimSpace(s)
+	}
+	return ""
+}
+
+// personNames returns the names of a ld+json person, organization or a list of them
+func personNames(value interface{}) string {
+	switch person := value.(type) {
+	case string:
+		return strings.TrimSpace(person)
+	case map[string]interface{}:
+		return stringValue(person["name"])
+	case []interface{}:
+		var names []string
+		for _, entry := range person {
+			if name := personNames(entry); name != "" {
+				names = append(names, name)
+			}
+		}
+		return strings.Join(names, ", ")
+	}
+	return ""
+}
+
+func parseDate(value string) time.Time {
+	for _, layout := range []string{time.RFC3339, "2006-01-02T15:04:05Z0700", "2006-01-02T15:04:05", "2006-01-02 15:04:05", "2006-01-02", time.RFC1123Z, time.RFC1123} {
+		parsed, err := time.Parse(layout, strings.TrimSpace(value))
+		if err == nil {
+			return parsed
+		}
+	}
+	return time.Time{}
+}
+
+// extractContent finds the element that most likely contains the article.
+// Paragraphs are scored by their length and amount of commas, the score is
+// propagated to their parents and the best scoring element is returned.
+func extractContent(doc *goquery.Document) *goquery.Selection {
+	doc.Find("script, style, noscript, iframe, form, button, input, select, textarea, svg, nav, header, footer, aside").Remove()
+
+	doc.Find("*").Each(func(i int, selection *goquery.Selection) {
+		if selection.Is("html, body, article, main") {
+			return
+		}
+		matchString := classAndID(selection)
+		if unlikelyCandidates.MatchString(matchString) && !maybeCandidates.MatchString(matchString) {
+			selection.Remove()
+		}
+	})
+
+	scores := map[*html.Node]float64{}
+	var candidates []*goquery.Selection
+
+	addScore := func(selection *goquery.Selection, score float64) {
+		if selection.Length() == 0 || selection.Is("html, body") {
+			return
+		}
+		node := selection.Get(0)
+		if _, ok := scores[node]; !ok {
+			scores[node] = initialScore(selection)
+			candidates = append(candidates, selection)
+		}
+		scores[node] += score
+	}
+
+	doc.Find("p, pre, td, blockquote").Each(func(i int, selection *goquery.Selection) {
+		text := strings.TrimSpace(selection.Text())
+		if len(text) < 25 {
+			return
+		}
+		score := 1 + float64(strings.Count(text, ",")) + math.Min(float64(len(text)/100), 3)
+		addScore(selection.Parent(), score)
+		addScore(selection.Parent().Parent(), score/2)
+	})
+
+	var best *goquery.Selection
+	bestScore := 0.0
+	for _, candidate := range candidates {
+		score := scores[candidate.Get(0)] * (1 - linkDensity(candidate))
+		if best == nil || score > bestScore {
+			best = candidate
+			bestScore = score
+		}
+	}
+
+	if best == nil {
+		best = doc.Find("article").First()
+	}
+	if best.Length() == 0 {
+		best = doc.Find("body").First()
+	}
+	return best
+}
+
+func classAndID(selection *goquery.Selection) string {
+	class, _ := selection.Attr("class")
+	id, _ := selection.Attr("id")
+	return class + " " + id
+}
+
+func initialScore(selection *goquery.Selection) float64 {
+	score := 0.0
+	switch goquery.NodeName(selection) {
+	case "article":
+		score += 10
+	case "div":
+		score += 5
+	case "pre", "td", "blockquote":
+		score += 3
+	case "address", "ol", "ul", "dl", "dd", "dt", "li", "form":
+		score -= 3
+	case "h1", "h2", "h3", "h4", "h5", "h6", "th":
+		score -= 5
+	}
+
+	matchString := classAndID(selection)
+	if negativeClassNames.MatchString(matchString) {
+		score -= 25
+	}
+	if positiveClassNames.MatchString(matchString) {
+		score += 25
+	}
+	return score
+}
+
+// linkDensity is the share of the text of an element that is part of a link
+func linkDensity(selection *goquery.Selection) float64 {
+	textLength := len(strings.TrimSpace(selection.Text()))
+	if textLength == 0 {
+		return 0
+	}
+	linkLength := 0
+	selection.Find("a").Each(func(i int, link *goquery.Selection) {
+		linkLength += len(strings.TrimSpace(link.Text()))
+	})
+	return float64(linkLength) / float64(textLength)
+}
+
+// cleanContent removes the styling and clutter of the page from the article content
+func cleanContent(content *goquery.Selection) {
+	// Link lists inside the article are most likely navigation or "read more" blocks
+	content.Find("ul, ol, div").Each(func(i int, selection *goquery.Selection) {
+		if linkDensity(selection) > 0.5 && len(strings.TrimSpace(selection.Text())) < 500 && selection.Find("img").Length() == 0 {
+			selection.Remove()
+		}
+	})
+
+	// Keep the image of <picture> elements only
+	content.Find("picture").Each(func(i int, selection *goquery.Selection) {
+		img := selection.Find("img").First()
+		if img.Length() == 0 {
+			selection.Remove()
+			return
+		}
+		selection.ReplaceWithSelection(img)
+	})
+
+	content.Find("*").AddSelection(content).Each(func(i int, selection *goquery.Selection) {
+		node := selection.Get(0)
+		var attributes []html.Attribute
+		for _, attribute := range node.Attr {
+			if keptAttributes[attribute.Key] || strings.HasPrefix(attribute.Key, "data-") && goquery.NodeName(selection) == "img" {
+				attributes = append(attributes, attribute)
+			}
+		}
+		node.Attr = attributes
+	})
+}
+
+// resolveURL makes a link of the page absolute
+func resolveURL(base *url.URL, ref string) string {
+	if base == nil {
+		return ref
+	}
+	u, err := base.Parse(strings.TrimSpace(ref))
+	if err != nil {
+		return ref
+	}
+	return u.String()
+}
+
+// isWebImage reports whether the image source is downloaded from the web or embedded in the page.
+// Any other source, e.g. a local path, would be read from the disk of the server.
+func isWebImage(src string) bool {
+	u, err := url.Parse(src)
+	if err != nil {
+		return false
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "http", "https", "data":
+		return true
+	}
+	return false
+}
+
+// imageSource returns the URL of an image, taking the common lazy loading attributes into account
+func imageSource(selection *goquery.Selection) string {
+	for _, attribute := range []string{"data-src", "data-lazy-src", "data-original", "src"} {
+		value, _ := selection.Attr(attribute)
+		if value != "" && !strings.HasPrefix(value, "data:") {
+			return value
+		}
+	}
+	srcset, _ := selection.Attr("srcset")
+	if srcset == "" {
+		srcset, _ = selection.Attr("data-srcset")
+	}
+	return largestSrcsetEntry(srcset)
+}
+
+// largestSrcsetEntry returns the URL of the last, usually largest, candidate of a srcset
+func largestSrcsetEntry(srcset string) string {
+	entries := strings.Split(srcset, ",")
+	for i := len(entries) - 1; i >= 0; i-- {
+		fields := strings.Fields(entries[i])
+		if len(fields) > 0 {
+			return fields[0]
+		}
+	}
+	return ""
+}
+
+// imageFilename returns the last path segment of an image URL to derive the file ending from
+func imageFilename(imgSrc string) string {
+	u, err := url.Parse(imgSrc)
+	if err != nil {
+		return imgSrc
+	}
+	return path.Base(u.Path)
+}
+
+// embedImages adds the images of the content to the book and resolves all links relative to base
+func embedImages(book *epub.Epub, content *goquery.Selection, base *url.URL) {
+	content.Find("a[href]").Each(func(i int, selection *goquery.Selection) {
+		href, _ := selection.Attr("href")
+		selection.SetAttr("href", resolveURL(base, href))
+	})
+
+	content.Find("img").Each(func(i int, selection *goquery.Selection) {
+		imgSrc := imageSource(selection)
+		if imgSrc == "" {
+			selection.Remove()
+			return
+		}
+		imgSrc = resolveURL(base, imgSrc)
+		if !isWebImage(imgSrc) {
+			selection.Remove()
+			return
+		}
+		image, err := book.AddImage(imgSrc, generateUUID(imageFilename(imgSrc)))
+		if err != nil {
+			selection.Remove()
+			return
+		}
+		alt, _ := selection.Attr("alt")
+		selection.ReplaceWithHtml(fmt.Sprintf("<img src=\"%s\" alt=\"%s\"/>", image, html.EscapeString(alt)))
+	})
+}
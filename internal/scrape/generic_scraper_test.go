@@ -0,0 +1,41 @@
+package scrape
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// articlePage returns an article page with structured data and the given amount of paragraphs
+func articlePage(free bool, wordCount int, paragraphs int, extra string) string {
+	paragraph := "<p>The quick brown fox jumps over the lazy dog, again and again, until the story ends.</p>"
+	return fmt.Sprintf(`<html><head><title>Story</title>
+<script type="application/ld+json">{"@type":"NewsArticle","headline":"Story","isAccessibleForFree":%t,"wordCount":%d,"author":{"name":"Jane"}}</script>
+</head><body><article><h1>Story</h1>%s%s</article></body></html>`, free, wordCount, strings.Repeat(paragraph, paragraphs), extra)
+}
+
+func TestGenericCheckPaywallAccessible(t *testing.T) {
+	tests := []struct {
+		name string
+		html string
+		want bool
+	}{
+		{name: "free article", html: articlePage(true, 0, 20, ""), want: true},
+		{name: "paid article readable without session", html: articlePage(false, 320, 20, ""), want: true},
+		{name: "paid article cut off", html: articlePage(false, 2000, 5, ""), want: false},
+		{name: "paid article with paywall", html: articlePage(false, 0, 5, `<div class="paywall"><h2>This post is for paid subscribers</h2></div>`), want: false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			targetURL := "https://example.com/p/story"
+			scraper := GenericScraper{Page: &Page{URL: targetURL, HTML: test.html}}
+			got, err := scraper.CheckPaywallAccessible(targetURL)
+			if err != nil {
+				t.Fatalf("CheckPaywallAccessible failed: %s", err)
+			}
+			if got != test.want {
+				t.Errorf("CheckPaywallAccessible() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
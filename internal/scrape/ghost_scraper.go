@@ -0,0 +1,80 @@
+package scrape
+
+import (
+	"fmt"
+	"github.com/PuerkitoBio/goquery"
+	"net/http"
+	"strings"
+)
+
+// GhostScraper exports posts of newsletters running on Ghost
+type GhostScraper struct {
+	// GhostSessionCookie is either the value of the ghost-members-ssr cookie or the
+	// complete Cookie header including ghost-members-ssr.sig
+	GhostSessionCookie *string
+	Progress           ProgressFunc
+	Page               *Page
+}
+
+// Call to action elements Ghost themes render instead of the content of member-only posts
+const ghostPaywallSelector = ".gh-post-upgrade-cta, .post-upgrade-cta, .gh-cta, .single-cta"
+
+func init() {
+	Register(Registration{
+		Name: PlatformGhost,
+		MatchHost: func(host string) bool {
+			return strings.HasSuffix(host, ".ghost.io")
+		},
+		MatchPage: func(doc *goquery.Document) bool {
+			generator, _ := doc.Find("meta[name=\"generator\"]").Attr("content")
+			return strings.HasPrefix(generator, "Ghost")
+		},
+		New: func(host string, opts Options) Scraper {
+			// Ghost publications are independent sites, so sessions are stored per host
+			return GhostScraper{GhostSessionCookie: opts.session(PlatformGhost, host), Progress: opts.Progress, Page: opts.Page}
+		},
+	})
+}
+
+func (g GhostScraper) cookies() []*http.Cookie {
+	return ParseCookies(g.GhostSessionCookie, "ghost-members-ssr")
+}
+
+// isGhostPaywalled checks whether the post is cut off by a call to action for members
+func isGhostPaywalled(doc *goquery.Document) bool {
+	// Some themes use the same classes for newsletter sign ups below free posts
+	return hasCallToAction(doc.Find(ghostPaywallSelector))
+}
+
+func (g GhostScraper) CheckPaywallAccessible(targetUrl string) (bool, error) {
+	doc, err := fetchDocument(targetUrl, g.Page, g.cookies()...)
+	if err != nil {
+		return false, err
+	}
+	return !isGhostPaywalled(doc), nil
+}
+
+func (g GhostScraper) Scrape(url *string) (*Book, error) {
+	doc, err := fetchDocument(*url, g.Page, g.cookies()...)
+	if err != nil {
+		return nil, err
+	}
+	meta := extractMetadata(doc)
+
+	// Ghost does not mark paid posts in its structured data, the visibility is only visible through the upgrade prompt
+	meta.Paywalled = isGhostPaywalled(doc)
+	meta.Free = !meta.Paywalled && doc.Find("article.post-access-paid, article.post-access-members, article.post-access-tiers").Length() == 0
+
+	content := doc.Find(".gh-content, .post-content, .article-content").First()
+	if content.Length() == 0 {
+		if meta.Paywalled {
+			return nil, ErrPaywall
+		}
+		return nil, fmt.Errorf("failed to parse the Ghost newsletter correctly")
+	}
+
+	// Cards that only work with javascript
+	content.Find(".kg-signup-card, .kg-toggle-card button, .kg-audio-card, .kg-video-card").Remove()
+
+	return buildBook(*url, meta, doc, content, g.Progress, g.Page != nil)
+}
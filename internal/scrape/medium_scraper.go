@@ -0,0 +1,174 @@
+package scrape
+
+import (
+	"fmt"
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// MediumScraper exports articles hosted on Medium, including publications on custom domains
+type MediumScraper struct {
+	// MediumSessionCookie is the value of the sid cookie of a Medium member or a Cookie header
+	MediumSessionCookie *string
+	Progress            ProgressFunc
+	Page                *Page
+}
+
+var (
+	// Medium serves webp by default, which is not supported by Kindle devices
+	mediumWebpFormat = regexp.MustCompile(`/format:webp`)
+
+	// Texts that are shown instead of the rest of the story if it is reserved for members
+	mediumPaywallMarkers = []string{
+		"The author made this story available to Medium members only",
+		"Read the full story with a free account",
+		"Create an account to read the full story",
+		"Become a member to read this story",
+	}
+)
+
+func init() {
+	Register(Registration{
+		Name:      PlatformMedium,
+		MatchHost: isMediumHost,
+		MatchPage: func(doc *goquery.Document) bool {
+			return doc.Find("meta[property=\"al:ios:app_name\"][content=\"Medium\"]").Length() > 0
+		},
+		New: func(host string, opts Options) Scraper {
+			// The member session is only sent to Medium itself, publications on a custom domain need a session of their own
+			cookie := opts.hostSession(PlatformMedium, host)
+			if isMediumHost(host) {
+				cookie = opts.session(PlatformMedium, host)
+			}
+			return MediumScraper{MediumSessionCookie: cookie, Progress: opts.Progress, Page: opts.Page}
+		},
+	})
+}
+
+func isMediumHost(host string) bool {
+	return host == "medium.com" || strings.HasSuffix(host, ".medium.com")
+}
+
+// cookies returns the session, it may be a plain sid value or a Cookie header
+func (m MediumScraper) cookies() []*http.Cookie {
+	return ParseCookies(m.MediumSessionCookie, "sid")
+}
+
+// isMediumTruncated checks the page for the hints Medium shows instead of the full member-only story
+func isMediumTruncated(doc *goquery.Document) bool {
+	text := doc.Find("article").Text()
+	for _, marker := range mediumPaywallMarkers {
+		if strings.Contains(text, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+func (m MediumScraper) CheckPaywallAccessible(targetUrl string) (bool, error) {
+	doc, err := fetchDocument(targetUrl, m.Page, m.cookies()...)
+	if err != nil {
+		return false, err
+	}
+	return !isMediumTruncated(doc), nil
+}
+
+func (m MediumScraper) Scrape(url *string) (*Book, error) {
+	doc, err := fetchDocument(*url, m.Page, m.cookies()...)
+	if err != nil {
+		return nil, err
+	}
+	meta := extractMetadata(doc)
+
+	// Stories reserved for members end after the first paragraphs
+	meta.Paywalled = !meta.Free && isMediumTruncated(doc)
+
+	if title := strings.TrimSpace(doc.Find("h1[data-testid=\"storyTitle\"], h1.pw-post-title").First().Text()); title != "" {
+		meta.Title = title
+	}
+	if meta.Author == "" {
+		meta.Author = strings.TrimSpace(doc.Find("a[data-testid=\"authorName\"]").First().Text())
+	}
+	// Stories outside of publications are published by Medium itself
+	if meta.Publisher == "Medium" {
+		meta.Publisher = ""
+	}
+
+	content := doc.Find("article section").First()
+	if content.Length() == 0 {
+		if meta.Paywalled {
+			return nil, fmt.Errorf("the article is reserved for Medium members: %w", ErrPaywall)
+		}
+		return nil, fmt.Errorf("failed to parse the Medium article correctly")
+	}
+
+	// Drop the header of the story, the title block is generated for the book
+	content.Find("h1[data-testid=\"storyTitle\"], h1.pw-post-title, .pw-subtitle-paragraph, .speechify-ignore, button, svg").Remove()
+	content.Find("[data-testid=\"authorPhoto\"], [data-testid=\"authorName\"], [data-testid=\"storyPublishDate\"], [data-testid=\"storyReadTime\"]").Each(func(i int, selection *goquery.Selection) {
+		selection.Closest("div").Remove()
+	})
+
+	prepareMediumImages(content)
+	prepareMediumCodeBlocks(content)
+
+	return buildBook(*url, meta, doc, content, m.Progress, m.Page != nil)
+}
+
+// prepareMediumImages replaces the lazy loaded <picture> elements of Medium with plain images.
+// The image itself only has a source once it was scrolled into view, so the URL is taken from the srcset.
+func prepareMediumImages(content *goquery.Selection) {
+	content.Find("picture").Each(func(i int, selection *goquery.Selection) {
+		img := selection.Find("img").First()
+		src := imageSource(img)
+		// Prefer the original format over the webp source
+		selection.Find("source").EachWithBreak(func(i int, source *goquery.Selection) bool {
+			sourceType, _ := source.Attr("type")
+			if sourceType == "image/webp" {
+				return true
+			}
+			srcset, _ := source.Attr("srcset")
+			if candidate := largestSrcsetEntry(srcset); candidate != "" {
+				src = candidate
+				return false
+			}
+			return true
+		})
+		if src == "" {
+			selection.Find("source").Each(func(i int, source *goquery.Selection) {
+				srcset, _ := source.Attr("srcset")
+				if candidate := largestSrcsetEntry(srcset); candidate != "" {
+					src = candidate
+				}
+			})
+		}
+		if src == "" {
+			selection.Remove()
+			return
+		}
+		alt, _ := img.Attr("alt")
+		selection.ReplaceWithHtml(fmt.Sprintf("<img src=\"%s\" alt=\"%s\"/>", html.EscapeString(mediumWebpFormat.ReplaceAllString(src, "")), html.EscapeString(alt)))
+	})
+
+	content.Find("img").Each(func(i int, selection *goquery.Selection) {
+		src := imageSource(selection)
+		if src != "" {
+			selection.SetAttr("src", mediumWebpFormat.ReplaceAllString(src, ""))
+		}
+	})
+
+	// Medium repeats the images inside <noscript> for clients without javascript
+	content.Find("noscript").Remove()
+}
+
+// prepareMediumCodeBlocks turns the highlighted code blocks of Medium into plain <pre><code> blocks.
+// Medium renders every token in its own <span> and separates lines with <br>.
+func prepareMediumCodeBlocks(content *goquery.Selection) {
+	content.Find("pre").Each(func(i int, selection *goquery.Selection) {
+		selection.Find("br").ReplaceWithHtml("\n")
+		code := selection.Text()
+		selection.ReplaceWithHtml(fmt.Sprintf("<pre><code>%s</code></pre>", html.EscapeString(code)))
+	})
+}
@@ -0,0 +1,42 @@
+package scrape
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMediumSession(t *testing.T) {
+	mediumPage := `<html><head><meta property="al:ios:app_name" content="Medium"/></head><body><article></article></body></html>`
+	member := Session{Platform: PlatformMedium, Cookie: "member"}
+	publication := Session{Platform: PlatformMedium, Domain: "blog.example.invalid", Cookie: "sid=publication; uid=42"}
+	tests := []struct {
+		name     string
+		url      string
+		sessions []Session
+		want     []string
+	}{
+		{name: "medium.com", url: "https://medium.com/@jane/story", sessions: []Session{member, publication}, want: []string{"sid=member"}},
+		{name: "subdomain of medium.com", url: "https://jane.medium.com/story", sessions: []Session{member}, want: []string{"sid=member"}},
+		{name: "custom domain without session", url: "https://other.example.invalid/story", sessions: []Session{member, publication}, want: nil},
+		{name: "custom domain with session", url: "https://blog.example.invalid/story", sessions: []Session{member, publication}, want: []string{"sid=publication", "uid=42"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			scraper, err := ForURL(test.url, Options{Sessions: test.sessions, Page: &Page{URL: test.url, HTML: mediumPage}})
+			if err != nil {
+				t.Fatalf("ForURL failed: %s", err)
+			}
+			medium, ok := scraper.(MediumScraper)
+			if !ok {
+				t.Fatalf("ForURL() = %T, want the Medium scraper", scraper)
+			}
+			var got []string
+			for _, cookie := range medium.cookies() {
+				got = append(got, cookie.String())
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("cookies = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
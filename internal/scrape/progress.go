@@ -0,0 +1,74 @@
+package scrape
+
+import (
+	"github.com/go-shiori/go-epub"
+	"net/http"
+	"sync"
+)
+
+const (
+	// StageImages reports the downloaded images of the article
+	StageImages = "images"
+	// StageBuilding is reported once all images are downloaded and the epub is assembled
+	StageBuilding = "building"
+)
+
+// ProgressFunc is informed while a scraper builds the book, done and total count the images
+type ProgressFunc func(stage string, done int, total int)
+
+// progressTransport counts the images of a book. The epub library checks every image with a HEAD
+// request when it is added and downloads it with a GET request when the book is written.
+type progressTransport struct {
+	progress ProgressFunc
+	mutex    sync.Mutex
+	total    int
+	done     int
+}
+
+func (t *progressTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := Transport.RoundTrip(req)
+	if err != nil {
+		return resp, err
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	switch req.Method {
+	case http.MethodHead:
+		t.total++
+	case http.MethodGet:
+		t.done++
+		t.progress(StageImages, t.done, max(t.done, t.total))
+		if t.done >= t.total {
+			t.progress(StageBuilding, t.done, t.done)
+		}
+	}
+	return resp, nil
+}
+
+// newBook creates an epub which downloads its images through Transport and reports them to the progress function
+func newBook(title string, progress ProgressFunc) (*epub.Epub, error) {
+	book, err := epub.NewEpub(title)
+	if err != nil {
+		return nil, err
+	}
+	book.Client = Client
+	if progress != nil {
+		book.Client = &http.Client{Transport: &progressTransport{progress: progress}}
+	}
+	return book, nil
+}
+
+// reportBuilding is called before the book is written. Books without images are assembled right away,
+// otherwise the building stage is reported after the last image was downloaded.
+func reportBuilding(book *epub.Epub) {
+	transport, ok := book.Client.Transport.(*progressTransport)
+	if !ok {
+		return
+	}
+	transport.mutex.Lock()
+	defer transport.mutex.Unlock()
+	if transport.total == 0 {
+		transport.progress(StageBuilding, 0, 0)
+	}
+}
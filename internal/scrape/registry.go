@@ -0,0 +1,170 @@
+package scrape
+
+import (
+	"fmt"
+	"github.com/PuerkitoBio/goquery"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const (
+	PlatformSubstack = "substack"
+	PlatformMedium   = "medium"
+	PlatformGhost    = "ghost"
+	PlatformBeehiiv  = "beehiiv"
+)
+
+// Session is a session cookie a user stored for a platform
+type Session struct {
+	Platform string
+	// Domain is the publication the cookie belongs to, empty if it is valid for the whole platform
+	Domain string
+	Cookie string
+}
+
+// Options holds the user specific values a scraper may need to access an article
+type Options struct {
+	// SubstackSession is the value of the connect.sid cookie of the user on substack.com
+	SubstackSession *string
+	// Progress is informed while the book is built, it may be nil
+	Progress ProgressFunc
+	// Sessions are the session cookies the user stored for the other platforms and single publications
+	Sessions []Session
+	// Page is used instead of fetching the article if the user supplied it
+	Page *Page
+}
+
+// session returns the cookie stored for the platform that fits the host best.
+// A cookie stored for the host itself takes precedence over a platform wide one.
+func (o Options) session(platform string, host string) *string {
+	if cookie := o.hostSession(platform, host); cookie != nil {
+		return cookie
+	}
+	for i, session := range o.Sessions {
+		if session.Platform == platform && session.Domain == "" {
+			return &o.Sessions[i].Cookie
+		}
+	}
+	return nil
+}
+
+// hostSession returns the cookie stored for the platform on exactly this host, nil if there is none
+func (o Options) hostSession(platform string, host string) *string {
+	if host == "" {
+		return nil
+	}
+	for i, session := range o.Sessions {
+		if session.Platform == platform && strings.EqualFold(session.Domain, host) {
+			return &o.Sessions[i].Cookie
+		}
+	}
+	return nil
+}
+
+// ParseCookies reads a stored session in the format of a Cookie header ("name=value; name2=value2").
+// A plain value is used as the cookie with the default name, if there is one.
+func ParseCookies(session *string, defaultName string) []*http.Cookie {
+	if session == nil {
+		return nil
+	}
+	value := strings.TrimSpace(*session)
+	if !strings.Contains(value, "=") {
+		if defaultName == "" || value == "" {
+			return nil
+		}
+		return []*http.Cookie{{Name: defaultName, Value: value}}
+	}
+	cookies, err := http.ParseCookie(value)
+	if err != nil {
+		return nil
+	}
+	return cookies
+}
+
+// Registration describes a Scraper implementation and how to detect the pages it can handle
+type Registration struct {
+	// Name is used for logging which scraper was picked
+	Name string
+	// MatchHost returns true if the scraper is responsible for all pages of the host
+	MatchHost func(host string) bool
+	// MatchPage returns true if the fetched page belongs to the platform of the scraper.
+	// This is used for publications that are hosted on a custom domain.
+	MatchPage func(doc *goquery.Document) bool
+	// Fallback marks the scraper to be used if no other scraper matched
+	Fallback bool
+	// New creates the scraper for a single request to the given host
+	New func(host string, opts Options) Scraper
+}
+
+var registrations []Registration
+
+// Register adds a scraper implementation to the registry.
+// Registrations are checked in the order they were added.
+func Register(r Registration) {
+	registrations = append(registrations, r)
+}
+
+// ForURL returns the scraper that is responsible for the given URL.
+// The host is checked first, if no scraper claims it the page is fetched and
+// checked for the fingerprints of the registered platforms.
+func ForURL(targetUrl string, opts Options) (Scraper, error) {
+	u, err := url.Parse(targetUrl)
+	if err != nil {
+		return nil, err
+	}
+	host := strings.ToLower(u.Hostname())
+
+	for _, r := range registrations {
+		if r.MatchHost != nil && r.MatchHost(host) {
+			return r.New(host, opts), nil
+		}
+	}
+
+	doc, err := fetchDocument(targetUrl, opts.Page)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range registrations {
+		if r.MatchPage != nil && r.MatchPage(doc) {
+			return r.New(host, opts), nil
+		}
+	}
+	for _, r := range registrations {
+		if r.Fallback {
+			return r.New(host, opts), nil
+		}
+	}
+
+	return nil, fmt.Errorf("no scraper is available for %s", host)
+}
+
+// fetchDocument loads the page, the HTML of a supplied page is used instead of requesting it
+func fetchDocument(targetUrl string, page *Page, cookies ...*http.Cookie) (*goquery.Document, error) {
+	req, err := http.NewRequest(http.MethodGet, targetUrl, nil)
+	if err != nil {
+		return nil, err
+	}
+	for _, cookie := range cookies {
+		req.AddCookie(cookie)
+	}
+	client := Client
+	if page != nil {
+		client = &http.Client{Transport: page.transport()}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching %s failed with status %s", targetUrl, resp.Status)
+	}
+	doc, err := goquery.NewDocumentFromReader(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	// Keep the URL after redirects to resolve relative links
+	doc.Url = resp.Request.URL
+	return doc, nil
+}
@@ -1,5 +1,15 @@
 package scrape
 
-type scraper interface {
-	Scrape(targetUrl string) error
+import "errors"
+
+// ErrPaywall is returned by Scrape if not even a preview of the article can be read with the configured session.
+// Previews of paywalled articles are returned as books with CompletenessPreview.
+var ErrPaywall = errors.New("the article is behind a paywall and not accessible")
+
+// Scraper turns the article behind a URL into a Book
+type Scraper interface {
+	// Scrape fetches the article and writes it as an epub to the output directory
+	Scrape(url *string) (*Book, error)
+	// CheckPaywallAccessible returns whether the full article can be read with the configured session
+	CheckPaywallAccessible(targetUrl string) (bool, error)
 }
@@ -6,28 +6,11 @@ import (
 	"github.com/PuerkitoBio/goquery"
 	"github.com/go-shiori/go-epub"
 	"github.com/gocolly/colly/v2"
-	"github.com/google/uuid"
-	"golang.org/x/text/runes"
-	"golang.org/x/text/transform"
-	"golang.org/x/text/unicode/norm"
-	"google.golang.org/appengine/log"
-	"kindExport/internal/config"
-	"net/http"
 	"net/url"
-	"os"
 	"strings"
 	"time"
-	"unicode"
 )
 
-type Book struct {
-	Book        *epub.Epub
-	Path        *string
-	Permalink   *string
-	Paid        bool
-	ReleaseDate time.Time
-}
-
 type ArticleEmbeddedImage struct {
 	URL string `json:"url"`
 }
@@ -47,70 +30,64 @@ type ArticleSchema struct {
 	Free        bool                    `json:"isAccessibleForFree"`
 	Author      []ArticleEmbeddedAuthor `json:"author"`
 	Publisher   ArticleEmbeddedAuthor   `json:"publisher"`
+	WordCount   json.Number             `json:"wordCount"`
 }
 
 type SubstackScraper struct {
 	SubstackLoginCookie *string
+	Progress            ProgressFunc
+	Page                *Page
 }
 
-func generateUUID(filename string) string {
-	imgUUID := uuid.New().String()
-	// Get file ending from src
-	if strings.Contains(filename, ".") {
-		splitted := strings.Split(filename, ".")
-		ending := splitted[len(splitted)-1]
-		imgUUID = fmt.Sprintf("%s.%s", imgUUID, ending)
-	} else {
-		imgUUID = fmt.Sprintf("%s.jpg", imgUUID)
-	}
-	return imgUUID
+func init() {
+	Register(Registration{
+		Name: "substack",
+		MatchHost: func(host string) bool {
+			return host == "substack.com" || strings.HasSuffix(host, ".substack.com")
+		},
+		MatchPage: func(doc *goquery.Document) bool {
+			// Publications on a custom domain still load their assets from the substack CDN
+			return doc.Find("link[href*=\"substackcdn.com\"], script[src*=\"substackcdn.com\"]").Length() > 0
+		},
+		New: func(host string, opts Options) Scraper {
+			// Publications on a custom domain may need a session of their own, the substack.com session is used otherwise
+			cookie := opts.session(PlatformSubstack, host)
+			if cookie == nil {
+				cookie = opts.SubstackSession
+			}
+			return SubstackScraper{SubstackLoginCookie: cookie, Progress: opts.Progress, Page: opts.Page}
+		},
+	})
 }
 
+// setCookies sets the session on the host of the URL, it may be a plain connect.sid value or a Cookie header
 func (s SubstackScraper) setCookies(c *colly.Collector, targetUrl string) {
-	if s.SubstackLoginCookie == nil {
+	cookies := ParseCookies(s.SubstackLoginCookie, "connect.sid")
+	if len(cookies) == 0 {
 		return
 	}
 	val, _ := url.Parse(targetUrl)
 	if val != nil {
-		c.SetCookies(val.Scheme+"://"+val.Host, []*http.Cookie{{
-			Name:  "connect.sid",
-			Value: *s.SubstackLoginCookie,
-		}})
+		c.SetCookies(val.Scheme+"://"+val.Host, cookies)
 	}
 }
 
-func normalizeStr(str string) string {
-	// We want to have a lowercase string with space replaced by - and all special characters removed
-	t := transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
-	str, _, _ = transform.String(t, str)
-	str = strings.ToLower(str)
-	str = strings.ReplaceAll(str, " ", "-")
-	str = strings.Map(func(r rune) rune {
-		if r >= 'a' && r <= 'z' {
-			return r
-		}
-		if r >= '0' && r <= '9' {
-			return r
-		}
-		if r == '-' || r == '_' {
-			return r
-		}
-		return -1
-	}, str)
-	return str
+// newCollector creates a collector with the session of the user, a supplied page is served instead of requesting it
+func (s SubstackScraper) newCollector(targetUrl string) *colly.Collector {
+	c := colly.NewCollector()
+	c.WithTransport(Transport)
+	if s.Page != nil {
+		c.WithTransport(s.Page.transport())
+	}
+	s.setCookies(c, targetUrl)
+	return c
 }
 
 func (s SubstackScraper) CheckPaywallAccessible(targetUrl string) (bool, error) {
 	// We want to scrape the URL and check for paywall newsletters
 	// whether they are accessible by the scraper or not by checking for the paywall-title class
 
-	c := colly.NewCollector()
-	s.setCookies(c, targetUrl)
-
-	c.OnRequest(func(r *colly.Request) {
-		_ = c
-		fmt.Println("Visiting", r.URL)
-	})
+	c := s.newCollector(targetUrl)
 
 	paywallAccessible := true
 
@@ -133,19 +110,16 @@ func (s SubstackScraper) Scrape(url *string) (*Book, error) {
 	// We want to scrape the URL and create an EPUB file from it
 	// First, we get the HTML content of the URL
 
-	book, err := epub.NewEpub("Placeholder")
+	book, err := newBook("Placeholder", s.Progress)
 	article := ArticleSchema{}
 
 	permalink := *url
 
-	c := colly.NewCollector()
-	s.setCookies(c, *url)
+	c := s.newCollector(*url)
 
-	c.OnRequest(func(r *colly.Request) {
-		fmt.Println("Visiting", r.URL)
-	})
-
-	sectionFound := false
+	// content is the element containing the article, its HTML is taken before the page is checked for the paywall
+	var content *goquery.Selection
+	var contentHTML string
 	releaseDate := time.Now()
 
 	// Next, we parse the HTML content
@@ -160,7 +134,6 @@ func (s SubstackScraper) Scrape(url *string) (*Book, error) {
 	// - Paid (optional)
 
 	c.OnHTML("script[type=\"application/ld+json\"]", func(e *colly.HTMLElement) {
-		fmt.Println("Found ld-json")
 		// Parse the JSON content
 		err := json.Unmarshal([]byte(e.Text), &article)
 		if err != nil {
@@ -190,18 +163,8 @@ func (s SubstackScraper) Scrape(url *string) (*Book, error) {
 			selection.Remove()
 		})
 
-		e.DOM.Find("img").Each(func(i int, selection *goquery.Selection) {
-			imgSrc, _ := selection.Attr("src")
-			// Get file ending from src
-			image, err := book.AddImage(imgSrc, generateUUID(imgSrc))
-			if err != nil {
-				return
-			}
-			selection.ReplaceWithHtml(fmt.Sprintf("<img src=\"%s\" alt=\"placeholder\"/>", image))
-			selection.SetAttr("src", image)
-			// Remove all attributes except src and alt
-		})
-		content, err := e.DOM.Html()
+		embedSubstackImages(book, e.DOM, e.Request.URL)
+		body, err := e.DOM.Html()
 		if err != nil {
 			return
 		}
@@ -212,15 +175,7 @@ func (s SubstackScraper) Scrape(url *string) (*Book, error) {
 		if err != nil {
 			return
 		}
-
-		// We want to format the releasedate for 25th February 2025 to "Feb 25, 2025"
-		content = fmt.Sprintf("<h1>%s</h1><p>By %s<em><br/>Published at %s</em></p><hr/>%s", book.Title(), book.Author(), releaseDate.Format("Jan 02, 2006"), content)
-
-		_, err = book.AddSection(content, book.Title(), fmt.Sprintf("%s.xhtml", normalizeStr(book.Title())), "")
-		if err != nil {
-			return
-		}
-		sectionFound = true
+		content, contentHTML = e.DOM, body
 	})
 
 	paywallAccessible := true
@@ -231,41 +186,56 @@ func (s SubstackScraper) Scrape(url *string) (*Book, error) {
 		}
 	})
 
+	// The whole page is checked for the paywall once the content was found
+	var page *goquery.Selection
+	c.OnHTML("html", func(e *colly.HTMLElement) {
+		page = e.DOM
+	})
+
 	err = c.Visit(*url)
 	if err != nil {
 		return nil, err
 	}
 
-	if !article.Free && !paywallAccessible {
-		return nil, fmt.Errorf("the article is behind a paywall and not accessible")
-	}
-
 	// Check whether everything was parsed correctly
-	if book.Title() == "" || book.Author() == "" || !sectionFound {
+	if content == nil && !article.Free && !paywallAccessible {
+		return nil, ErrPaywall
+	}
+	if book.Title() == "" || book.Author() == "" || content == nil || page == nil {
 		return nil, fmt.Errorf("failed to parse the Substack newsletter correctly")
 	}
 
-	// Create output dir if not already existing
-	conf, _ := config.GetConfig()
-	err = os.MkdirAll(fmt.Sprintf("%s/%s", conf.OutputDirectory, book.Title()), os.ModePerm)
-	if err != nil {
-		log.Errorf(nil, "Failed to create output directory: %s", err.Error())
-		return nil, err
+	completeness := CompletenessPreview
+	if paywallAccessible {
+		wordCount, _ := article.WordCount.Int64()
+		completeness = detectCompleteness(page, content, int(wordCount))
 	}
-
-	// We can now create an EPUB from the parsed HTML content
-	epubPath := fmt.Sprintf("%s/%s.epub", conf.OutputDirectory, book.Title())
-	err = book.Write(epubPath)
-
+	err = addArticleSection(book, contentHTML, releaseDate, completeness)
 	if err != nil {
 		return nil, err
 	}
 
-	return &Book{
-		Book:        book,
-		Path:        &epubPath,
-		Permalink:   &permalink,
-		Paid:        !article.Free,
-		ReleaseDate: releaseDate,
-	}, nil
+	// Articles that are cut off are paid, even if the page does not say so
+	paid := !article.Free || completeness == CompletenessPreview
+	return writeBook(book, permalink, paid, completeness, releaseDate, s.Page != nil)
+}
+
+// embedSubstackImages adds the images of the content to the book, the sources are resolved relative to base.
+// Images that cannot be added or are not taken from the web are dropped.
+func embedSubstackImages(book *epub.Epub, content *goquery.Selection, base *url.URL) {
+	content.Find("img").Each(func(i int, selection *goquery.Selection) {
+		imgSrc, _ := selection.Attr("src")
+		imgSrc = resolveURL(base, imgSrc)
+		if imgSrc == "" || !isWebImage(imgSrc) {
+			selection.Remove()
+			return
+		}
+		image, err := book.AddImage(imgSrc, generateUUID(imageFilename(imgSrc)))
+		if err != nil {
+			selection.Remove()
+			return
+		}
+		// Remove all attributes except src and alt
+		selection.ReplaceWithHtml(fmt.Sprintf("<img src=\"%s\" alt=\"placeholder\"/>", image))
+	})
 }
@@ -0,0 +1,116 @@
+package scrape
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image"
+	"image/png"
+	"kindExport/internal/ebook"
+	"os"
+	"path"
+	"strings"
+	"testing"
+)
+
+// TestMain writes the books of all tests to a temporary directory, the configuration is only read once
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "scrape")
+	if err != nil {
+		panic(err)
+	}
+	os.Setenv("OUTPUT_DIRECTORY", dir)
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// substackPage returns a free Substack post whose content contains the given images
+func substackPage(images string) string {
+	return fmt.Sprintf(`<html><head><title>Local news</title>
+<script type="application/ld+json">{"@type":"NewsArticle","headline":"Local news","url":"https://janedoe.substack.invalid/p/local-news","description":"One year later","datePublished":"2026-10-06T13:02:11.000Z","isAccessibleForFree":true,"author":[{"name":"Jane Doe"}],"publisher":{"name":"The Weekly Letter"}}</script>
+</head><body><article><div class="available-content"><div class="body markup">
+<p>The town paper closed a year ago. This is what happened since.</p>
+<div class="pencraft"><button>Subscribe</button></div>
+<picture><source type="image/webp" srcset="https://substackcdn.com/image/fetch/w_424/photo.webp"/>%s</picture>
+<p>Thanks for reading.</p>
+</div></div></article></body></html>`, images)
+}
+
+// pngDataURL returns a data URL of a small PNG image
+func pngDataURL(t *testing.T) string {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, 2, 2))); err != nil {
+		t.Fatalf("encoding the image failed: %s", err)
+	}
+	return "data:image/png;base64," + base64.StdEncoding.EncodeToString(buf.Bytes())
+}
+
+func TestSubstackScrapeSuppliedPage(t *testing.T) {
+	targetURL := "https://janedoe.substack.invalid/p/local-news"
+	images := fmt.Sprintf(`<img src="%s" alt="Main street" width="1456" srcset="https://substackcdn.com/image/fetch/w_424/photo.png 424w"/>`, pngDataURL(t))
+	scraper := SubstackScraper{Page: &Page{URL: targetURL, HTML: substackPage(images)}}
+
+	book, err := scraper.Scrape(&targetURL)
+	if err != nil {
+		t.Fatalf("Scrape failed: %s", err)
+	}
+	if book.Completeness != CompletenessFull || book.Paid {
+		t.Errorf("book is %s and paid %v, want the full free post", book.Completeness, book.Paid)
+	}
+	document, err := ebook.Read(*book.Path)
+	if err != nil {
+		t.Fatalf("reading the book failed: %s", err)
+	}
+	if document.Title != "Local news" || document.Author != "The Weekly Letter - Jane Doe" {
+		t.Errorf("book is %q by %q", document.Title, document.Author)
+	}
+	if len(document.Sections) != 1 {
+		t.Fatalf("book has %d sections, want 1", len(document.Sections))
+	}
+	body := document.Sections[0].Body
+	for _, text := range []string{"The town paper closed a year ago.", "Thanks for reading.", "Published at Oct 06, 2026"} {
+		if !strings.Contains(body, text) {
+			t.Errorf("body does not contain %q", text)
+		}
+	}
+	for _, text := range []string{"Subscribe", "<source", "srcset", "Main street"} {
+		if strings.Contains(body, text) {
+			t.Errorf("body still contains %q", text)
+		}
+	}
+
+	// The image is stored in the book and referenced without its other attributes
+	if len(document.Images) != 1 {
+		t.Fatalf("book has %d images, want 1", len(document.Images))
+	}
+	for imagePath := range document.Images {
+		img := fmt.Sprintf(`<img src="../images/%s" alt="placeholder"`, path.Base(imagePath))
+		if !strings.Contains(body, img) {
+			t.Errorf("body does not reference the image %s", imagePath)
+		}
+	}
+}
+
+func TestSubstackScrapeDropsLocalImages(t *testing.T) {
+	targetURL := "https://janedoe.substack.invalid/p/local-news"
+	images := fmt.Sprintf(`<img src="%s"/><img src="/etc/passwd"/><img src="file:///etc/hostname"/>`, pngDataURL(t))
+	scraper := SubstackScraper{Page: &Page{URL: targetURL, HTML: substackPage(images)}}
+
+	book, err := scraper.Scrape(&targetURL)
+	if err != nil {
+		t.Fatalf("Scrape failed: %s", err)
+	}
+	document, err := ebook.Read(*book.Path)
+	if err != nil {
+		t.Fatalf("reading the book failed: %s", err)
+	}
+	// Only the embedded image is kept, the files of the server are never read
+	if len(document.Images) != 1 {
+		t.Errorf("book has %d images, want 1", len(document.Images))
+	}
+	if strings.Contains(document.Sections[0].Body, "/etc/") {
+		t.Errorf("body still references a local file")
+	}
+}
@@ -0,0 +1,122 @@
+package scrape
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html/charset"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// ErrUnsupportedUpload is returned for uploaded files that are neither HTML nor MHTML
+var ErrUnsupportedUpload = errors.New("only saved HTML and MHTML pages are supported")
+
+// savedFrom is the comment browsers add to pages saved as HTML, e.g. <!-- saved from url=(0031)https://example.com/p/article -->
+var savedFrom = regexp.MustCompile(`<!-- saved from url=\(\d+\)(\S+?) -->`)
+
+// ParseUpload reads a page the user saved in the browser.
+// The URL of the page is taken from the file if possible, it is empty otherwise.
+func ParseUpload(filename string, data []byte) (*Page, error) {
+	var page *Page
+	var err error
+	switch strings.ToLower(path.Ext(filename)) {
+	case ".mhtml", ".mht":
+		page, err = parseMHTML(data)
+	case ".html", ".htm", ".xhtml":
+		page, err = parseHTML(data, "")
+	default:
+		return nil, ErrUnsupportedUpload
+	}
+	if err != nil {
+		return nil, err
+	}
+	if page.URL == "" {
+		page.URL = pageURL(page.HTML)
+	}
+	return page, nil
+}
+
+// parseHTML decodes the page to UTF-8, the charset is detected from the content type or the page itself
+func parseHTML(data []byte, contentType string) (*Page, error) {
+	reader, err := charset.NewReader(bytes.NewReader(data), contentType)
+	if err != nil {
+		return nil, err
+	}
+	content, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+	return &Page{HTML: string(content)}, nil
+}
+
+// parseMHTML returns the HTML part of a web archive, which is a multipart MIME message
+func parseMHTML(data []byte) (*Page, error) {
+	message, err := mail.ReadMessage(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("reading MHTML: %w", err)
+	}
+	mediaType, params, err := mime.ParseMediaType(message.Header.Get("Content-Type"))
+	if err != nil || !strings.HasPrefix(mediaType, "multipart/") {
+		return nil, errors.New("the MHTML file is not a multipart archive")
+	}
+
+	parts := multipart.NewReader(message.Body, params["boundary"])
+	for {
+		part, err := parts.NextPart()
+		if err == io.EOF {
+			return nil, errors.New("the MHTML file does not contain an HTML page")
+		}
+		if err != nil {
+			return nil, fmt.Errorf("reading MHTML: %w", err)
+		}
+		contentType := part.Header.Get("Content-Type")
+		if partType, _, _ := mime.ParseMediaType(contentType); partType != "text/html" {
+			continue
+		}
+
+		// Quoted-printable is decoded by the multipart reader
+		var body io.Reader = part
+		if strings.EqualFold(part.Header.Get("Content-Transfer-Encoding"), "base64") {
+			body = base64.NewDecoder(base64.StdEncoding, part)
+		}
+		content, err := io.ReadAll(body)
+		if err != nil {
+			return nil, fmt.Errorf("reading MHTML: %w", err)
+		}
+		page, err := parseHTML(content, contentType)
+		if err != nil {
+			return nil, err
+		}
+		page.URL = message.Header.Get("Snapshot-Content-Location")
+		if page.URL == "" {
+			page.URL = part.Header.Get("Content-Location")
+		}
+		return page, nil
+	}
+}
+
+// pageURL finds the address of a saved page in its canonical link, its metadata or the comment of the browser
+func pageURL(pageHTML string) string {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(pageHTML))
+	if err == nil {
+		for _, selector := range []string{`link[rel="canonical"]`, `meta[property="og:url"]`} {
+			selection := doc.Find(selector).First()
+			value := selection.AttrOr("href", selection.AttrOr("content", ""))
+			if strings.HasPrefix(value, "http") {
+				return value
+			}
+		}
+	}
+	if match := savedFrom.FindStringSubmatch(pageHTML); match != nil {
+		return match[1]
+	}
+	return ""
+}
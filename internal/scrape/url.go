@@ -0,0 +1,48 @@
+package scrape
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// NormalizeURL removes the query and fragment of an URL to use it as key for articles
+func NormalizeURL(urlValue string) string {
+	u, err := url.Parse(urlValue)
+	if err != nil {
+		return urlValue
+	}
+
+	// Return the url without the query while normalizing the scheme to https
+	// And remove trailing slash
+
+	u.Scheme = "https"
+	u.RawQuery = ""
+	u.Fragment = ""
+	u.Path = strings.TrimSuffix(u.Path, "/")
+	return u.String()
+}
+
+// NormalizeDomain returns the host of a publication, which may be given as URL or plain domain
+func NormalizeDomain(value string) string {
+	value = strings.TrimSpace(strings.ToLower(value))
+	if !strings.Contains(value, "://") {
+		value = "https://" + value
+	}
+	u, err := url.Parse(value)
+	if err != nil {
+		return ""
+	}
+	return u.Hostname()
+}
+
+// ResolveRedirects returns the URL the given URL redirects to
+func ResolveRedirects(urlValue string) (string, error) {
+	client := http.Client{Transport: Transport}
+	resp, err := client.Get(urlValue)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	return resp.Request.URL.String(), nil
+}
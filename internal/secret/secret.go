@@ -0,0 +1,161 @@
+package secret
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"kindExport/internal/config"
+	"strings"
+)
+
+// Stored values have the format enc:v1:<key id>:<wrapped data key>:<ciphertext>.
+// Every value is encrypted with its own random data key, only the data key is encrypted with the
+// configured key. Rotating the configured key therefore only has to re-encrypt the data keys.
+const prefix = "enc:v1:"
+
+// IsEncrypted reports whether the stored value was produced by Encrypt
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, prefix)
+}
+
+// Encrypt encrypts the value with the key from the configuration
+func Encrypt(plaintext string) (string, error) {
+	conf, err := config.GetStorageConfig()
+	if err != nil {
+		return "", err
+	}
+	return EncryptWithKey(conf.EncryptionKey, plaintext)
+}
+
+// Decrypt decrypts a value created by Encrypt with the key from the configuration
+func Decrypt(value string) (string, error) {
+	conf, err := config.GetStorageConfig()
+	if err != nil {
+		return "", err
+	}
+	return DecryptWithKey(conf.EncryptionKey, value)
+}
+
+// EncryptWithKey encrypts the value with a new data key, which is wrapped with the given key
+func EncryptWithKey(key []byte, plaintext string) (string, error) {
+	dataKey := make([]byte, 32)
+	if _, err := rand.Read(dataKey); err != nil {
+		return "", err
+	}
+	wrappedKey, err := seal(key, dataKey)
+	if err != nil {
+		return "", err
+	}
+	ciphertext, err := seal(dataKey, []byte(plaintext))
+	if err != nil {
+		return "", err
+	}
+	return format(key, wrappedKey, ciphertext), nil
+}
+
+// DecryptWithKey decrypts a value created by EncryptWithKey
+func DecryptWithKey(key []byte, value string) (string, error) {
+	dataKey, ciphertext, err := unwrap(key, value)
+	if err != nil {
+		return "", err
+	}
+	plaintext, err := open(dataKey, ciphertext)
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
+
+// Rewrap re-encrypts the data key of the value with a new key, the encrypted content stays untouched
+func Rewrap(oldKey []byte, newKey []byte, value string) (string, error) {
+	dataKey, ciphertext, err := unwrap(oldKey, value)
+	if err != nil {
+		return "", err
+	}
+	wrappedKey, err := seal(newKey, dataKey)
+	if err != nil {
+		return "", err
+	}
+	return format(newKey, wrappedKey, ciphertext), nil
+}
+
+// KeyID identifies a key without revealing it, so values encrypted with another key are recognized
+func KeyID(key []byte) string {
+	sum := sha256.Sum256(key)
+	return hex.EncodeToString(sum[:4])
+}
+
+func format(key []byte, wrappedKey []byte, ciphertext []byte) string {
+	return prefix + KeyID(key) + ":" +
+		base64.RawStdEncoding.EncodeToString(wrappedKey) + ":" +
+		base64.RawStdEncoding.EncodeToString(ciphertext)
+}
+
+// unwrap decrypts the data key of the value and returns it together with the encrypted content
+func unwrap(key []byte, value string) ([]byte, []byte, error) {
+	if !IsEncrypted(value) {
+		return nil, nil, errors.New("the value is not encrypted")
+	}
+	parts := strings.Split(strings.TrimPrefix(value, prefix), ":")
+	if len(parts) != 3 {
+		return nil, nil, errors.New("the encrypted value is malformed")
+	}
+	if parts[0] != KeyID(key) {
+		return nil, nil, fmt.Errorf("the value was encrypted with key %s, but key %s is configured", parts[0], KeyID(key))
+	}
+	wrappedKey, err := base64.RawStdEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, nil, errors.New("the encrypted value is malformed")
+	}
+	ciphertext, err := base64.RawStdEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, nil, errors.New("the encrypted value is malformed")
+	}
+	dataKey, err := open(key, wrappedKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	return dataKey, ciphertext, nil
+}
+
+// seal encrypts the plaintext with AES-GCM and prepends the random nonce
+func seal(key []byte, plaintext []byte) ([]byte, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+// open decrypts the output of seal
+func open(key []byte, sealed []byte) ([]byte, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < aead.NonceSize() {
+		return nil, errors.New("the encrypted value is malformed")
+	}
+	plaintext, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], nil)
+	if err != nil {
+		return nil, errors.New("the encrypted value could not be decrypted")
+	}
+	return plaintext, nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,185 @@
+package substack
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"kindExport/internal/scrape"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ErrSessionExpired is returned if Substack does not accept the session cookie (anymore)
+var ErrSessionExpired = errors.New("the session cookie is not valid or has expired")
+
+// paidMembershipStates are the membership states of subscriptions that unlock paid posts
+var paidMembershipStates = map[string]bool{
+	"subscribed": true,
+	"founding":   true,
+	"gift":       true,
+	"comp":       true,
+}
+
+// Profile is the Substack account a session belongs to
+type Profile struct {
+	ID     int64  `json:"id"`
+	Name   string `json:"name"`
+	Handle string `json:"handle"`
+}
+
+// Publication is a newsletter the account is subscribed to
+type Publication struct {
+	Name   string
+	Domain string
+	Paid   bool
+}
+
+// Account is the result of validating a session cookie
+type Account struct {
+	Profile      Profile
+	Publications []Publication
+}
+
+type subscriptionsResponse struct {
+	Publications []struct {
+		ID           int64  `json:"id"`
+		Name         string `json:"name"`
+		Subdomain    string `json:"subdomain"`
+		CustomDomain string `json:"custom_domain"`
+	} `json:"publications"`
+	Subscriptions []struct {
+		PublicationID   int64  `json:"publication_id"`
+		MembershipState string `json:"membership_state"`
+	} `json:"subscriptions"`
+}
+
+// GetProfile returns the account of the session cookie
+func GetProfile(cookie string) (*Profile, error) {
+	var profile Profile
+	err := get(cookie, "/api/v1/user/profile/self", &profile)
+	if err != nil {
+		return nil, err
+	}
+	return &profile, nil
+}
+
+// GetPublications returns the publications the account of the session cookie is subscribed to
+func GetPublications(cookie string) ([]Publication, error) {
+	var body subscriptionsResponse
+	err := get(cookie, "/api/v1/subscriptions", &body)
+	if err != nil {
+		return nil, err
+	}
+
+	paid := map[int64]bool{}
+	for _, subscription := range body.Subscriptions {
+		paid[subscription.PublicationID] = paidMembershipStates[subscription.MembershipState]
+	}
+	publications := make([]Publication, 0, len(body.Publications))
+	for _, publication := range body.Publications {
+		domain := publication.CustomDomain
+		if domain == "" {
+			domain = publication.Subdomain + ".substack.com"
+		}
+		publications = append(publications, Publication{
+			Name:   publication.Name,
+			Domain: domain,
+			Paid:   paid[publication.ID],
+		})
+	}
+	return publications, nil
+}
+
+// Validate checks the session cookie with Substack and returns the account and subscriptions it unlocks
+func Validate(cookie string) (*Account, error) {
+	profile, err := GetProfile(cookie)
+	if err != nil {
+		return nil, err
+	}
+	publications, err := GetPublications(cookie)
+	if err != nil {
+		return nil, err
+	}
+	return &Account{Profile: *profile, Publications: publications}, nil
+}
+
+// PaidPublications returns the names of the publications with a paid subscription
+func (a *Account) PaidPublications() []string {
+	var names []string
+	for _, publication := range a.Publications {
+		if publication.Paid {
+			names = append(names, publication.Name)
+		}
+	}
+	return names
+}
+
+// Summary describes the account and the paid subscriptions it unlocks
+func (a *Account) Summary() string {
+	summary := "Substack account " + a.Profile.Name
+	if a.Profile.Handle != "" {
+		summary += " (@" + a.Profile.Handle + ")"
+	}
+	paid := a.PaidPublications()
+	if len(paid) == 0 {
+		return summary + ", without paid subscriptions"
+	}
+	return fmt.Sprintf("%s, unlocks %d paid subscriptions: %s", summary, len(paid), strings.Join(paid, ", "))
+}
+
+// ValidatePublication checks the session cookie of a publication on a custom domain with the publication
+// itself, as those sessions are not accepted by substack.com
+func ValidatePublication(domain string, cookie string) (*Profile, error) {
+	var profile Profile
+	err := getFrom("https://"+domain, cookie, "/api/v1/user/profile/self", &profile)
+	if err != nil {
+		return nil, err
+	}
+	return &profile, nil
+}
+
+// get queries the API with the session cookie and decodes the JSON response
+func get(cookie string, path string, v any) error {
+	return getFrom(baseURL, cookie, path, v)
+}
+
+// getFrom queries the API served by origin with the session cookie and decodes the JSON response
+func getFrom(origin string, cookie string, path string, v any) error {
+	req, err := http.NewRequest(http.MethodGet, origin+path, nil)
+	if err != nil {
+		return err
+	}
+	for _, c := range requestCookies(cookie) {
+		req.AddCookie(c)
+	}
+
+	// Publications on a custom domain are named by the user
+	client := &http.Client{Transport: scrape.Transport, Timeout: 30 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		return ErrSessionExpired
+	}
+	if resp.StatusCode != http.StatusOK {
+		return responseError(resp)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// requestCookies returns the cookies of a stored session, which is either a complete Cookie header
+// or the plain value of the session cookie
+func requestCookies(cookie string) []*http.Cookie {
+	if cookies := scrape.ParseCookies(&cookie, ""); len(cookies) > 0 {
+		return cookies
+	}
+	// A plain value is sent under every name Substack used for the session cookie
+	cookies := make([]*http.Cookie, 0, len(sessionCookies))
+	for _, name := range sessionCookies {
+		cookies = append(cookies, &http.Cookie{Name: name, Value: strings.TrimSpace(cookie)})
+	}
+	return cookies
+}
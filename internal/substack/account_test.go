@@ -0,0 +1,34 @@
+package substack
+
+import (
+	"testing"
+)
+
+func TestRequestCookies(t *testing.T) {
+	tests := []struct {
+		name   string
+		cookie string
+		want   map[string]string
+	}{
+		{name: "plain value", cookie: "s%3Aabc.def", want: map[string]string{"connect.sid": "s%3Aabc.def", "substack.sid": "s%3Aabc.def"}},
+		{name: "cookie header", cookie: "substack.sid=s%3Aabc.def; ab_testing_id=42", want: map[string]string{"substack.sid": "s%3Aabc.def", "ab_testing_id": "42"}},
+		{name: "single cookie", cookie: " connect.sid=s%3Axyz ", want: map[string]string{"connect.sid": "s%3Axyz"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cookies := requestCookies(test.cookie)
+			got := map[string]string{}
+			for _, cookie := range cookies {
+				got[cookie.Name] = cookie.Value
+			}
+			if len(got) != len(test.want) {
+				t.Fatalf("requestCookies(%q) = %v, want %v", test.cookie, got, test.want)
+			}
+			for name, value := range test.want {
+				if got[name] != value {
+					t.Errorf("cookie %s = %q, want %q", name, got[name], value)
+				}
+			}
+		})
+	}
+}
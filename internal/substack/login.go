@@ -0,0 +1,176 @@
+package substack
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// baseURL is the address of the Substack API, sessions of substack.com are valid for all publications
+const baseURL = "https://substack.com"
+
+// sessionCookies are the names Substack uses for the session cookie, the first one is preferred
+var sessionCookies = []string{"connect.sid", "substack.sid"}
+
+var (
+	// ErrInvalidCredentials is returned if Substack rejected the email address or password
+	ErrInvalidCredentials = errors.New("the email address or password is wrong")
+	// ErrInvalidLink is returned for login links that do not lead to Substack
+	ErrInvalidLink = errors.New("the link is not a Substack login link")
+	// ErrNoSession is returned if Substack answered without a session cookie
+	ErrNoSession = errors.New("substack did not return a session")
+)
+
+// Session is the result of a successful login
+type Session struct {
+	// Cookie is the value of the session cookie
+	Cookie string
+	// Username is the handle of the Substack account, empty if it could not be determined
+	Username string
+}
+
+type apiError struct {
+	Error  string `json:"error"`
+	Errors []struct {
+		Msg string `json:"msg"`
+	} `json:"errors"`
+}
+
+// Login signs in with email address and password
+func Login(email string, password string) (*Session, error) {
+	client := newClient()
+	resp, err := post(client, "/api/v1/login", map[string]any{
+		"email":            email,
+		"password":         password,
+		"captcha_response": nil,
+		"for_pub":          "",
+		"redirect":         "/",
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusBadRequest {
+		return nil, ErrInvalidCredentials
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, responseError(resp)
+	}
+	return newSession(client)
+}
+
+// RequestLoginLink makes Substack send a mail with a login link to the address
+func RequestLoginLink(email string) error {
+	resp, err := post(newClient(), "/api/v1/email-login", map[string]any{
+		"email":           email,
+		"for_pub":         "",
+		"redirect":        "/",
+		"can_create_user": false,
+	})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return responseError(resp)
+	}
+	return nil
+}
+
+// ConfirmLoginLink opens the login link of the mail and returns the session it creates.
+// Only links to Substack are followed, including the tracking links of its mails.
+func ConfirmLoginLink(link string) (*Session, error) {
+	u, err := url.Parse(strings.TrimSpace(link))
+	if err != nil || u.Scheme != "https" || !isSubstackHost(u.Hostname()) {
+		return nil, ErrInvalidLink
+	}
+
+	client := newClient()
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if len(via) >= 10 {
+			return errors.New("too many redirects")
+		}
+		// The session is set by Substack, redirects to publications afterwards are not needed
+		if !isSubstackHost(req.URL.Hostname()) {
+			return http.ErrUseLastResponse
+		}
+		return nil
+	}
+	resp, err := client.Get(u.String())
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+	return newSession(client)
+}
+
+func isSubstackHost(host string) bool {
+	host = strings.ToLower(host)
+	return host == "substack.com" || strings.HasSuffix(host, ".substack.com")
+}
+
+func newClient() *http.Client {
+	jar, _ := cookiejar.New(nil)
+	return &http.Client{Jar: jar, Timeout: 30 * time.Second}
+}
+
+func post(client *http.Client, path string, body any) (*http.Response, error) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest(http.MethodPost, baseURL+path, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return client.Do(req)
+}
+
+// responseError reads the error message of a failed request
+func responseError(resp *http.Response) error {
+	var body apiError
+	if err := json.NewDecoder(resp.Body).Decode(&body); err == nil {
+		if body.Error != "" {
+			return fmt.Errorf("substack: %s", body.Error)
+		}
+		if len(body.Errors) > 0 {
+			return fmt.Errorf("substack: %s", body.Errors[0].Msg)
+		}
+	}
+	return fmt.Errorf("substack answered with status %s", resp.Status)
+}
+
+// newSession takes the session cookie from the client and looks up the account it belongs to
+func newSession(client *http.Client) (*Session, error) {
+	u, _ := url.Parse(baseURL)
+	cookies := map[string]string{}
+	for _, cookie := range client.Jar.Cookies(u) {
+		cookies[cookie.Name] = cookie.Value
+	}
+	session := &Session{}
+	for _, name := range sessionCookies {
+		if cookies[name] != "" {
+			session.Cookie = cookies[name]
+			break
+		}
+	}
+	if session.Cookie == "" {
+		return nil, ErrNoSession
+	}
+
+	profile, err := GetProfile(session.Cookie)
+	if err != nil {
+		log.Printf("Error querying Substack profile: %s", err.Error())
+	} else {
+		session.Username = profile.Handle
+	}
+	return session, nil
+}
@@ -0,0 +1,116 @@
+package substack
+
+import (
+	"errors"
+	"kindExport/generated/model"
+	"kindExport/internal/db"
+	"kindExport/internal/secret"
+	"log"
+	"time"
+)
+
+// checkInterval is the time between two checks for sessions that are due for validation
+const checkInterval = time.Hour
+
+// Monitor validates the stored Substack sessions periodically and notifies users once theirs expired
+type Monitor struct {
+	interval time.Duration
+	notify   func(discordID string, message string) error
+}
+
+func NewMonitor(interval time.Duration, notify func(discordID string, message string) error) *Monitor {
+	return &Monitor{interval: interval, notify: notify}
+}
+
+// Run validates the sessions that were not validated within the interval, it never returns
+func (m *Monitor) Run() {
+	ticker := time.NewTicker(checkInterval)
+	defer ticker.Stop()
+	for {
+		m.checkDue()
+		<-ticker.C
+	}
+}
+
+func (m *Monitor) checkDue() {
+	users, err := db.GetUsersWithSubstackSession()
+	if err != nil {
+		log.Printf("Error querying Substack sessions: %s", err.Error())
+		return
+	}
+	for _, user := range users {
+		if user.SubstackValidatedAt != nil && time.Since(*user.SubstackValidatedAt) < m.interval {
+			continue
+		}
+		err = m.check(user)
+		if err != nil {
+			log.Printf("Error validating Substack session of user %d: %s", *user.ID, err.Error())
+		}
+	}
+
+	sessions, err := db.GetSubstackPublicationSessions()
+	if err != nil {
+		log.Printf("Error querying Substack publication sessions: %s", err.Error())
+		return
+	}
+	for _, session := range sessions {
+		if session.ValidatedAt != nil && time.Since(*session.ValidatedAt) < m.interval {
+			continue
+		}
+		err = m.checkPublication(session)
+		if err != nil {
+			log.Printf("Error validating Substack session of user %d for %s: %s", session.UserID, session.Domain, err.Error())
+		}
+	}
+}
+
+func (m *Monitor) check(user model.Users) error {
+	cookie, err := secret.Decrypt(*user.SubstackSession)
+	if err != nil {
+		return err
+	}
+	profile, err := GetProfile(cookie)
+	if errors.Is(err, ErrSessionExpired) {
+		err = db.MarkSubstackSessionExpired(*user.ID)
+		if err != nil {
+			return err
+		}
+		m.sendNotification(user, "Your Substack session has expired, paid articles will only be exported as preview or not at all. "+
+			"Log in again with `/login` or set a new session cookie with `/session`.")
+		return nil
+	}
+	if err != nil {
+		// Substack could not be reached, the session is checked again with the next run
+		return err
+	}
+	return db.MarkSubstackSessionValid(*user.ID, profile.Handle)
+}
+
+// checkPublication validates a session stored for a publication on a custom domain
+func (m *Monitor) checkPublication(session db.PublicationSession) error {
+	cookie, err := secret.Decrypt(session.Cookie)
+	if err != nil {
+		return err
+	}
+	_, err = ValidatePublication(session.Domain, cookie)
+	if errors.Is(err, ErrSessionExpired) {
+		err = db.MarkSessionExpired(*session.ID)
+		if err != nil {
+			return err
+		}
+		m.sendNotification(session.User, "Your Substack session for "+session.Domain+" has expired, its paid articles will only be exported as preview or not at all. "+
+			"Set a new session cookie with `/session platform:substack domain:"+session.Domain+"`.")
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return db.MarkSessionValid(*session.ID)
+}
+
+func (m *Monitor) sendNotification(user model.Users, message string) {
+	err := m.notify(user.DiscordID, message)
+	if err != nil {
+		log.Printf("Error notifying user %d: %s", *user.ID, err.Error())
+	}
+}
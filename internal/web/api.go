@@ -0,0 +1,234 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"kindExport/generated/model"
+	"kindExport/internal/db"
+	"kindExport/internal/ebook"
+	"kindExport/internal/export"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// defaultArticleLimit and maxArticleLimit bound the amount of articles returned by a single request
+	defaultArticleLimit = 50
+	maxArticleLimit     = 200
+)
+
+type apiError struct {
+	Error string `json:"error"`
+}
+
+type apiExportRequest struct {
+	URL string `json:"url"`
+}
+
+type apiExport struct {
+	ID        int32     `json:"id"`
+	URL       string    `json:"url"`
+	State     string    `json:"state"`
+	Attempts  int32     `json:"attempts"`
+	Result    *string   `json:"result"`
+	LastError *string   `json:"last_error"`
+	ArticleID *int32    `json:"article_id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type apiArticle struct {
+	ID          int32     `json:"id"`
+	Title       string    `json:"title"`
+	Author      string    `json:"author"`
+	URL         string    `json:"url"`
+	ReleaseDate time.Time `json:"release_date"`
+	Paid        bool      `json:"paid"`
+	// Status is the outcome of the delivery to the user
+	Status string `json:"status"`
+	// Preview is set if the user only received the preview of a paywalled article
+	Preview    bool      `json:"preview"`
+	ExportedAt time.Time `json:"exported_at"`
+}
+
+type apiArticles struct {
+	Total    int64        `json:"total"`
+	Articles []apiArticle `json:"articles"`
+}
+
+func registerAPI(mux *http.ServeMux) {
+	mux.HandleFunc("POST /exports", apiHandler(createExport))
+	mux.HandleFunc("GET /exports/{id}", apiHandler(getExport))
+	mux.HandleFunc("GET /articles", apiHandler(listArticles))
+	mux.HandleFunc("GET /articles/{id}/epub", apiHandler(downloadEpub))
+}
+
+// apiHandler authenticates the request with the bearer token of the user
+func apiHandler(handle func(w http.ResponseWriter, r *http.Request, user *model.Users)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeJSON(w, http.StatusUnauthorized, apiError{"An API token is required, create one with the /apitoken command"})
+			return
+		}
+		user, err := db.GetUserByToken(strings.TrimSpace(token), db.TokenScopeAPI)
+		if err != nil {
+			log.Printf("Error querying token: %s", err.Error())
+			writeJSON(w, http.StatusInternalServerError, apiError{"An internal error occurred"})
+			return
+		}
+		if user == nil {
+			w.Header().Set("WWW-Authenticate", "Bearer error=\"invalid_token\"")
+			writeJSON(w, http.StatusUnauthorized, apiError{"The API token is invalid"})
+			return
+		}
+		handle(w, r, user)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, value any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	err := json.NewEncoder(w).Encode(value)
+	if err != nil {
+		log.Printf("Error encoding response: %s", err.Error())
+	}
+}
+
+// createExport queues the export of an article, it is processed like exports of the /export command
+func createExport(w http.ResponseWriter, r *http.Request, user *model.Users) {
+	var request apiExportRequest
+	err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 64*1024)).Decode(&request)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, apiError{"The body has to be a JSON object with the url of the article"})
+		return
+	}
+	if err := export.ValidateURL(request.URL); err != nil {
+		writeJSON(w, http.StatusBadRequest, apiError{"Invalid URL"})
+		return
+	}
+
+	id, err := export.Enqueue(*user.ID, request.URL, "", "")
+	if err != nil {
+		log.Printf("Error queueing export: %s", err.Error())
+		writeJSON(w, http.StatusInternalServerError, apiError{"An internal error occurred while queueing the export"})
+		return
+	}
+	job, err := db.GetExportJob(id)
+	if err != nil {
+		log.Printf("Error querying export job %d: %s", id, err.Error())
+		writeJSON(w, http.StatusInternalServerError, apiError{"An internal error occurred"})
+		return
+	}
+	w.Header().Set("Location", "/exports/"+strconv.Itoa(int(id)))
+	writeJSON(w, http.StatusAccepted, newAPIExport(*job))
+}
+
+func getExport(w http.ResponseWriter, r *http.Request, user *model.Users) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeJSON(w, http.StatusNotFound, apiError{"Export not found"})
+		return
+	}
+	job, err := db.GetExportJob(int32(id))
+	if err != nil {
+		log.Printf("Error querying export job %d: %s", id, err.Error())
+		writeJSON(w, http.StatusInternalServerError, apiError{"An internal error occurred"})
+		return
+	}
+	// Jobs of other users are not revealed
+	if job == nil || job.UserID != *user.ID {
+		writeJSON(w, http.StatusNotFound, apiError{"Export not found"})
+		return
+	}
+	writeJSON(w, http.StatusOK, newAPIExport(*job))
+}
+
+// listArticles returns the articles exported for the user, newest first
+func listArticles(w http.ResponseWriter, r *http.Request, user *model.Users) {
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil || limit <= 0 {
+		limit = defaultArticleLimit
+	}
+	limit = min(limit, maxArticleLimit)
+	offset, err := strconv.Atoi(r.URL.Query().Get("offset"))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+
+	total, err := db.CountHistory(*user.ID)
+	var entries []db.HistoryEntry
+	if err == nil {
+		entries, err = db.GetHistory(*user.ID, int64(limit), int64(offset))
+	}
+	if err != nil {
+		log.Printf("Error querying history: %s", err.Error())
+		writeJSON(w, http.StatusInternalServerError, apiError{"An internal error occurred"})
+		return
+	}
+
+	response := apiArticles{Total: total, Articles: make([]apiArticle, 0, len(entries))}
+	for _, entry := range entries {
+		response.Articles = append(response.Articles, apiArticle{
+			ID:          *entry.Article.ID,
+			Title:       entry.Article.Title,
+			Author:      entry.Article.Author,
+			URL:         entry.Article.URL,
+			ReleaseDate: entry.Article.ReleaseDate,
+			Paid:        entry.Article.Paid,
+			Status:      entry.Status,
+			Preview:     entry.Preview,
+			ExportedAt:  entry.CreatedAt,
+		})
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+// downloadEpub sends the epub of an article that was exported for the user
+func downloadEpub(w http.ResponseWriter, r *http.Request, user *model.Users) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeJSON(w, http.StatusNotFound, apiError{"Article not found"})
+		return
+	}
+	entry, err := db.GetHistoryEntry(*user.ID, int32(id))
+	if err != nil {
+		log.Printf("Error querying history: %s", err.Error())
+		writeJSON(w, http.StatusInternalServerError, apiError{"An internal error occurred"})
+		return
+	}
+	if entry == nil {
+		writeJSON(w, http.StatusNotFound, apiError{"Article not found"})
+		return
+	}
+
+	epubPath, err := export.HistoryEpub(user, *entry)
+	if errors.Is(err, export.ErrWithheld) {
+		writeJSON(w, http.StatusForbidden, apiError{"The article is behind a paywall your sessions cannot read"})
+		return
+	}
+	if err != nil {
+		log.Printf("Error preparing download of article %d: %s", id, err.Error())
+		writeJSON(w, http.StatusInternalServerError, apiError{"The article could not be prepared for download"})
+		return
+	}
+	serveDownload(w, r, epubPath, downloadTypes[ebook.FormatEPUB])
+}
+
+func newAPIExport(job model.ExportJobs) apiExport {
+	return apiExport{
+		ID:        *job.ID,
+		URL:       job.URL,
+		State:     job.State,
+		Attempts:  job.Attempts,
+		Result:    job.Result,
+		LastError: job.LastError,
+		ArticleID: job.ArticleID,
+		CreatedAt: job.CreatedAt,
+		UpdatedAt: job.UpdatedAt,
+	}
+}
@@ -0,0 +1,415 @@
+package web
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"kindExport/generated/model"
+	"kindExport/internal/config"
+	"kindExport/internal/db"
+	"kindExport/internal/ebook"
+	"kindExport/internal/export"
+	"kindExport/internal/feed"
+	"kindExport/internal/scrape"
+	"kindExport/internal/substack"
+	"log"
+	"net/http"
+	"net/mail"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// sessionCookie contains the dashboard token of a logged in browser
+	sessionCookie = "kindexport_session"
+	// noticeCookie carries the result of a form to the page shown after the redirect
+	noticeCookie = "kindexport_notice"
+	// historyPageSize is the amount of articles on a page of the history
+	historyPageSize = 20
+)
+
+// sessionPlatforms are the platforms a session cookie can be stored for
+var sessionPlatforms = []string{scrape.PlatformSubstack, scrape.PlatformMedium, scrape.PlatformGhost, scrape.PlatformBeehiiv}
+
+// dashboardRequest is a request of a logged in user
+type dashboardRequest struct {
+	w     http.ResponseWriter
+	r     *http.Request
+	user  *model.Users
+	token string
+}
+
+// dashboardPage is the data shown on the dashboard
+type dashboardPage struct {
+	User            *model.Users
+	CSRF            string
+	Notice          string
+	SubstackSession bool
+	Sessions        []model.UserSessions
+	Platforms       []string
+	Subscriptions   []model.Subscriptions
+	History         []db.HistoryEntry
+	Page            int
+	Pages           int
+	Total           int64
+}
+
+func registerDashboard(mux *http.ServeMux) {
+	mux.Handle("GET /dashboard", http.RedirectHandler("/dashboard/", http.StatusMovedPermanently))
+	mux.HandleFunc("GET /dashboard/{$}", dashboardHandler(showDashboard))
+	mux.HandleFunc("GET /dashboard/login/{token}", showLogin)
+	mux.HandleFunc("POST /dashboard/login/{token}", handleLogin)
+	mux.HandleFunc("POST /dashboard/logout", dashboardHandler(handleLogout))
+	mux.HandleFunc("POST /dashboard/mail", dashboardHandler(updateMail))
+	mux.HandleFunc("POST /dashboard/sessions", dashboardHandler(addSession))
+	mux.HandleFunc("POST /dashboard/sessions/{id}/delete", dashboardHandler(removeSession))
+	mux.HandleFunc("POST /dashboard/subscriptions", dashboardHandler(addSubscription))
+	mux.HandleFunc("POST /dashboard/subscriptions/{id}/delete", dashboardHandler(removeSubscription))
+	mux.HandleFunc("GET /dashboard/articles/{id}/epub", dashboardHandler(downloadArticle))
+}
+
+// dashboardHandler resolves the logged in user, other visitors are asked to log in
+func dashboardHandler(handle func(d *dashboardRequest)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var user *model.Users
+		cookie, err := r.Cookie(sessionCookie)
+		if err == nil {
+			user, err = db.GetUserByToken(cookie.Value, db.TokenScopeDashboard)
+			if err != nil {
+				log.Printf("Error querying token: %s", err.Error())
+				http.Error(w, "An internal error occurred", http.StatusInternalServerError)
+				return
+			}
+		}
+		if user == nil {
+			if r.Method != http.MethodGet {
+				http.Redirect(w, r, "/dashboard/", http.StatusSeeOther)
+				return
+			}
+			renderTemplate(w, http.StatusOK, "login.html", map[string]string{})
+			return
+		}
+
+		d := &dashboardRequest{w: w, r: r, user: user, token: cookie.Value}
+		// The session cookie is sent along with forms of other sites in older browsers
+		if r.Method == http.MethodPost && subtle.ConstantTimeCompare([]byte(r.FormValue("csrf")), []byte(d.csrf())) != 1 {
+			http.Error(w, "The form expired, reload the page and try again", http.StatusForbidden)
+			return
+		}
+		handle(d)
+	}
+}
+
+// csrf returns the token that forms of the dashboard have to contain, it is derived from the session
+func (d *dashboardRequest) csrf() string {
+	hash := sha256.Sum256([]byte("csrf:" + d.token))
+	return hex.EncodeToString(hash[:])
+}
+
+// redirect shows the dashboard with the notice after a form was submitted
+func (d *dashboardRequest) redirect(notice string) {
+	http.SetCookie(d.w, &http.Cookie{
+		Name:     noticeCookie,
+		Value:    url.QueryEscape(notice),
+		Path:     "/dashboard/",
+		MaxAge:   60,
+		HttpOnly: true,
+		Secure:   secureCookies(),
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.Redirect(d.w, d.r, "/dashboard/", http.StatusSeeOther)
+}
+
+// secureCookies is true if the dashboard is served via https
+func secureCookies() bool {
+	conf, err := config.GetConfig()
+	return err == nil && strings.HasPrefix(conf.PublicURL, "https://")
+}
+
+// showLogin asks for a confirmation before the one-time token is used, link previews must not log in
+func showLogin(w http.ResponseWriter, r *http.Request) {
+	renderTemplate(w, http.StatusOK, "login.html", map[string]string{"Token": r.PathValue("token")})
+}
+
+func handleLogin(w http.ResponseWriter, r *http.Request) {
+	user, err := db.ConsumeToken(r.PathValue("token"), db.TokenScopeLogin)
+	if err != nil {
+		log.Printf("Error querying token: %s", err.Error())
+		http.Error(w, "An internal error occurred", http.StatusInternalServerError)
+		return
+	}
+	if user == nil {
+		renderTemplate(w, http.StatusOK, "login.html", map[string]string{"Error": "The login link is invalid or expired."})
+		return
+	}
+
+	token, err := db.AddToken(*user.ID, db.TokenScopeDashboard)
+	if err != nil {
+		log.Printf("Error creating token: %s", err.Error())
+		http.Error(w, "An internal error occurred", http.StatusInternalServerError)
+		return
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookie,
+		Value:    token,
+		Path:     "/dashboard/",
+		MaxAge:   int((30 * 24 * time.Hour).Seconds()),
+		HttpOnly: true,
+		Secure:   secureCookies(),
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.Redirect(w, r, "/dashboard/", http.StatusSeeOther)
+}
+
+func handleLogout(d *dashboardRequest) {
+	err := db.DeleteToken(d.token, db.TokenScopeDashboard)
+	if err != nil {
+		log.Printf("Error deleting token: %s", err.Error())
+	}
+	http.SetCookie(d.w, &http.Cookie{Name: sessionCookie, Path: "/dashboard/", MaxAge: -1})
+	http.Redirect(d.w, d.r, "/dashboard/", http.StatusSeeOther)
+}
+
+func showDashboard(d *dashboardRequest) {
+	page := dashboardPage{
+		User:            d.user,
+		CSRF:            d.csrf(),
+		SubstackSession: d.user.SubstackSession != nil && *d.user.SubstackSession != "",
+		Platforms:       sessionPlatforms,
+	}
+	if cookie, err := d.r.Cookie(noticeCookie); err == nil {
+		page.Notice, _ = url.QueryUnescape(cookie.Value)
+		http.SetCookie(d.w, &http.Cookie{Name: noticeCookie, Path: "/dashboard/", MaxAge: -1})
+	}
+
+	var err error
+	page.Total, err = db.CountHistory(*d.user.ID)
+	if err == nil {
+		page.Pages = max(1, int((page.Total+historyPageSize-1)/historyPageSize))
+		page.Page, _ = strconv.Atoi(d.r.URL.Query().Get("page"))
+		page.Page = max(1, min(page.Page, page.Pages))
+		page.History, err = db.GetHistory(*d.user.ID, historyPageSize, int64((page.Page-1)*historyPageSize))
+	}
+	if err == nil {
+		page.Sessions, err = db.GetStoredSessions(*d.user.ID)
+	}
+	if err == nil {
+		page.Subscriptions, err = db.GetSubscriptions(d.user.ID)
+	}
+	if err != nil {
+		log.Printf("Error querying dashboard: %s", err.Error())
+		http.Error(d.w, "An internal error occurred", http.StatusInternalServerError)
+		return
+	}
+	renderTemplate(d.w, http.StatusOK, "dashboard.html", page)
+}
+
+func updateMail(d *dashboardRequest) {
+	address, err := mail.ParseAddress(d.r.FormValue("address"))
+	if err != nil {
+		d.redirect("Invalid mail address")
+		return
+	}
+	err = db.SetKindleMail(*d.user.ID, address.Address)
+	if err != nil {
+		log.Printf("Error updating mail address: %s", err.Error())
+		d.redirect("An internal error occurred while updating the mail address")
+		return
+	}
+	d.redirect("Your ebooks are exported to " + address.Address + " from now on")
+}
+
+func addSession(d *dashboardRequest) {
+	platform := d.r.FormValue("platform")
+	domain := scrape.NormalizeDomain(d.r.FormValue("domain"))
+	cookie := strings.TrimSpace(d.r.FormValue("cookie"))
+	if cookie == "" {
+		d.redirect("The session cookie is required")
+		return
+	}
+
+	var err error
+	switch platform {
+	case scrape.PlatformSubstack:
+		if domain == "" || domain == "substack.com" {
+			addSubstackSession(d, cookie)
+			return
+		}
+		// Publications on a custom domain have sessions of their own
+		addSubstackPublicationSession(d, domain, cookie)
+		return
+	case scrape.PlatformMedium:
+		err = db.SetSession(*d.user.ID, platform, domain, cookie)
+	case scrape.PlatformGhost, scrape.PlatformBeehiiv:
+		if domain == "" {
+			d.redirect("The domain of the publication is required for " + platform + " sessions")
+			return
+		}
+		err = db.SetSession(*d.user.ID, platform, domain, cookie)
+	default:
+		d.redirect("Unknown platform")
+		return
+	}
+	if err != nil {
+		log.Printf("Error storing session: %s", err.Error())
+		d.redirect("An internal error occurred while storing the session")
+		return
+	}
+	d.redirect("Session cookie has been updated")
+}
+
+// addSubstackSession stores the Substack session cookie once Substack accepted it
+func addSubstackSession(d *dashboardRequest, cookie string) {
+	account, err := substack.Validate(cookie)
+	if errors.Is(err, substack.ErrSessionExpired) {
+		d.redirect("Substack did not accept the session cookie, it is not valid or has expired")
+		return
+	}
+	if err != nil {
+		log.Printf("Error validating session: %s", err.Error())
+		d.redirect("The session cookie could not be validated: " + err.Error())
+		return
+	}
+	err = db.SetSubstackSession(*d.user.ID, cookie, account.Profile.Handle)
+	if err != nil {
+		log.Printf("Error storing session: %s", err.Error())
+		d.redirect("An internal error occurred while storing the session")
+		return
+	}
+	d.redirect("Session cookie has been updated. " + account.Summary())
+}
+
+// addSubstackPublicationSession stores the session of a Substack publication on a custom domain once the publication accepted it
+func addSubstackPublicationSession(d *dashboardRequest, domain string, cookie string) {
+	profile, err := substack.ValidatePublication(domain, cookie)
+	if errors.Is(err, substack.ErrSessionExpired) {
+		d.redirect(domain + " did not accept the session cookie, it is not valid or has expired")
+		return
+	}
+	if err != nil {
+		log.Printf("Error validating session for %s: %s", domain, err.Error())
+		d.redirect("The session cookie could not be validated with " + domain + ": " + err.Error())
+		return
+	}
+	err = db.SetSession(*d.user.ID, scrape.PlatformSubstack, domain, cookie)
+	if err != nil {
+		log.Printf("Error storing session: %s", err.Error())
+		d.redirect("An internal error occurred while storing the session")
+		return
+	}
+	d.redirect("Session cookie for " + domain + " has been updated, it belongs to the Substack account " + profile.Name)
+}
+
+// removeSession deletes a stored session, the id substack refers to the Substack session
+func removeSession(d *dashboardRequest) {
+	if d.r.PathValue("id") == scrape.PlatformSubstack {
+		err := db.RemoveSubstackSession(*d.user.ID)
+		if err != nil {
+			log.Printf("Error removing session: %s", err.Error())
+			d.redirect("An internal error occurred while removing the session")
+			return
+		}
+		d.redirect("Session has been removed")
+		return
+	}
+
+	id, err := strconv.Atoi(d.r.PathValue("id"))
+	if err != nil {
+		d.redirect("Session not found")
+		return
+	}
+	removed, err := db.RemoveSession(*d.user.ID, int32(id))
+	if err != nil {
+		log.Printf("Error removing session: %s", err.Error())
+		d.redirect("An internal error occurred while removing the session")
+		return
+	}
+	if !removed {
+		d.redirect("Session not found")
+		return
+	}
+	d.redirect("Session has been removed")
+}
+
+func addSubscription(d *dashboardRequest) {
+	urlValue := strings.TrimSpace(d.r.FormValue("url"))
+	if _, err := url.ParseRequestURI(urlValue); err != nil {
+		d.redirect("Invalid URL")
+		return
+	}
+
+	feedURL, err := feed.Discover(urlValue)
+	if err != nil {
+		d.redirect("Could not find a feed for this URL: " + err.Error())
+		return
+	}
+	parsed, err := feed.Fetch(feedURL)
+	if err != nil {
+		d.redirect("Could not read the feed: " + err.Error())
+		return
+	}
+	title, err := feed.Subscribe(*d.user.ID, feedURL, parsed)
+	if errors.Is(err, feed.ErrAlreadySubscribed) {
+		d.redirect("You are already subscribed to " + title)
+		return
+	}
+	if err != nil {
+		log.Printf("Error adding subscription: %s", err.Error())
+		d.redirect("An internal error occurred while adding the subscription")
+		return
+	}
+	d.redirect("Subscribed to " + title + ", new posts will be sent to your mail address")
+}
+
+func removeSubscription(d *dashboardRequest) {
+	id, err := strconv.Atoi(d.r.PathValue("id"))
+	if err != nil {
+		d.redirect("Subscription not found")
+		return
+	}
+	removed, err := db.RemoveSubscription(*d.user.ID, int32(id))
+	if err != nil {
+		log.Printf("Error removing subscription: %s", err.Error())
+		d.redirect("An internal error occurred while removing the subscription")
+		return
+	}
+	if !removed {
+		d.redirect("Subscription not found")
+		return
+	}
+	d.redirect("Subscription has been removed")
+}
+
+// downloadArticle sends the epub of an article from the history of the user
+func downloadArticle(d *dashboardRequest) {
+	id, err := strconv.Atoi(d.r.PathValue("id"))
+	if err != nil {
+		http.NotFound(d.w, d.r)
+		return
+	}
+	entry, err := db.GetHistoryEntry(*d.user.ID, int32(id))
+	if err != nil {
+		log.Printf("Error querying history: %s", err.Error())
+		http.Error(d.w, "An internal error occurred", http.StatusInternalServerError)
+		return
+	}
+	if entry == nil {
+		http.NotFound(d.w, d.r)
+		return
+	}
+
+	epubPath, err := export.HistoryEpub(d.user, *entry)
+	if errors.Is(err, export.ErrWithheld) {
+		http.Error(d.w, "The article is behind a paywall your sessions cannot read", http.StatusForbidden)
+		return
+	}
+	if err != nil {
+		log.Printf("Error preparing download of article %d: %s", id, err.Error())
+		http.Error(d.w, "The article could not be prepared for download", http.StatusInternalServerError)
+		return
+	}
+	serveDownload(d.w, d.r, epubPath, downloadTypes[ebook.FormatEPUB])
+}
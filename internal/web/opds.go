@@ -0,0 +1,295 @@
+package web
+
+import (
+	"errors"
+	"kindExport/generated/model"
+	"kindExport/internal/config"
+	"kindExport/internal/db"
+	"kindExport/internal/ebook"
+	"kindExport/internal/export"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+const (
+	// catalogPageSize is the amount of articles on a page of the catalog
+	catalogPageSize = 50
+
+	// The catalog is served as OPDS 1.2 and OPDS 2.0 with the same paths below these prefixes
+	opdsV1Prefix = "/opds"
+	opdsV2Prefix = "/opds2"
+)
+
+// downloadTypes are the formats articles can be downloaded in with their media types
+var downloadTypes = map[string]string{
+	ebook.FormatEPUB: "application/epub+zip",
+	ebook.FormatAZW3: "application/x-mobi8-ebook",
+	ebook.FormatMOBI: "application/x-mobipocket-ebook",
+	ebook.FormatPDF:  "application/pdf",
+}
+
+// catalogFeed is a page of the catalog independent of the OPDS version
+type catalogFeed struct {
+	ID    string
+	Title string
+	// Path is the location of the feed relative to the root of the catalog
+	Path       string
+	Navigation []catalogLink
+	// Acquisition is true for feeds listing articles
+	Acquisition bool
+	Articles    []model.Articles
+	// Formats are offered for the download of every article
+	Formats []string
+	Page    int
+	Total   int64
+}
+
+// catalogLink leads to another feed of the catalog
+type catalogLink struct {
+	Title       string
+	Path        string
+	Count       int64
+	Acquisition bool
+}
+
+// catalogRequest is an authenticated request to the catalog of a user
+type catalogRequest struct {
+	r    *http.Request
+	user *model.Users
+	// root is the path of the catalog including the token of the user
+	root string
+	v2   bool
+}
+
+func registerOPDS(mux *http.ServeMux) {
+	for _, prefix := range []string{opdsV1Prefix, opdsV2Prefix} {
+		root := prefix + "/{token}"
+		mux.HandleFunc("GET "+root+"/{$}", feedHandler(rootFeed))
+		mux.HandleFunc("GET "+root+"/recent", feedHandler(recentFeed))
+		mux.HandleFunc("GET "+root+"/shelf", feedHandler(shelfFeed))
+		mux.HandleFunc("GET "+root+"/authors", feedHandler(authorsFeed))
+		mux.HandleFunc("GET "+root+"/authors/{author}", feedHandler(authorFeed))
+		mux.HandleFunc("GET "+root+"/publications", feedHandler(publicationsFeed))
+		mux.HandleFunc("GET "+root+"/publications/{publication}", feedHandler(publicationFeed))
+		mux.HandleFunc("GET "+root+"/articles/{id}/{format}", handleDownload)
+	}
+}
+
+// authenticate resolves the user of the token in the path, it responds with an error if the token is unknown
+func authenticate(w http.ResponseWriter, r *http.Request) (*catalogRequest, bool) {
+	token := r.PathValue("token")
+	user, err := db.GetUserByToken(token, db.TokenScopeOPDS)
+	if err != nil {
+		log.Printf("Error querying token: %s", err.Error())
+		http.Error(w, "An internal error occurred", http.StatusInternalServerError)
+		return nil, false
+	}
+	if user == nil {
+		http.Error(w, "The catalog link is invalid, create a new one with the /opds command", http.StatusUnauthorized)
+		return nil, false
+	}
+
+	prefix := opdsV1Prefix
+	if strings.HasPrefix(r.URL.Path, opdsV2Prefix+"/") {
+		prefix = opdsV2Prefix
+	}
+	return &catalogRequest{
+		r:    r,
+		user: user,
+		root: prefix + "/" + url.PathEscape(token),
+		v2:   prefix == opdsV2Prefix,
+	}, true
+}
+
+func feedHandler(build func(c *catalogRequest) (*catalogFeed, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		c, ok := authenticate(w, r)
+		if !ok {
+			return
+		}
+		feed, err := build(c)
+		if err != nil {
+			log.Printf("Error building catalog feed: %s", err.Error())
+			http.Error(w, "An internal error occurred", http.StatusInternalServerError)
+			return
+		}
+		if c.v2 {
+			writeOPDS2(w, c, feed)
+		} else {
+			writeOPDS1(w, c, feed)
+		}
+	}
+}
+
+// href returns the link to a path of the catalog, page 0 omits the page parameter
+func (c *catalogRequest) href(path string, page int) string {
+	href := c.root + "/" + path
+	if page > 0 {
+		href += "?page=" + strconv.Itoa(page)
+	}
+	return href
+}
+
+func (c *catalogRequest) page() int {
+	page, err := strconv.Atoi(c.r.URL.Query().Get("page"))
+	if err != nil {
+		return 1
+	}
+	return max(1, page)
+}
+
+// filter completes the filter with the user and whether the catalog of the bot is shared between users
+func (c *catalogRequest) filter(filter db.CatalogFilter) db.CatalogFilter {
+	filter.UserID = *c.user.ID
+	if conf, err := config.GetConfig(); err == nil {
+		filter.Shared = conf.SharedCatalog
+	}
+	return filter
+}
+
+// formats returns the download formats of the user, the epub is always offered
+func (c *catalogRequest) formats() []string {
+	formats := []string{ebook.FormatEPUB}
+	if _, ok := downloadTypes[c.user.Format]; ok && c.user.Format != ebook.FormatEPUB {
+		formats = append(formats, c.user.Format)
+	}
+	return formats
+}
+
+func rootFeed(c *catalogRequest) (*catalogFeed, error) {
+	total, err := db.CountCatalogArticles(c.filter(db.CatalogFilter{}))
+	if err != nil {
+		return nil, err
+	}
+	shelf, err := db.CountCatalogArticles(c.filter(db.CatalogFilter{Shelf: true}))
+	if err != nil {
+		return nil, err
+	}
+	return &catalogFeed{
+		ID:    "root",
+		Title: "kindExport",
+		Navigation: []catalogLink{
+			{Title: "Recently exported", Path: "recent", Count: total, Acquisition: true},
+			{Title: "My shelf", Path: "shelf", Count: shelf, Acquisition: true},
+			{Title: "By author", Path: "authors"},
+			{Title: "By publication", Path: "publications"},
+		},
+	}, nil
+}
+
+func recentFeed(c *catalogRequest) (*catalogFeed, error) {
+	return articlesFeed(c, "recent", "Recently exported", "recent", db.CatalogFilter{})
+}
+
+// shelfFeed lists the articles that were exported for the user
+func shelfFeed(c *catalogRequest) (*catalogFeed, error) {
+	return articlesFeed(c, "shelf", "My shelf", "shelf", db.CatalogFilter{Shelf: true})
+}
+
+func authorsFeed(c *catalogRequest) (*catalogFeed, error) {
+	authors, err := db.GetCatalogAuthors(c.filter(db.CatalogFilter{}))
+	if err != nil {
+		return nil, err
+	}
+	return groupsFeed("authors", "By author", authors), nil
+}
+
+func authorFeed(c *catalogRequest) (*catalogFeed, error) {
+	author := c.r.PathValue("author")
+	return articlesFeed(c, "authors:"+author, author, "authors/"+url.PathEscape(author), db.CatalogFilter{Author: author})
+}
+
+func publicationsFeed(c *catalogRequest) (*catalogFeed, error) {
+	publications, err := db.GetCatalogPublications(c.filter(db.CatalogFilter{}))
+	if err != nil {
+		return nil, err
+	}
+	return groupsFeed("publications", "By publication", publications), nil
+}
+
+func publicationFeed(c *catalogRequest) (*catalogFeed, error) {
+	publication := c.r.PathValue("publication")
+	return articlesFeed(c, "publications:"+publication, publication, "publications/"+url.PathEscape(publication), db.CatalogFilter{Publication: publication})
+}
+
+// groupsFeed links to a feed for every author or publication
+func groupsFeed(path string, title string, groups []db.CatalogGroup) *catalogFeed {
+	feed := &catalogFeed{ID: path, Title: title, Path: path}
+	for _, group := range groups {
+		feed.Navigation = append(feed.Navigation, catalogLink{
+			Title:       group.Name,
+			Path:        path + "/" + url.PathEscape(group.Name),
+			Count:       group.Count,
+			Acquisition: true,
+		})
+	}
+	return feed
+}
+
+func articlesFeed(c *catalogRequest, id string, title string, path string, filter db.CatalogFilter) (*catalogFeed, error) {
+	filter = c.filter(filter)
+	page := c.page()
+	total, err := db.CountCatalogArticles(filter)
+	if err != nil {
+		return nil, err
+	}
+	articles, err := db.GetCatalogArticles(filter, catalogPageSize, int64(page-1)*catalogPageSize)
+	if err != nil {
+		return nil, err
+	}
+	return &catalogFeed{
+		ID:          id,
+		Title:       title,
+		Path:        path,
+		Acquisition: true,
+		Articles:    articles,
+		Formats:     c.formats(),
+		Page:        page,
+		Total:       total,
+	}, nil
+}
+
+// handleDownload sends an article of the catalog, formats besides epub are converted on the first download
+func handleDownload(w http.ResponseWriter, r *http.Request) {
+	c, ok := authenticate(w, r)
+	if !ok {
+		return
+	}
+	format := r.PathValue("format")
+	contentType, ok := downloadTypes[format]
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if !ok || err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	article, err := db.GetCatalogArticle(c.filter(db.CatalogFilter{}), int32(id))
+	if err != nil {
+		log.Printf("Error querying article: %s", err.Error())
+		http.Error(w, "An internal error occurred", http.StatusInternalServerError)
+		return
+	}
+	if article == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	filePath, err := export.StoredEpub(c.user, *article)
+	if err == nil && format != ebook.FormatEPUB {
+		user := *c.user
+		user.Format = format
+		filePath, err = export.Rendition(article.ID, filePath, &user)
+	}
+	if errors.Is(err, export.ErrWithheld) {
+		http.Error(w, "The full article is behind a paywall your sessions cannot read", http.StatusForbidden)
+		return
+	}
+	if err != nil {
+		log.Printf("Error preparing download of article %d: %s", id, err.Error())
+		http.Error(w, "The article could not be prepared for download", http.StatusInternalServerError)
+		return
+	}
+	serveDownload(w, r, filePath, contentType)
+}
@@ -0,0 +1,172 @@
+package web
+
+import (
+	"encoding/xml"
+	"fmt"
+	"kindExport/generated/model"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	atomNavigationType  = "application/atom+xml;profile=opds-catalog;kind=navigation"
+	atomAcquisitionType = "application/atom+xml;profile=opds-catalog;kind=acquisition"
+)
+
+type atomFeed struct {
+	XMLName         xml.Name    `xml:"feed"`
+	Xmlns           string      `xml:"xmlns,attr"`
+	XmlnsDC         string      `xml:"xmlns:dc,attr"`
+	XmlnsOpenSearch string      `xml:"xmlns:opensearch,attr"`
+	ID              string      `xml:"id"`
+	Title           string      `xml:"title"`
+	Updated         string      `xml:"updated"`
+	TotalResults    int64       `xml:"opensearch:totalResults,omitempty"`
+	ItemsPerPage    int         `xml:"opensearch:itemsPerPage,omitempty"`
+	Links           []atomLink  `xml:"link"`
+	Entries         []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Rel   string `xml:"rel,attr,omitempty"`
+	Href  string `xml:"href,attr"`
+	Type  string `xml:"type,attr,omitempty"`
+	Title string `xml:"title,attr,omitempty"`
+}
+
+type atomEntry struct {
+	ID      string       `xml:"id"`
+	Title   string       `xml:"title"`
+	Updated string       `xml:"updated"`
+	Authors []atomAuthor `xml:"author"`
+	Issued  string       `xml:"dc:issued,omitempty"`
+	Content *atomContent `xml:"content,omitempty"`
+	Links   []atomLink   `xml:"link"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+}
+
+type atomContent struct {
+	Type string `xml:"type,attr"`
+	Text string `xml:",chardata"`
+}
+
+// writeOPDS1 renders the feed as OPDS 1.2 Atom feed
+func writeOPDS1(w http.ResponseWriter, c *catalogRequest, feed *catalogFeed) {
+	feedType := atomNavigationType
+	if feed.Acquisition {
+		feedType = atomAcquisitionType
+	}
+	updated := time.Now().UTC().Format(time.RFC3339)
+
+	atom := atomFeed{
+		Xmlns:           "http://www.w3.org/2005/Atom",
+		XmlnsDC:         "http://purl.org/dc/terms/",
+		XmlnsOpenSearch: "http://a9.com/-/spec/opensearch/1.1/",
+		ID:              "urn:kindexport:catalog:" + feed.ID,
+		Title:           feed.Title,
+		Updated:         updated,
+		Links: []atomLink{
+			{Rel: "self", Href: c.href(feed.Path, 0), Type: feedType},
+			{Rel: "start", Href: c.href("", 0), Type: atomNavigationType},
+		},
+	}
+	for _, link := range pageLinks(feed) {
+		atom.Links = append(atom.Links, atomLink{Rel: link.rel, Href: c.href(feed.Path, link.page), Type: feedType})
+	}
+	if feed.Acquisition {
+		atom.TotalResults = feed.Total
+		atom.ItemsPerPage = catalogPageSize
+	}
+
+	for _, link := range feed.Navigation {
+		linkType := atomNavigationType
+		if link.Acquisition {
+			linkType = atomAcquisitionType
+		}
+		entry := atomEntry{
+			ID:      "urn:kindexport:catalog:" + feed.ID + ":" + link.Title,
+			Title:   link.Title,
+			Updated: updated,
+			Links:   []atomLink{{Rel: "subsection", Href: c.href(link.Path, 0), Type: linkType}},
+		}
+		if link.Count > 0 {
+			entry.Content = &atomContent{Type: "text", Text: articleCount(link.Count)}
+		}
+		atom.Entries = append(atom.Entries, entry)
+	}
+	for _, article := range feed.Articles {
+		atom.Entries = append(atom.Entries, atomArticle(c, feed, article))
+	}
+
+	output, err := xml.MarshalIndent(atom, "", "  ")
+	if err != nil {
+		log.Printf("Error encoding OPDS feed: %s", err.Error())
+		http.Error(w, "An internal error occurred", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", feedType+";charset=utf-8")
+	_, _ = w.Write([]byte(xml.Header))
+	_, _ = w.Write(output)
+}
+
+func atomArticle(c *catalogRequest, feed *catalogFeed, article model.Articles) atomEntry {
+	entry := atomEntry{
+		ID:      articleURN(article),
+		Title:   article.Title,
+		Updated: article.CreatedAt.UTC().Format(time.RFC3339),
+		Authors: []atomAuthor{{Name: article.Author}},
+		Links:   []atomLink{{Rel: "alternate", Href: article.URL, Type: "text/html", Title: "Original article"}},
+	}
+	if !article.ReleaseDate.IsZero() {
+		entry.Issued = article.ReleaseDate.UTC().Format(time.DateOnly)
+	}
+	for _, format := range feed.Formats {
+		entry.Links = append(entry.Links, atomLink{
+			Rel:  "http://opds-spec.org/acquisition",
+			Href: c.href(articlePath(article, format), 0),
+			Type: downloadTypes[format],
+		})
+	}
+	return entry
+}
+
+// pageLink is a link to another page of an acquisition feed
+type pageLink struct {
+	rel  string
+	page int
+}
+
+func pageLinks(feed *catalogFeed) []pageLink {
+	if !feed.Acquisition {
+		return nil
+	}
+	last := max(1, int((feed.Total+catalogPageSize-1)/catalogPageSize))
+	links := []pageLink{{"first", 1}, {"last", last}}
+	if feed.Page > 1 {
+		links = append(links, pageLink{"previous", min(feed.Page-1, last)})
+	}
+	if feed.Page < last {
+		links = append(links, pageLink{"next", feed.Page + 1})
+	}
+	return links
+}
+
+func articleURN(article model.Articles) string {
+	return "urn:kindexport:article:" + strconv.Itoa(int(*article.ID))
+}
+
+func articlePath(article model.Articles, format string) string {
+	return fmt.Sprintf("articles/%d/%s", *article.ID, format)
+}
+
+func articleCount(count int64) string {
+	if count == 1 {
+		return "1 article"
+	}
+	return fmt.Sprintf("%d articles", count)
+}
@@ -0,0 +1,121 @@
+package web
+
+import (
+	"encoding/json"
+	"kindExport/generated/model"
+	"log"
+	"net/http"
+	"time"
+)
+
+const opds2Type = "application/opds+json"
+
+type opds2Feed struct {
+	Metadata   opds2Metadata `json:"metadata"`
+	Links      []opds2Link   `json:"links"`
+	Navigation []opds2Link   `json:"navigation,omitempty"`
+	// Publications is a pointer to keep empty acquisition feeds valid with an empty list
+	Publications *[]opds2Publication `json:"publications,omitempty"`
+}
+
+type opds2Metadata struct {
+	Title         string `json:"title"`
+	NumberOfItems *int64 `json:"numberOfItems,omitempty"`
+	ItemsPerPage  int    `json:"itemsPerPage,omitempty"`
+	CurrentPage   int    `json:"currentPage,omitempty"`
+}
+
+type opds2Link struct {
+	Rel        string           `json:"rel,omitempty"`
+	Href       string           `json:"href"`
+	Type       string           `json:"type,omitempty"`
+	Title      string           `json:"title,omitempty"`
+	Properties *opds2Properties `json:"properties,omitempty"`
+}
+
+type opds2Properties struct {
+	NumberOfItems int64 `json:"numberOfItems"`
+}
+
+type opds2Publication struct {
+	Metadata opds2PublicationMetadata `json:"metadata"`
+	Links    []opds2Link              `json:"links"`
+}
+
+type opds2PublicationMetadata struct {
+	Type       string             `json:"@type"`
+	Identifier string             `json:"identifier"`
+	Title      string             `json:"title"`
+	Author     []opds2Contributor `json:"author,omitempty"`
+	Published  string             `json:"published,omitempty"`
+	Modified   string             `json:"modified"`
+}
+
+type opds2Contributor struct {
+	Name string `json:"name"`
+}
+
+// writeOPDS2 renders the feed as OPDS 2.0 JSON feed
+func writeOPDS2(w http.ResponseWriter, c *catalogRequest, feed *catalogFeed) {
+	opds := opds2Feed{
+		Metadata: opds2Metadata{Title: feed.Title},
+		Links: []opds2Link{
+			{Rel: "self", Href: c.href(feed.Path, 0), Type: opds2Type},
+			{Rel: "start", Href: c.href("", 0), Type: opds2Type},
+		},
+	}
+	for _, link := range pageLinks(feed) {
+		opds.Links = append(opds.Links, opds2Link{Rel: link.rel, Href: c.href(feed.Path, link.page), Type: opds2Type})
+	}
+
+	for _, link := range feed.Navigation {
+		navigation := opds2Link{Href: c.href(link.Path, 0), Type: opds2Type, Title: link.Title}
+		if link.Acquisition {
+			navigation.Properties = &opds2Properties{NumberOfItems: link.Count}
+		}
+		opds.Navigation = append(opds.Navigation, navigation)
+	}
+	if feed.Acquisition {
+		opds.Metadata.NumberOfItems = &feed.Total
+		opds.Metadata.ItemsPerPage = catalogPageSize
+		opds.Metadata.CurrentPage = feed.Page
+		publications := make([]opds2Publication, 0, len(feed.Articles))
+		for _, article := range feed.Articles {
+			publications = append(publications, opds2Article(c, feed, article))
+		}
+		opds.Publications = &publications
+	}
+
+	output, err := json.MarshalIndent(opds, "", "  ")
+	if err != nil {
+		log.Printf("Error encoding OPDS feed: %s", err.Error())
+		http.Error(w, "An internal error occurred", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", opds2Type)
+	_, _ = w.Write(output)
+}
+
+func opds2Article(c *catalogRequest, feed *catalogFeed, article model.Articles) opds2Publication {
+	publication := opds2Publication{
+		Metadata: opds2PublicationMetadata{
+			Type:       "http://schema.org/Book",
+			Identifier: articleURN(article),
+			Title:      article.Title,
+			Author:     []opds2Contributor{{Name: article.Author}},
+			Modified:   article.CreatedAt.UTC().Format(time.RFC3339),
+		},
+		Links: []opds2Link{{Rel: "alternate", Href: article.URL, Type: "text/html", Title: "Original article"}},
+	}
+	if !article.ReleaseDate.IsZero() {
+		publication.Metadata.Published = article.ReleaseDate.UTC().Format(time.DateOnly)
+	}
+	for _, format := range feed.Formats {
+		publication.Links = append(publication.Links, opds2Link{
+			Rel:  "http://opds-spec.org/acquisition",
+			Href: c.href(articlePath(article, format), 0),
+			Type: downloadTypes[format],
+		})
+	}
+	return publication
+}
@@ -0,0 +1,85 @@
+package web
+
+import (
+	"embed"
+	"errors"
+	"html/template"
+	"log"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// templates are the HTML pages of the dashboard and the share endpoint
+var (
+	//go:embed templates
+	templateFiles embed.FS
+	templates     = template.Must(template.New("").Funcs(template.FuncMap{
+		"date": func(t time.Time) string { return t.Format("Jan 02, 2006") },
+		"add":  func(a int, b int) int { return a + b },
+	}).ParseFS(templateFiles, "templates/*.html"))
+)
+
+// Server serves the HTTP endpoints of the bot
+type Server struct {
+	server *http.Server
+}
+
+// NewServer creates the server listening on the given address
+func NewServer(address string) *Server {
+	mux := http.NewServeMux()
+	registerOPDS(mux)
+	registerDashboard(mux)
+	registerAPI(mux)
+	registerShare(mux)
+
+	return &Server{
+		server: &http.Server{
+			Addr:              address,
+			Handler:           mux,
+			ReadHeaderTimeout: 10 * time.Second,
+		},
+	}
+}
+
+// Run serves requests until the server is closed
+func (s *Server) Run() {
+	err := s.server.ListenAndServe()
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Printf("Error running HTTP server: %s", err.Error())
+	}
+}
+
+func renderTemplate(w http.ResponseWriter, status int, name string, data any) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	err := templates.ExecuteTemplate(w, name, data)
+	if err != nil {
+		log.Printf("Error rendering %s: %s", name, err.Error())
+	}
+}
+
+// serveDownload sends the file as attachment with its name
+func serveDownload(w http.ResponseWriter, r *http.Request, filePath string, contentType string) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		log.Printf("Error opening %s: %s", filePath, err.Error())
+		http.Error(w, "The file is not available", http.StatusNotFound)
+		return
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		http.Error(w, "The file is not available", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{
+		"filename": filepath.Base(filePath),
+	}))
+	http.ServeContent(w, r, filepath.Base(filePath), info.ModTime(), file)
+}
@@ -0,0 +1,78 @@
+package web
+
+import (
+	"errors"
+	"kindExport/internal/db"
+	"kindExport/internal/export"
+	"log"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// maxSharedPageSize limits the body of a share request, which may contain the rendered page
+const maxSharedPageSize = 10 << 20
+
+// sharedLink finds the link in the text of share sheets that do not send it as separate field
+var sharedLink = regexp.MustCompile(`https?://\S+`)
+
+type shareResponse struct {
+	ID      int32  `json:"id"`
+	Message string `json:"message"`
+}
+
+func registerShare(mux *http.ServeMux) {
+	mux.HandleFunc("POST /share/{token}", handleShare)
+}
+
+// handleShare exports a page that a bookmarklet or the share sheet of a phone sent.
+// Besides the URL, the request may contain the HTML of the page as rendered by the browser of the user,
+// which is only delivered to that user. The token of the link is bound to the share scope and expires.
+func handleShare(w http.ResponseWriter, r *http.Request) {
+	user, err := db.GetUserByToken(r.PathValue("token"), db.TokenScopeShare)
+	if err != nil {
+		log.Printf("Error querying token: %s", err.Error())
+		respondShare(w, r, http.StatusInternalServerError, "An internal error occurred", 0)
+		return
+	}
+	if user == nil {
+		respondShare(w, r, http.StatusUnauthorized, "The share link is invalid or has expired, create a new one with the /share command", 0)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxSharedPageSize)
+	err = r.ParseMultipartForm(maxSharedPageSize)
+	if err != nil && !errors.Is(err, http.ErrNotMultipart) {
+		respondShare(w, r, http.StatusBadRequest, "The shared page could not be read", 0)
+		return
+	}
+	urlValue := strings.TrimSpace(r.FormValue("url"))
+	if urlValue == "" {
+		urlValue = sharedLink.FindString(r.FormValue("text"))
+	}
+	if err := export.ValidateURL(urlValue); err != nil {
+		respondShare(w, r, http.StatusBadRequest, "No valid link was shared", 0)
+		return
+	}
+
+	id, err := export.EnqueuePage(*user.ID, urlValue, r.FormValue("html"), "", "")
+	if err != nil {
+		log.Printf("Error queueing export: %s", err.Error())
+		respondShare(w, r, http.StatusInternalServerError, "An internal error occurred while queueing the export", 0)
+		return
+	}
+	respondShare(w, r, http.StatusAccepted, "The article is being exported, you get a message on Discord once it is delivered", id)
+}
+
+// respondShare answers scripts with JSON and browsers with a page they show in the tab the bookmarklet opened
+func respondShare(w http.ResponseWriter, r *http.Request, status int, message string, id int32) {
+	if strings.Contains(r.Header.Get("Accept"), "application/json") {
+		if id == 0 {
+			writeJSON(w, status, apiError{message})
+		} else {
+			writeJSON(w, status, shareResponse{ID: id, Message: message})
+		}
+		return
+	}
+	renderTemplate(w, status, "share.html", map[string]string{"Message": message})
+}
@@ -3,27 +3,22 @@ package main
 import (
 	"kindExport/internal/config"
 	"kindExport/internal/db"
+	"kindExport/internal/digest"
 	"kindExport/internal/discord"
-	"kindExport/internal/scrape"
+	"kindExport/internal/export"
+	"kindExport/internal/feed"
+	"kindExport/internal/mailer"
+	"kindExport/internal/substack"
+	"kindExport/internal/web"
 	"log"
+	"os"
 )
 
-func mainSubstack() {
-	link := "https://newsletter.pragmaticengineer.com/p/leaving-big-tech"
-	scraper := scrape.SubstackScraper{}
-
-	//_, err := scraper.CheckPaywallAccessible("https://newsletter.pragmaticengineer.com/p/the-pulse-118")
-	//return
-	_, err := scraper.Scrape(&link)
-	if err != nil {
-		print(err.Error())
+func main() {
+	if len(os.Args) > 1 {
+		runCommand(os.Args[1:])
 		return
 	}
-}
-
-func main() {
-	//mainSubstack()
-	//return
 	log.Printf("Initializing database")
 	dbSession, err := db.GetDB()
 	if err != nil {
@@ -34,7 +29,7 @@ func main() {
 		defer dbSession.Close()
 	}
 	log.Printf("Checking mail configuration")
-	err = discord.CheckMailConfig()
+	err = mailer.CheckMailConfig()
 	if err != nil {
 		log.Printf("Error checking mail configuration: %s", err.Error())
 		return
@@ -51,5 +46,27 @@ func main() {
 		return
 	}
 
+	log.Printf("Starting export workers")
+	queue := export.NewQueue(conf.ExportWorkers, listener.UpdateExport)
+	queue.Run()
+
+	log.Printf("Starting feed poller")
+	poller := feed.NewPoller(conf.FeedPollInterval, listener.SendDirectMessage)
+	go poller.Run()
+
+	log.Printf("Starting digest scheduler")
+	scheduler := digest.NewScheduler(listener.SendDirectMessage)
+	go scheduler.Run()
+
+	log.Printf("Starting Substack session monitor")
+	monitor := substack.NewMonitor(conf.SessionCheckInterval, listener.SendDirectMessage)
+	go monitor.Run()
+
+	if conf.HTTPAddress != "" {
+		log.Printf("Starting HTTP server on %s", conf.HTTPAddress)
+		server := web.NewServer(conf.HTTPAddress)
+		go server.Run()
+	}
+
 	listener.Listen()
 }